@@ -1,29 +1,155 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"finuchet-bot/config"
+	"finuchet-bot/internal/currency"
 	"finuchet-bot/internal/handlers"
+	"finuchet-bot/internal/repository"
+	"finuchet-bot/internal/scheduler"
+	"finuchet-bot/internal/services"
 	"finuchet-bot/pkg/database"
+	"finuchet-bot/pkg/redis"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Коды завершения процесса
+const (
+	exitOK              = 0
+	exitError           = 1 // Ошибка запуска или работы бота
+	exitShutdownTimeout = 2 // Не все принятые обновления успели обработаться при остановке
 )
 
 func main() {
+	os.Exit(run())
+}
+
+func run() int {
 	// Загружаем конфигурацию
 	cfg := config.LoadConfig()
+	if err := cfg.Validate(); err != nil {
+		log.Printf("Некорректная конфигурация: %v", err)
+		return exitError
+	}
+
+	// SIGINT/SIGTERM (в том числе от docker compose) запускают корректную остановку
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop() // Повторный сигнал завершит процесс немедленно
+		log.Printf("Получен сигнал остановки, завершаем работу (не более %s)", cfg.ShutdownTimeout)
+	}()
 
 	// Инициализируем подключение к базе данных
 	db, err := database.Connect(cfg.DB)
 	if err != nil {
-		log.Fatalf("Не удалось подключиться к базе данных: %v", err)
+		log.Printf("Не удалось подключиться к базе данных: %v", err)
+		return exitError
 	}
 	defer db.Close()
 
+	// Фоновые задачи процесса; запускаются после подключения к базе
+	var jobs scheduler.Group
+
+	// Инициализируем хранилище состояний диалогов
+	states, err := newStateStore(ctx, cfg, db, &jobs)
+	if err != nil {
+		log.Printf("Не удалось инициализировать хранилище состояний: %v", err)
+		return exitError
+	}
+	// Закрывается после завершения обработчиков, но до закрытия базы данных
+	defer func() {
+		if err := states.Close(); err != nil {
+			log.Printf("Ошибка закрытия хранилища состояний: %v", err)
+		}
+	}()
+	// Хранилища закрываются только после завершения фоновых задач
+	defer func() {
+		stop()
+		waitCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+		if err := jobs.Wait(waitCtx); err != nil {
+			log.Printf("Фоновые задачи не завершились за %s", cfg.ShutdownTimeout)
+		}
+	}()
+
 	// Инициализируем Telegram-бота
-	bot, err := handlers.NewBotHandler(cfg.BotToken, db)
+	bot, err := handlers.NewBotHandler(cfg, db, states)
+	if err != nil {
+		log.Printf("Ошибка инициализации бота: %v", err)
+		return exitError
+	}
+
+	// Загружаем курсы валют, если задан источник
+	if cfg.Rates.File != "" {
+		service := services.NewFinanceService(repository.NewPostgresRepository(db))
+		provider := currency.FileProvider{Path: cfg.Rates.File}
+		jobs.Go(func() {
+			scheduler.Run(ctx, scheduler.SystemClock{}, cfg.Rates.Refresh, func(time.Time) { refreshRates(ctx, service, provider) })
+		})
+	}
+
+	// Запускаем обработку обновлений до получения сигнала остановки
+	if err := bot.Start(ctx); err != nil {
+		log.Printf("Ошибка обработки обновлений: %v", err)
+		if errors.Is(err, handlers.ErrShutdownTimeout) {
+			return exitShutdownTimeout
+		}
+		return exitError
+	}
+
+	log.Println("Бот остановлен")
+	return exitOK
+}
+
+// Выбор хранилища состояний по конфигурации
+func newStateStore(ctx context.Context, cfg *config.Config, db *sql.DB, jobs *scheduler.Group) (repository.StateStore, error) {
+	switch cfg.State.Backend {
+	case "redis":
+		client, err := redis.Connect(cfg.Redis)
+		if err != nil {
+			return nil, err
+		}
+		return repository.NewRedisStateStore(client, cfg.State.TTL), nil
+	case "postgres":
+		store := repository.NewPostgresStateStore(db, cfg.State.TTL)
+		jobs.Go(func() {
+			scheduler.Run(ctx, scheduler.SystemClock{}, time.Hour, func(time.Time) { purgeExpiredStates(store) })
+		})
+		return store, nil
+	case "memory":
+		return repository.NewMemoryStateStore(cfg.State.TTL), nil
+	default:
+		return nil, fmt.Errorf("unknown state backend: %q", cfg.State.Backend)
+	}
+}
+
+// Очистка брошенных диалогов в Postgres (в Redis их удаляет TTL)
+func purgeExpiredStates(store *repository.PostgresStateStore) {
+	n, err := store.PurgeExpired()
 	if err != nil {
-		log.Fatalf("Ошибка инициализации бота: %v", err)
+		log.Printf("Ошибка очистки устаревших состояний: %v", err)
+	} else if n > 0 {
+		log.Printf("Удалено устаревших состояний: %d", n)
 	}
+}
 
-	// Запускаем обработку обновлений
-	bot.Start()
+// Загрузка курсов валют; вызывается при запуске и затем с периодом RATES_REFRESH
+func refreshRates(ctx context.Context, service *services.FinanceService, provider currency.Provider) {
+	// Ошибка по одной валюте не мешает сохранить курсы остальных
+	n, err := service.UpdateRates(ctx, provider)
+	if err != nil {
+		log.Printf("Ошибка загрузки курсов валют: %v", err)
+	}
+	if n > 0 || err == nil {
+		log.Printf("Загружено курсов валют: %d", n)
+	}
 }
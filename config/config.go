@@ -1,15 +1,45 @@
 package config
 
 import (
+	"errors"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	BotToken string
-	DB       DBConfig
+	BotToken   string
+	UpdateMode string // Способ получения обновлений: "polling" или "webhook"
+	Webhook    WebhookConfig
+	DB         DBConfig
+	Redis      RedisConfig
+	State      StateConfig
+	Dispatch   DispatchConfig
+
+	ShutdownTimeout   time.Duration // Время на завершение обработки при остановке
+	ImportLayoutsFile string        // JSON-файл с дополнительными шаблонами банковских выписок
+	RecurringInterval time.Duration // Период проверки повторяющихся операций
+	DigestInterval    time.Duration // Период проверки сводок к отправке
+	Rates             RatesConfig
+}
+
+// Настройки загрузки курсов валют
+type RatesConfig struct {
+	File    string        // JSON-файл с курсами; если пуст, курсы задаются только вручную
+	Refresh time.Duration // Период перечитывания файла
+}
+
+// Настройки приема обновлений через webhook
+type WebhookConfig struct {
+	URL         string // Публичный адрес для setWebhook; если пуст, webhook регистрируется вручную
+	Listen      string // Адрес HTTP-сервера, например ":8080"
+	Path        string // Путь, на который Telegram присылает обновления
+	SecretToken string // Проверяется по заголовку X-Telegram-Bot-Api-Secret-Token
+	CertFile    string // TLS включается, если заданы сертификат и ключ
+	KeyFile     string
 }
 
 type DBConfig struct {
@@ -20,6 +50,24 @@ type DBConfig struct {
 	DBName   string
 }
 
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+}
+
+// Настройки хранения состояний диалогов
+type StateConfig struct {
+	Backend string        // "postgres", "redis" или "memory"
+	TTL     time.Duration // Время жизни незавершенного диалога
+}
+
+// Настройки параллельной обработки обновлений
+type DispatchConfig struct {
+	Workers   int // Количество воркеров; обновления одного чата обрабатывает один воркер
+	QueueSize int // Размер очереди каждого воркера
+}
+
 func LoadConfig() *Config {
 	err := godotenv.Load() // Переменные окружения из файла .env
 	if err != nil {
@@ -27,7 +75,16 @@ func LoadConfig() *Config {
 	}
 
 	return &Config{
-		BotToken: getEnv("BOT_TOKEN", ""),
+		BotToken:   getEnv("BOT_TOKEN", ""),
+		UpdateMode: getEnv("UPDATE_MODE", "polling"),
+		Webhook: WebhookConfig{
+			URL:         getEnv("WEBHOOK_URL", ""),
+			Listen:      getEnv("WEBHOOK_LISTEN", ":8080"),
+			Path:        getEnv("WEBHOOK_PATH", "/telegram/webhook"),
+			SecretToken: getEnv("WEBHOOK_SECRET", ""),
+			CertFile:    getEnv("WEBHOOK_CERT_FILE", ""),
+			KeyFile:     getEnv("WEBHOOK_KEY_FILE", ""),
+		},
 		DB: DBConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
 			Port:     5432,
@@ -35,7 +92,39 @@ func LoadConfig() *Config {
 			Password: getEnv("DB_PASSWORD", "root"),
 			DBName:   getEnv("DB_NAME", "db_admin"),
 		},
+		Redis: RedisConfig{
+			Addr:     getEnv("REDIS_ADDR", "localhost:6379"),
+			Password: getEnv("REDIS_PASSWORD", ""),
+			DB:       getEnvInt("REDIS_DB", 0),
+		},
+		State: StateConfig{
+			Backend: getEnv("STATE_BACKEND", "postgres"),
+			TTL:     getEnvDuration("STATE_TTL", 24*time.Hour),
+		},
+		Dispatch: DispatchConfig{
+			Workers:   getEnvInt("BOT_WORKERS", 8),
+			QueueSize: getEnvInt("BOT_QUEUE_SIZE", 100),
+		},
+		ShutdownTimeout:   getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		ImportLayoutsFile: getEnv("IMPORT_LAYOUTS_FILE", ""),
+		RecurringInterval: getEnvDuration("RECURRING_INTERVAL", 10*time.Minute),
+		DigestInterval:    getEnvDuration("DIGEST_INTERVAL", time.Minute),
+		Rates: RatesConfig{
+			File:    getEnv("RATES_FILE", ""),
+			Refresh: getEnvDuration("RATES_REFRESH", 12*time.Hour),
+		},
+	}
+}
+
+// ErrWebhookSecret - webhook без секрета принял бы поддельные обновления от кого угодно
+var ErrWebhookSecret = errors.New("WEBHOOK_SECRET is required when UPDATE_MODE=webhook")
+
+// Validate проверяет сочетания настроек, с которыми бот нельзя запускать
+func (c *Config) Validate() error {
+	if c.UpdateMode == "webhook" && c.Webhook.SecretToken == "" {
+		return ErrWebhookSecret
 	}
+	return nil
 }
 
 func getEnv(key, defaultVal string) string {
@@ -45,3 +134,29 @@ func getEnv(key, defaultVal string) string {
 	log.Printf("Переменная окружения %s не задана, используем значение по умолчанию.", key)
 	return defaultVal
 }
+
+func getEnvInt(key string, defaultVal int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultVal
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Некорректное значение переменной %s=%q, используем %d.", key, value, defaultVal)
+		return defaultVal
+	}
+	return n
+}
+
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultVal
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Некорректное значение переменной %s=%q, используем %s.", key, value, defaultVal)
+		return defaultVal
+	}
+	return d
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		secret  string
+		wantErr error
+	}{
+		{"polling without secret", "polling", "", nil},
+		{"webhook with secret", "webhook", "s3cret", nil},
+		{"webhook without secret", "webhook", "", ErrWebhookSecret},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{UpdateMode: tt.mode, Webhook: WebhookConfig{SecretToken: tt.secret}}
+			if err := cfg.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+// Package bankimport разбирает выписки банков в CSV по настраиваемым шаблонам
+// (номера колонок, формат даты, десятичный разделитель, кодировка).
+package bankimport
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"finuchet-bot/internal/currency"
+	"finuchet-bot/internal/money"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// MaxRows ограничивает размер одной выписки
+const MaxRows = 10000
+
+var ErrTooManyRows = fmt.Errorf("statement has more than %d rows", MaxRows)
+
+// Layout описывает формат CSV-выписки. Номера колонок начинаются с нуля.
+type Layout struct {
+	Name              string `json:"name"`
+	Title             string `json:"title"`
+	Delimiter         string `json:"delimiter"`          // По умолчанию ";"
+	Encoding          string `json:"encoding"`           // "utf-8" (по умолчанию) или "cp1251"
+	SkipRows          int    `json:"skip_rows"`          // Строки заголовка
+	DateColumn        int    `json:"date_column"`        //
+	DateFormat        string `json:"date_format"`        // Формат Go, например "02.01.2006"
+	AmountColumn      int    `json:"amount_column"`      // Сумма со знаком: отрицательная - расход
+	DescriptionColumn int    `json:"description_column"` //
+	DecimalSeparator  string `json:"decimal_separator"`  // "," или "." (по умолчанию)
+	InvertSign        bool   `json:"invert_sign"`        // Банк пишет расходы положительными числами
+	CurrencyColumn    *int   `json:"currency_column"`    // Колонка с кодом валюты, если есть
+	Currency          string `json:"currency"`           // Валюта всех строк, если колонки нет; пусто - базовая
+}
+
+// DefaultLayouts - встроенные шаблоны
+func DefaultLayouts() []Layout {
+	currencyColumn := 5
+	return []Layout{
+		{
+			Name: "tinkoff", Title: "Т-Банк",
+			Delimiter: ";", Encoding: "cp1251", SkipRows: 1,
+			DateColumn: 0, DateFormat: "02.01.2006 15:04:05",
+			AmountColumn: 4, DescriptionColumn: 11, DecimalSeparator: ",",
+			CurrencyColumn: &currencyColumn,
+		},
+		{
+			Name: "generic", Title: "Дата;Сумма;Описание",
+			Delimiter: ";", Encoding: "utf-8", SkipRows: 1,
+			DateColumn: 0, DateFormat: "02.01.2006",
+			AmountColumn: 1, DescriptionColumn: 2, DecimalSeparator: ",",
+		},
+		{
+			Name: "generic_iso", Title: "date,amount,description",
+			Delimiter: ",", Encoding: "utf-8", SkipRows: 1,
+			DateColumn: 0, DateFormat: "2006-01-02",
+			AmountColumn: 1, DescriptionColumn: 2, DecimalSeparator: ".",
+		},
+	}
+}
+
+// LoadLayouts читает дополнительные шаблоны из JSON-файла (массив Layout)
+func LoadLayouts(path string) ([]Layout, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var layouts []Layout
+	if err := json.Unmarshal(data, &layouts); err != nil {
+		return nil, fmt.Errorf("parse import layouts %s: %v", path, err)
+	}
+	for _, l := range layouts {
+		if l.Name == "" || l.DateFormat == "" {
+			return nil, fmt.Errorf("import layout in %s must have name and date_format", path)
+		}
+	}
+	return layouts, nil
+}
+
+// Row - операция из выписки
+type Row struct {
+	Line        int
+	Date        time.Time
+	Amount      money.Amount // Всегда положительная
+	Type        string       // "income" или "expense"
+	Currency    string       // Код ISO 4217; пусто - базовая валюта пользователя
+	Description string
+}
+
+// Hash - отпечаток операции для поиска дублей
+func (r Row) Hash() string {
+	return Hash(r.Date, r.Type, r.Amount, r.Description)
+}
+
+// Hash вычисляет отпечаток операции по дате, типу, сумме и описанию
+func Hash(date time.Time, transactionType string, amount money.Amount, description string) string {
+	description = strings.ToLower(strings.Join(strings.Fields(description), " "))
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%s", date.Format("2006-01-02"), transactionType, amount, description)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Result - разобранная выписка
+type Result struct {
+	Rows     []Row
+	Skipped  int   // Строки, которые не удалось разобрать (итоги, пустые строки)
+	FirstErr error // Причина пропуска первой из них
+}
+
+// Parse разбирает выписку по шаблону. Нераспознанные строки пропускаются и учитываются в Skipped.
+func Parse(r io.Reader, layout Layout) (*Result, error) {
+	switch strings.ToLower(layout.Encoding) {
+	case "cp1251", "windows-1251":
+		r = charmap.Windows1251.NewDecoder().Reader(r)
+	case "", "utf-8", "utf8":
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", layout.Encoding)
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = ';'
+	if layout.Delimiter != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(layout.Delimiter)
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	result := &Result{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if line <= layout.SkipRows {
+			continue
+		}
+		if len(result.Rows) >= MaxRows {
+			return nil, ErrTooManyRows
+		}
+
+		row, err := parseRecord(record, layout)
+		if err != nil {
+			result.Skipped++
+			if result.FirstErr == nil {
+				result.FirstErr = fmt.Errorf("line %d: %w", line, err)
+			}
+			continue
+		}
+		row.Line = line
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+func parseRecord(record []string, layout Layout) (Row, error) {
+	field := func(i int) (string, error) {
+		if i < 0 || i >= len(record) {
+			return "", fmt.Errorf("no column %d", i)
+		}
+		return strings.TrimSpace(strings.TrimPrefix(record[i], "\ufeff")), nil
+	}
+
+	dateValue, err := field(layout.DateColumn)
+	if err != nil {
+		return Row{}, err
+	}
+	date, err := time.Parse(layout.DateFormat, dateValue)
+	if err != nil {
+		return Row{}, fmt.Errorf("invalid date %q", dateValue)
+	}
+
+	amountValue, err := field(layout.AmountColumn)
+	if err != nil {
+		return Row{}, err
+	}
+	amount, err := parseAmount(amountValue, layout.DecimalSeparator)
+	if err != nil {
+		return Row{}, err
+	}
+	if layout.InvertSign {
+		amount = -amount
+	}
+
+	description, err := field(layout.DescriptionColumn)
+	if err != nil {
+		return Row{}, err
+	}
+
+	code := layout.Currency
+	if layout.CurrencyColumn != nil {
+		if code, err = field(*layout.CurrencyColumn); err != nil {
+			return Row{}, err
+		}
+	}
+	if code != "" {
+		parsed, ok := currency.Parse(code)
+		if !ok {
+			return Row{}, fmt.Errorf("%w %q", currency.ErrUnknownCurrency, code)
+		}
+		code = parsed
+	}
+
+	row := Row{
+		Date:        time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC),
+		Amount:      amount,
+		Type:        "income",
+		Currency:    code,
+		Description: description,
+	}
+	if amount < 0 {
+		row.Amount = -amount
+		row.Type = "expense"
+	}
+	return row, nil
+}
+
+var errZeroAmount = errors.New("zero amount")
+
+// Сумма с учетом десятичного разделителя; пробелы и разделители тысяч отбрасываются
+func parseAmount(value, decimalSeparator string) (money.Amount, error) {
+	if decimalSeparator == "" {
+		decimalSeparator = "."
+	}
+	thousands := ","
+	if decimalSeparator == "," {
+		thousands = "."
+	}
+
+	value = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || string(r) == thousands || r == '\'' {
+			return -1
+		}
+		return r
+	}, value)
+	value = strings.Replace(value, decimalSeparator, ".", 1)
+	value = strings.Replace(value, "−", "-", 1) // Типографский минус
+
+	amount, err := money.Parse(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q: %w", value, err)
+	}
+	if amount == 0 {
+		return 0, errZeroAmount
+	}
+	return amount, nil
+}
@@ -0,0 +1,178 @@
+package bankimport
+
+import (
+	"errors"
+	"finuchet-bot/internal/currency"
+	"finuchet-bot/internal/money"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func layout(t *testing.T, name string) Layout {
+	t.Helper()
+	for _, l := range DefaultLayouts() {
+		if l.Name == name {
+			return l
+		}
+	}
+	t.Fatalf("no layout %q", name)
+	return Layout{}
+}
+
+func TestParseCP1251(t *testing.T) {
+	f, err := os.Open("testdata/tinkoff_cp1251.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	result, err := Parse(f, layout(t, "tinkoff"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := []Row{
+		{Line: 2, Date: time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), Amount: 1250_50, Type: "expense", Currency: "RUB", Description: "Кофейня «Ёлка»"},
+		{Line: 3, Date: time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), Amount: 1250_50, Type: "expense", Currency: "RUB", Description: "Кофейня «Ёлка»"},
+		{Line: 4, Date: time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC), Amount: 85000_00, Type: "income", Currency: "RUB", Description: "Зарплата ООО Ромашка"},
+		{Line: 5, Date: time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC), Amount: 20_00, Type: "expense", Currency: "USD", Description: "Netflix; подписка"},
+	}
+	if len(result.Rows) != len(want) {
+		t.Fatalf("parsed %d rows, want %d: %+v", len(result.Rows), len(want), result.Rows)
+	}
+	for i, row := range result.Rows {
+		if row != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, row, want[i])
+		}
+	}
+	// Строка итогов без даты пропускается
+	if result.Skipped != 1 || result.FirstErr == nil || !strings.Contains(result.FirstErr.Error(), "line 6") {
+		t.Errorf("Skipped = %d, FirstErr = %v; want the totals line 6 skipped", result.Skipped, result.FirstErr)
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	currencyColumn := 3
+	tests := []struct {
+		name    string
+		layout  Layout
+		line    string
+		want    Row
+		wantErr error // nil - строка разбирается
+	}{
+		{
+			name:   "generic with comma decimals",
+			layout: layout(t, "generic"),
+			line:   "05.03.2026;-1 200,50;Такси",
+			want:   Row{Date: time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), Amount: 1200_50, Type: "expense", Description: "Такси"},
+		},
+		{
+			name:   "dot as thousands separator",
+			layout: layout(t, "generic"),
+			line:   "05.03.2026;1.200,50;Возврат",
+			want:   Row{Date: time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), Amount: 1200_50, Type: "income", Description: "Возврат"},
+		},
+		{
+			name:   "iso dates and comma as thousands separator",
+			layout: layout(t, "generic_iso"),
+			line:   `2026-03-05,"1,200.50",Salary`,
+			want:   Row{Date: time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), Amount: 1200_50, Type: "income", Description: "Salary"},
+		},
+		{
+			name:   "typographic minus",
+			layout: layout(t, "generic"),
+			line:   "05.03.2026;−350;Кафе",
+			want:   Row{Date: time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), Amount: 350_00, Type: "expense", Description: "Кафе"},
+		},
+		{
+			name: "custom columns, inverted sign and currency column",
+			layout: Layout{Delimiter: "|", DateColumn: 2, DateFormat: "2.1.06", AmountColumn: 0, DescriptionColumn: 1,
+				InvertSign: true, CurrencyColumn: &currencyColumn},
+			line: "99.90|Steam|5.3.26|usd",
+			want: Row{Date: time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), Amount: 99_90, Type: "expense", Currency: "USD", Description: "Steam"},
+		},
+		{
+			name:   "currency of the whole statement",
+			layout: Layout{DateColumn: 0, DateFormat: "02.01.2006", AmountColumn: 1, DescriptionColumn: 2, Currency: "₸"},
+			line:   "05.03.2026;-4500;Magnum",
+			want:   Row{Date: time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), Amount: 4500_00, Type: "expense", Currency: "KZT", Description: "Magnum"},
+		},
+		{name: "zero amount", layout: layout(t, "generic"), line: "05.03.2026;0,00;Комиссия", wantErr: errZeroAmount},
+		{name: "too many decimals", layout: layout(t, "generic"), line: "05.03.2026;10,005;Кафе", wantErr: money.ErrPrecision},
+		{name: "unknown currency", layout: Layout{DateFormat: "02.01.2006", AmountColumn: 1, DescriptionColumn: 2, CurrencyColumn: &currencyColumn},
+			line: "05.03.2026;10;Кафе;XYZ", wantErr: currency.ErrUnknownCurrency},
+		{name: "bad date", layout: layout(t, "generic"), line: "5 марта;10;Кафе", wantErr: errAny},
+		{name: "missing column", layout: layout(t, "generic"), line: "05.03.2026;10", wantErr: errAny},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := tt.layout
+			l.SkipRows = 0
+			result, err := Parse(strings.NewReader(tt.line), l)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if tt.wantErr != nil {
+				if len(result.Rows) != 0 || result.Skipped != 1 {
+					t.Fatalf("rows %+v, skipped %d; want the line skipped", result.Rows, result.Skipped)
+				}
+				if tt.wantErr != errAny && !errors.Is(result.FirstErr, tt.wantErr) {
+					t.Errorf("FirstErr = %v, want %v", result.FirstErr, tt.wantErr)
+				}
+				return
+			}
+			if len(result.Rows) != 1 {
+				t.Fatalf("parsed %d rows, want 1 (skip reason: %v)", len(result.Rows), result.FirstErr)
+			}
+			tt.want.Line = 1
+			if got := result.Rows[0]; got != tt.want {
+				t.Errorf("row = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Любая причина пропуска строки
+var errAny = errors.New("any error")
+
+func TestParseUnsupportedEncoding(t *testing.T) {
+	if _, err := Parse(strings.NewReader(""), Layout{Encoding: "koi8-r"}); err == nil {
+		t.Error("Parse() with koi8-r succeeded, want an error")
+	}
+}
+
+func TestHash(t *testing.T) {
+	date := time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)
+	base := Hash(date, "expense", 350_00, "Кофейня «Ёлка»")
+
+	same := []struct {
+		name string
+		hash string
+	}{
+		{"case and spaces", Hash(date, "expense", 350_00, "  кофейня   «ЁЛКА» ")},
+		{"time of day", Hash(date.Add(19*time.Hour), "expense", 350_00, "Кофейня «Ёлка»")},
+		{"row", Row{Date: date, Type: "expense", Amount: 350_00, Description: "Кофейня «Ёлка»", Line: 7}.Hash()},
+	}
+	for _, tt := range same {
+		if tt.hash != base {
+			t.Errorf("%s: hash differs", tt.name)
+		}
+	}
+
+	different := []struct {
+		name string
+		hash string
+	}{
+		{"date", Hash(date.AddDate(0, 0, 1), "expense", 350_00, "Кофейня «Ёлка»")},
+		{"type", Hash(date, "income", 350_00, "Кофейня «Ёлка»")},
+		{"amount", Hash(date, "expense", 350_01, "Кофейня «Ёлка»")},
+		{"description", Hash(date, "expense", 350_00, "Кофейня «Ёлка» #2")},
+	}
+	for _, tt := range different {
+		if tt.hash == base {
+			t.Errorf("%s: hash is the same", tt.name)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package charts
+
+import (
+	"io"
+	"math"
+)
+
+// Month - доходы и расходы за месяц
+type Month struct {
+	Label   string // Подпись под столбцами, например "03.26"
+	Income  float64
+	Expense float64
+}
+
+// Bars рисует парные столбцы доходов и расходов по месяцам
+func Bars(w io.Writer, title string, months []Month) error {
+	var max float64
+	for _, m := range months {
+		max = math.Max(max, math.Max(m.Income, m.Expense))
+	}
+	if len(months) == 0 || max == 0 {
+		return ErrNoData
+	}
+
+	c, err := newCanvas(title)
+	if err != nil {
+		return err
+	}
+	p := newPlot(c, 0, max)
+
+	slot := float64(p.right-p.left) / float64(len(months))
+	barWidth := int(slot * 0.35)
+	for i, m := range months {
+		x := p.left + int(slot*float64(i)+slot*0.15)
+		c.rect(x, p.y(m.Income), x+barWidth, p.y(0), incomeColor)
+		c.rect(x+barWidth, p.y(m.Expense), x+2*barWidth, p.y(0), expenseColor)
+		c.text(textFace, m.Label, x+barWidth, p.bottom+20, alignCenter)
+	}
+
+	// Легенда
+	c.rect(p.left, height-24, p.left+14, height-10, incomeColor)
+	c.text(textFace, "Доходы", p.left+20, height-11, alignLeft)
+	c.rect(p.left+110, height-24, p.left+124, height-10, expenseColor)
+	c.text(textFace, "Расходы", p.left+130, height-11, alignLeft)
+	return c.encode(w)
+}
@@ -0,0 +1,247 @@
+// Package charts рисует диаграммы для отчетов в PNG без внешних сервисов.
+// Результат зависит только от входных данных, поэтому одинаковые данные дают
+// побайтно одинаковые изображения.
+package charts
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// Виды диаграмм, используемые в кнопках отчета
+const (
+	KindCategories = "pie"     // Доли расходов по категориям
+	KindMonthly    = "monthly" // Доходы и расходы по месяцам
+	KindBalance    = "balance" // Накопленный баланс
+)
+
+var (
+	ErrNoData      = errors.New("no data for chart") // Нечего рисовать
+	ErrUnknownKind = errors.New("unknown chart kind")
+)
+
+// Supported сообщает, умеет ли пакет рисовать диаграмму вида kind
+func Supported(kind string) bool {
+	switch kind {
+	case KindCategories, KindMonthly, KindBalance:
+		return true
+	}
+	return false
+}
+
+const (
+	width  = 800
+	height = 500
+
+	marginLeft   = 90
+	marginRight  = 30
+	marginTop    = 60
+	marginBottom = 60
+
+	fontSize  = 14
+	titleSize = 20
+)
+
+var (
+	background = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	textColor  = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	gridColor  = color.RGBA{0xe0, 0xe0, 0xe0, 0xff}
+	axisColor  = color.RGBA{0x99, 0x99, 0x99, 0xff}
+
+	incomeColor  = color.RGBA{0x4c, 0xaf, 0x50, 0xff}
+	expenseColor = color.RGBA{0xe5, 0x39, 0x35, 0xff}
+	lineColor    = color.RGBA{0x1e, 0x88, 0xe5, 0xff}
+
+	// Цвета секторов в порядке убывания суммы
+	palette = []color.RGBA{
+		{0x1e, 0x88, 0xe5, 0xff}, {0xe5, 0x39, 0x35, 0xff}, {0x43, 0xa0, 0x47, 0xff},
+		{0xfb, 0x8c, 0x00, 0xff}, {0x8e, 0x24, 0xaa, 0xff}, {0x00, 0xac, 0xc1, 0xff},
+		{0xfd, 0xd8, 0x35, 0xff}, {0x6d, 0x4c, 0x41, 0xff}, {0x9e, 0x9e, 0x9e, 0xff},
+	}
+)
+
+// Шрифты загружаются один раз; шрифт Go содержит кириллицу
+var (
+	fontsOnce sync.Once
+	fontsErr  error
+	textFace  font.Face
+	titleFace font.Face
+)
+
+func loadFonts() error {
+	fontsOnce.Do(func() {
+		var f *opentype.Font
+		if f, fontsErr = opentype.Parse(goregular.TTF); fontsErr != nil {
+			return
+		}
+		if textFace, fontsErr = opentype.NewFace(f, &opentype.FaceOptions{Size: fontSize, DPI: 72, Hinting: font.HintingFull}); fontsErr != nil {
+			return
+		}
+		titleFace, fontsErr = opentype.NewFace(f, &opentype.FaceOptions{Size: titleSize, DPI: 72, Hinting: font.HintingFull})
+	})
+	return fontsErr
+}
+
+// Холст диаграммы
+type canvas struct {
+	img *image.RGBA
+}
+
+func newCanvas(title string) (*canvas, error) {
+	if err := loadFonts(); err != nil {
+		return nil, err
+	}
+	c := &canvas{img: image.NewRGBA(image.Rect(0, 0, width, height))}
+	draw.Draw(c.img, c.img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	c.text(titleFace, title, width/2, 35, alignCenter)
+	return c, nil
+}
+
+func (c *canvas) encode(w io.Writer) error {
+	return (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(w, c.img)
+}
+
+type align int
+
+const (
+	alignLeft align = iota
+	alignCenter
+	alignRight
+)
+
+// Текст с базовой линией y; x - левый край, середина или правый край в зависимости от a
+func (c *canvas) text(face font.Face, s string, x, y int, a align) {
+	d := &font.Drawer{Dst: c.img, Src: image.NewUniform(textColor), Face: face}
+	advance := d.MeasureString(s).Round()
+	switch a {
+	case alignCenter:
+		x -= advance / 2
+	case alignRight:
+		x -= advance
+	}
+	d.Dot = fixed.P(x, y)
+	d.DrawString(s)
+}
+
+func (c *canvas) rect(x0, y0, x1, y1 int, col color.Color) {
+	draw.Draw(c.img, image.Rect(x0, y0, x1, y1), image.NewUniform(col), image.Point{}, draw.Over)
+}
+
+// Закрашенный многоугольник со сглаживанием
+func (c *canvas) polygon(points [][2]float32, col color.Color) {
+	if len(points) < 3 {
+		return
+	}
+	r := vector.NewRasterizer(width, height)
+	r.MoveTo(points[0][0], points[0][1])
+	for _, p := range points[1:] {
+		r.LineTo(p[0], p[1])
+	}
+	r.ClosePath()
+	r.Draw(c.img, c.img.Bounds(), image.NewUniform(col), image.Point{})
+}
+
+// Отрезок толщиной thickness
+func (c *canvas) line(x0, y0, x1, y1, thickness float32, col color.Color) {
+	dx, dy := x1-x0, y1-y0
+	length := float32(math.Hypot(float64(dx), float64(dy)))
+	if length == 0 {
+		return
+	}
+	nx, ny := -dy/length*thickness/2, dx/length*thickness/2
+	c.polygon([][2]float32{{x0 + nx, y0 + ny}, {x1 + nx, y1 + ny}, {x1 - nx, y1 - ny}, {x0 - nx, y0 - ny}}, col)
+}
+
+// Область построения графиков с осью значений
+type plot struct {
+	min, max float64
+	left     int
+	right    int
+	top      int
+	bottom   int
+}
+
+// Область построения с горизонтальной сеткой по "круглым" значениям, включающая ноль
+func newPlot(c *canvas, min, max float64) *plot {
+	min, max = math.Min(min, 0), math.Max(max, 0)
+	if min == max {
+		max = 1
+	}
+	step := niceStep((max - min) / 5)
+	min = math.Floor(min/step) * step
+	max = math.Ceil(max/step) * step
+
+	p := &plot{min: min, max: max,
+		left: marginLeft, right: width - marginRight, top: marginTop, bottom: height - marginBottom}
+	for v := min; v <= max+step/2; v += step {
+		y := p.y(v)
+		col := gridColor
+		if math.Abs(v) < step/2 {
+			col = axisColor
+		}
+		c.rect(p.left, y, p.right, y+1, col)
+		c.text(textFace, formatValue(v), p.left-8, y+5, alignRight)
+	}
+	return p
+}
+
+// Координата y для значения v
+func (p *plot) y(v float64) int {
+	return p.bottom - int(math.Round((v-p.min)/(p.max-p.min)*float64(p.bottom-p.top)))
+}
+
+// Шаг сетки вида 1, 2 или 5, умноженных на степень десяти
+func niceStep(raw float64) float64 {
+	if raw <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if raw <= m*magnitude {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// Подпись оси: 1500, 25k, 1.2M
+func formatValue(v float64) string {
+	abs := math.Abs(v)
+	switch {
+	case abs >= 1e6:
+		return trimZeros(strconv.FormatFloat(v/1e6, 'f', 1, 64)) + "M"
+	case abs >= 1e4:
+		return trimZeros(strconv.FormatFloat(v/1e3, 'f', 1, 64)) + "k"
+	}
+	return strconv.FormatFloat(v, 'f', 0, 64)
+}
+
+func trimZeros(s string) string {
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// Обрезка подписи до max символов
+func shorten(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,113 @@
+package charts
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Эталонные изображения перезаписываются командой go test ./internal/charts -update
+var update = flag.Bool("update", false, "rewrite golden PNG files in testdata")
+
+func checkGolden(t *testing.T, name string, render func(w io.Writer) error) {
+	t.Helper()
+	var got bytes.Buffer
+	if err := render(&got); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	// Одинаковые данные дают побайтно одинаковое изображение
+	var again bytes.Buffer
+	if err := render(&again); err != nil {
+		t.Fatalf("second render: %v", err)
+	}
+	if !bytes.Equal(got.Bytes(), again.Bytes()) {
+		t.Fatal("two renders of the same data differ")
+	}
+
+	path := filepath.Join("testdata", name+".golden.png")
+	if *update {
+		if err := os.WriteFile(path, got.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		failed := filepath.Join(t.TempDir(), name+".png")
+		os.WriteFile(failed, got.Bytes(), 0o644)
+		t.Errorf("%s differs from %s; rendered image saved to %s", name, path, failed)
+	}
+}
+
+func TestPieGolden(t *testing.T) {
+	slices := []Slice{
+		{"Аренда", 30000}, {"Продукты 🛒", 18250.40}, {"Одежда", 5300}, {"Транспорт", 4200},
+		{"Кафе и рестораны с длинным названием", 3100.5}, {"Здоровье", 2400}, {"Подарки", 1500},
+		{"Связь", 950}, {"Книги", 700}, {"Кино", 600}, // Последние три объединяются в "Прочие"
+		{"Возврат", 0}, // Нулевые секторы не рисуются
+	}
+	checkGolden(t, "pie", func(w io.Writer) error { return Pie(w, "Расходы по категориям", slices) })
+}
+
+func TestBarsGolden(t *testing.T) {
+	months := []Month{
+		{"11.25", 95000, 71200.5}, {"12.25", 120000, 134800}, {"01.26", 95000, 64000},
+		{"02.26", 0, 0}, {"03.26", 97500, 80150.25}, {"04.26", 0, 12000},
+	}
+	checkGolden(t, "bars", func(w io.Writer) error { return Bars(w, "Доходы и расходы по месяцам", months) })
+}
+
+func TestLineGolden(t *testing.T) {
+	var points []Point
+	balance := 5000.0
+	for day := 1; day <= 30; day++ {
+		switch {
+		case day%10 == 0:
+			balance += 40000
+		case day%3 == 0:
+			balance -= 12500
+		default:
+			balance -= 700
+		}
+		points = append(points, Point{Label: fmt.Sprintf("%02d.03", day), Value: balance})
+	}
+	checkGolden(t, "line", func(w io.Writer) error { return Line(w, "Накопленный баланс", points) })
+}
+
+func TestLineSinglePointGolden(t *testing.T) {
+	points := []Point{{"05.03", -1200}}
+	checkGolden(t, "line_single", func(w io.Writer) error { return Line(w, "Накопленный баланс", points) })
+}
+
+func TestEmptyData(t *testing.T) {
+	tests := []struct {
+		name   string
+		render func(w io.Writer) error
+	}{
+		{"pie without slices", func(w io.Writer) error { return Pie(w, "", nil) }},
+		{"pie with zero slices", func(w io.Writer) error { return Pie(w, "", []Slice{{"Кафе", 0}, {"Такси", -5}}) }},
+		{"bars without months", func(w io.Writer) error { return Bars(w, "", nil) }},
+		{"bars with zero months", func(w io.Writer) error { return Bars(w, "", []Month{{Label: "01.26"}, {Label: "02.26"}}) }},
+		{"line without points", func(w io.Writer) error { return Line(w, "", nil) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.render(&buf); !errors.Is(err, ErrNoData) {
+				t.Fatalf("error = %v, want ErrNoData", err)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("wrote %d bytes for empty data", buf.Len())
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package charts
+
+import (
+	"io"
+	"math"
+)
+
+// Point - значение на дату
+type Point struct {
+	Label string // Подпись на оси, например "05.03"
+	Value float64
+}
+
+// Сколько подписей дат помещается под осью
+const maxLineLabels = 8
+
+// Line рисует линейный график; подписи дат прореживаются, чтобы не налезать друг на друга
+func Line(w io.Writer, title string, points []Point) error {
+	if len(points) == 0 {
+		return ErrNoData
+	}
+
+	min, max := points[0].Value, points[0].Value
+	for _, pt := range points {
+		min, max = math.Min(min, pt.Value), math.Max(max, pt.Value)
+	}
+
+	c, err := newCanvas(title)
+	if err != nil {
+		return err
+	}
+	p := newPlot(c, min, max)
+
+	x := func(i int) float32 {
+		if len(points) == 1 {
+			return float32(p.left+p.right) / 2
+		}
+		return float32(p.left) + float32(p.right-p.left)*float32(i)/float32(len(points)-1)
+	}
+
+	for i := 1; i < len(points); i++ {
+		c.line(x(i-1), float32(p.y(points[i-1].Value)), x(i), float32(p.y(points[i].Value)), 3, lineColor)
+	}
+	if len(points) == 1 {
+		y := float32(p.y(points[0].Value))
+		c.line(x(0)-3, y, x(0)+3, y, 6, lineColor)
+	}
+
+	every := (len(points) + maxLineLabels - 1) / maxLineLabels
+	for i := 0; i < len(points); i += every {
+		c.text(textFace, points[i].Label, int(x(i)), p.bottom+20, alignCenter)
+	}
+	return c.encode(w)
+}
@@ -0,0 +1,64 @@
+package charts
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// Сколько секторов рисовать отдельно; остальные объединяются в "Прочие"
+const maxSlices = 8
+
+// Slice - сектор круговой диаграммы
+type Slice struct {
+	Label string
+	Value float64
+}
+
+// Pie рисует круговую диаграмму с легендой. Секторы ожидаются по убыванию значения.
+func Pie(w io.Writer, title string, slices []Slice) error {
+	var total float64
+	var parts []Slice
+	for _, s := range slices {
+		if s.Value <= 0 {
+			continue
+		}
+		total += s.Value
+		if len(parts) < maxSlices {
+			parts = append(parts, s)
+		} else {
+			parts[maxSlices-1].Label = "Прочие"
+			parts[maxSlices-1].Value += s.Value
+		}
+	}
+	if total == 0 {
+		return ErrNoData
+	}
+
+	c, err := newCanvas(title)
+	if err != nil {
+		return err
+	}
+
+	const radius = 180
+	cx, cy := float64(marginLeft+radius), float64(marginTop+20+radius)
+	angle := -math.Pi / 2 // Первый сектор начинается сверху
+	for i, s := range parts {
+		sweep := s.Value / total * 2 * math.Pi
+		points := [][2]float32{{float32(cx), float32(cy)}}
+		// Дуга аппроксимируется отрезками не длиннее одного градуса
+		steps := int(math.Ceil(sweep / (math.Pi / 180)))
+		for j := 0; j <= steps; j++ {
+			a := angle + sweep*float64(j)/float64(steps)
+			points = append(points, [2]float32{float32(cx + radius*math.Cos(a)), float32(cy + radius*math.Sin(a))})
+		}
+		c.polygon(points, palette[i%len(palette)])
+		angle += sweep
+
+		// Легенда справа
+		x, y := marginLeft+2*radius+50, marginTop+40+i*32
+		c.rect(x, y-13, x+16, y+3, palette[i%len(palette)])
+		c.text(textFace, fmt.Sprintf("%s — %.1f%%", shorten(s.Label, 24), s.Value/total*100), x+26, y, alignLeft)
+	}
+	return c.encode(w)
+}
@@ -0,0 +1,116 @@
+// Package currency распознает коды валют и загружает курсы из подключаемых источников.
+package currency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Default - валюта операций и базовая валюта пользователя по умолчанию
+const Default = "RUB"
+
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// Обозначения валют, которые можно писать вместо кода ISO 4217
+var aliases = map[string]string{
+	"р": "RUB", "руб": "RUB", "рубль": "RUB", "рублей": "RUB", "₽": "RUB",
+	"$": "USD", "долл": "USD", "доллар": "USD", "долларов": "USD",
+	"€": "EUR", "евро": "EUR",
+	"£": "GBP", "фунт": "GBP",
+	"¥": "CNY", "юань": "CNY", "юаней": "CNY",
+	"₸": "KZT", "тенге": "KZT",
+	"₺": "TRY", "лир": "TRY", "лира": "TRY",
+	"₾": "GEL", "лари": "GEL",
+	"֏": "AMD", "драм": "AMD",
+}
+
+// Поддерживаемые коды ISO 4217. Список ограничен, чтобы слова из трех латинских букв
+// в быстром вводе ("350 bar") не принимались за валюту.
+var codes = map[string]bool{
+	"RUB": true, "USD": true, "EUR": true, "GBP": true, "CNY": true, "JPY": true, "CHF": true,
+	"KZT": true, "BYN": true, "UAH": true, "UZS": true, "KGS": true, "TJS": true, "AZN": true,
+	"AMD": true, "GEL": true, "MDL": true, "TRY": true, "AED": true, "THB": true, "VND": true,
+	"IDR": true, "INR": true, "KRW": true, "HKD": true, "SGD": true, "CAD": true, "AUD": true,
+	"PLN": true, "CZK": true, "SEK": true, "NOK": true, "RSD": true, "ILS": true, "EGP": true,
+}
+
+// Parse возвращает код ISO 4217 по коду ("usd") или обозначению ("$", "евро")
+func Parse(s string) (string, bool) {
+	s = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), ".")
+	if code, ok := aliases[s]; ok {
+		return code, true
+	}
+	if code := strings.ToUpper(s); codes[code] {
+		return code, true
+	}
+	return "", false
+}
+
+// Provider - источник курсов валют.
+// Rates возвращает, сколько единиц quote стоит одна единица каждой валюты на дату.
+type Provider interface {
+	Rates(ctx context.Context, quote string, date time.Time) (map[string]float64, error)
+}
+
+// FileProvider читает курсы из локального JSON-файла вида
+// {"quote": "RUB", "rates": {"USD": 92.5, "EUR": 100.1}}.
+// Курсы считаются действующими на запрошенную дату.
+type FileProvider struct {
+	Path string
+}
+
+type ratesFile struct {
+	Quote string             `json:"quote"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+func (p FileProvider) Rates(ctx context.Context, quote string, date time.Time) (map[string]float64, error) {
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	var file ratesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse rates file %s: %v", p.Path, err)
+	}
+	if file.Quote == "" {
+		file.Quote = Default
+	}
+	return convertRates(file.Quote, file.Rates, quote)
+}
+
+// StaticProvider возвращает заранее заданные курсы к Quote; используется как подмена
+// настоящего источника в тестах и при ручной настройке
+type StaticProvider struct {
+	Quote  string
+	Values map[string]float64
+}
+
+func (p StaticProvider) Rates(ctx context.Context, quote string, date time.Time) (map[string]float64, error) {
+	return convertRates(p.Quote, p.Values, quote)
+}
+
+// Пересчет курсов к другой валюте через общую: USD/EUR = USD/RUB / EUR/RUB
+func convertRates(from string, rates map[string]float64, to string) (map[string]float64, error) {
+	result := make(map[string]float64, len(rates))
+	divisor := 1.0
+	if to != from {
+		var ok bool
+		if divisor, ok = rates[to]; !ok || divisor <= 0 {
+			return nil, fmt.Errorf("%w: no rate for %s", ErrUnknownCurrency, to)
+		}
+		result[from] = 1 / divisor
+	}
+	for code, rate := range rates {
+		if code == to || rate <= 0 {
+			continue
+		}
+		result[code] = rate / divisor
+	}
+	return result, nil
+}
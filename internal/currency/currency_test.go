@@ -0,0 +1,75 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestConvertRates(t *testing.T) {
+	rub := map[string]float64{"USD": 90, "EUR": 100, "KZT": 0.2, "XXX": 0}
+
+	tests := []struct {
+		name    string
+		to      string
+		want    map[string]float64
+		wantErr error
+	}{
+		{
+			name: "same quote",
+			to:   "RUB",
+			want: map[string]float64{"USD": 90, "EUR": 100, "KZT": 0.2},
+		},
+		{
+			name: "inverse and cross rates",
+			to:   "USD",
+			want: map[string]float64{"RUB": 1.0 / 90, "EUR": 100.0 / 90, "KZT": 0.2 / 90},
+		},
+		{
+			name: "cross rate to a cheaper currency",
+			to:   "KZT",
+			want: map[string]float64{"RUB": 5, "USD": 450, "EUR": 500},
+		},
+		{name: "missing quote", to: "GBP", wantErr: ErrUnknownCurrency},
+		{name: "quote with non-positive rate", to: "XXX", wantErr: ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := convertRates("RUB", rub, tt.to)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("convertRates() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("convertRates() = %v, want %v", got, tt.want)
+			}
+			for code, want := range tt.want {
+				if math.Abs(got[code]-want) > 1e-12 {
+					t.Errorf("%s/%s = %v, want %v", code, tt.to, got[code], want)
+				}
+			}
+		})
+	}
+}
+
+func TestStaticProvider(t *testing.T) {
+	p := StaticProvider{Quote: "RUB", Values: map[string]float64{"USD": 80, "EUR": 88}}
+	got, err := p.Rates(context.Background(), "EUR", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got["USD"]-80.0/88) > 1e-12 || math.Abs(got["RUB"]-1.0/88) > 1e-12 {
+		t.Errorf("Rates(EUR) = %v", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := map[string]string{"usd": "USD", " $ ": "USD", "руб.": "RUB", "Евро": "EUR", "bar": "", "": ""}
+	for in, want := range tests {
+		got, ok := Parse(in)
+		if got != want || ok != (want != "") {
+			t.Errorf("Parse(%q) = %q, %v, want %q", in, got, ok, want)
+		}
+	}
+}
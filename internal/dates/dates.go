@@ -0,0 +1,126 @@
+package dates
+
+import (
+	"strings"
+	"time"
+)
+
+// Слова, обозначающие день относительно сегодняшнего
+var relativeDays = map[string]int{
+	"сегодня":   0,
+	"вчера":     -1,
+	"позавчера": -2,
+}
+
+// Дни недели: сокращения и полные названия, в том числе в винительном падеже ("в пятницу")
+var weekdays = map[string]time.Weekday{
+	"пн": time.Monday, "понедельник": time.Monday,
+	"вт": time.Tuesday, "вторник": time.Tuesday,
+	"ср": time.Wednesday, "среда": time.Wednesday, "среду": time.Wednesday,
+	"чт": time.Thursday, "четверг": time.Thursday,
+	"пт": time.Friday, "пятница": time.Friday, "пятницу": time.Friday,
+	"сб": time.Saturday, "суббота": time.Saturday, "субботу": time.Saturday,
+	"вс": time.Sunday, "воскресенье": time.Sunday,
+}
+
+// Parse распознает дату, записанную словом ("вчера"), днем недели ("пт") или числами ("05.03", "05.03.2026").
+// День недели означает ближайший такой день не позже сегодняшнего.
+// Возвращает начало дня в часовом поясе now.
+func Parse(word string, now time.Time) (time.Time, bool) {
+	word = strings.ToLower(strings.TrimSpace(word))
+	today := Day(now)
+
+	if offset, ok := relativeDays[word]; ok {
+		return today.AddDate(0, 0, offset), true
+	}
+	if weekday, ok := weekdays[word]; ok {
+		return today.AddDate(0, 0, -((int(today.Weekday()) - int(weekday) + 7) % 7)), true
+	}
+
+	for _, layout := range []string{"02.01.2006", "2.1.2006", "02.01.06", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, word, now.Location()); err == nil {
+			return t, true
+		}
+	}
+	for _, layout := range []string{"02.01", "2.1"} {
+		if t, err := time.ParseInLocation(layout, word, now.Location()); err == nil {
+			// Год не указан: берем текущий, а будущую дату считаем прошлогодней
+			d := time.Date(now.Year(), t.Month(), t.Day(), 0, 0, 0, 0, now.Location())
+			if d.After(today) {
+				d = d.AddDate(-1, 0, 0)
+			}
+			return d, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Слова, обозначающие срок относительно сегодняшнего дня
+var futureDays = map[string]int{
+	"сегодня":     0,
+	"завтра":      1,
+	"послезавтра": 2,
+}
+
+// Месяцы в именительном, родительном и дательном падежах: "июль", "июля", "к июлю"
+var months = map[string]time.Month{
+	"январь": time.January, "января": time.January, "январю": time.January,
+	"февраль": time.February, "февраля": time.February, "февралю": time.February,
+	"март": time.March, "марта": time.March, "марту": time.March,
+	"апрель": time.April, "апреля": time.April, "апрелю": time.April,
+	"май": time.May, "мая": time.May, "маю": time.May,
+	"июнь": time.June, "июня": time.June, "июню": time.June,
+	"июль": time.July, "июля": time.July, "июлю": time.July,
+	"август": time.August, "августа": time.August, "августу": time.August,
+	"сентябрь": time.September, "сентября": time.September, "сентябрю": time.September,
+	"октябрь": time.October, "октября": time.October, "октябрю": time.October,
+	"ноябрь": time.November, "ноября": time.November, "ноябрю": time.November,
+	"декабрь": time.December, "декабря": time.December, "декабрю": time.December,
+}
+
+// ParseFuture распознает срок, например возврата долга: "завтра", "пт", "05.03", "05.03.2027".
+// В отличие от Parse день недели и дата без года означают ближайший такой день не раньше сегодняшнего.
+// Название месяца ("к июлю") означает первое число ближайшего такого месяца после текущего.
+func ParseFuture(word string, now time.Time) (time.Time, bool) {
+	word = strings.ToLower(strings.TrimSpace(word))
+	today := Day(now)
+
+	if offset, ok := futureDays[word]; ok {
+		return today.AddDate(0, 0, offset), true
+	}
+	if month, ok := months[word]; ok {
+		d := time.Date(now.Year(), month, 1, 0, 0, 0, 0, now.Location())
+		if !d.After(today) {
+			d = d.AddDate(1, 0, 0)
+		}
+		return d, true
+	}
+	if weekday, ok := weekdays[word]; ok {
+		return today.AddDate(0, 0, (int(weekday)-int(today.Weekday())+7)%7), true
+	}
+	for _, layout := range []string{"02.01", "2.1"} {
+		if t, err := time.ParseInLocation(layout, word, now.Location()); err == nil {
+			d := time.Date(now.Year(), t.Month(), t.Day(), 0, 0, 0, 0, now.Location())
+			if d.Before(today) {
+				d = d.AddDate(1, 0, 0)
+			}
+			return d, true
+		}
+	}
+	return Parse(word, now)
+}
+
+// Day возвращает начало дня для t
+func Day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// Location возвращает часовой пояс по имени IANA ("Europe/Moscow").
+// Неизвестное имя заменяется UTC, чтобы расписания и отчеты не ломались.
+func Location(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
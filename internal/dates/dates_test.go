@@ -0,0 +1,164 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+func location(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("no timezone data: %v", err)
+	}
+	return loc
+}
+
+type parseCase struct {
+	word string
+	now  time.Time
+	want time.Time // Нулевое значение - слово не распознается
+}
+
+func checkParse(t *testing.T, name string, parse func(string, time.Time) (time.Time, bool), tests []parseCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.word+" at "+tt.now.Format(time.RFC3339), func(t *testing.T) {
+			got, ok := parse(tt.word, tt.now)
+			if ok != !tt.want.IsZero() {
+				t.Fatalf("%s(%q) ok = %v, want %v", name, tt.word, ok, !tt.want.IsZero())
+			}
+			if !ok {
+				return
+			}
+			if !got.Equal(tt.want) || got.Location() != tt.now.Location() {
+				t.Errorf("%s(%q) = %s, want %s", name, tt.word, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	moscow := location(t, "Europe/Moscow")
+	vladivostok := location(t, "Asia/Vladivostok")
+	// Среда, 11 марта 2026
+	now := time.Date(2026, 3, 11, 15, 0, 0, 0, moscow)
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, moscow)
+	}
+
+	checkParse(t, "Parse", Parse, []parseCase{
+		{"05.03.2025", now, day(2025, 3, 5)},
+		{"5.3.2025", now, day(2025, 3, 5)},
+		{"05.03.25", now, day(2025, 3, 5)},
+		{"2025-03-05", now, day(2025, 3, 5)},
+		{"05.03", now, day(2026, 3, 5)},
+		{"11.03", now, day(2026, 3, 11)},
+		// Дата без года в будущем относится к прошлому году
+		{"12.03", now, day(2025, 3, 12)},
+		{"31.12", now, day(2025, 12, 31)},
+		{"29.02.2024", now, day(2024, 2, 29)},
+		{"29.02.2026", now, time.Time{}},
+		{"32.01", now, time.Time{}},
+		{"кафе", now, time.Time{}},
+		{"", now, time.Time{}},
+		// 02:30 11 марта во Владивостоке - еще 10 марта по UTC: "сегодня" считается по местному времени
+		{"11.03", time.Date(2026, 3, 11, 2, 30, 0, 0, vladivostok), time.Date(2026, 3, 11, 0, 0, 0, 0, vladivostok)},
+		{"12.03", time.Date(2026, 3, 11, 2, 30, 0, 0, vladivostok), time.Date(2025, 3, 12, 0, 0, 0, 0, vladivostok)},
+	})
+}
+
+func TestParseRelative(t *testing.T) {
+	moscow := location(t, "Europe/Moscow")
+	berlin := location(t, "Europe/Berlin")
+	vladivostok := location(t, "Asia/Vladivostok")
+	// Среда, 11 марта 2026
+	now := time.Date(2026, 3, 11, 15, 0, 0, 0, moscow)
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, moscow)
+	}
+
+	checkParse(t, "Parse", Parse, []parseCase{
+		{"сегодня", now, day(2026, 3, 11)},
+		{"вчера", now, day(2026, 3, 10)},
+		{"позавчера", now, day(2026, 3, 9)},
+		{"ВЧЕРА", now, day(2026, 3, 10)},
+		{" вчера ", now, day(2026, 3, 10)},
+		{"завтра", now, time.Time{}},
+		// День недели - ближайший не позже сегодняшнего
+		{"пн", now, day(2026, 3, 9)},
+		{"вт", now, day(2026, 3, 10)},
+		{"ср", now, day(2026, 3, 11)},
+		{"среду", now, day(2026, 3, 11)},
+		{"чт", now, day(2026, 3, 5)},
+		{"пятницу", now, day(2026, 3, 6)},
+		{"сб", now, day(2026, 3, 7)},
+		{"воскресенье", now, day(2026, 3, 8)},
+		{"вс", now, day(2026, 3, 8)},
+		{"Понедельник", now, day(2026, 3, 9)},
+		// Через границу месяца
+		{"вчера", time.Date(2026, 3, 1, 10, 0, 0, 0, moscow), day(2026, 2, 28)},
+		{"пт", time.Date(2026, 3, 1, 10, 0, 0, 0, moscow), day(2026, 2, 27)},
+		// Воскресенье 29 марта в Берлине - день перехода на летнее время
+		{"вчера", time.Date(2026, 3, 29, 12, 0, 0, 0, berlin), time.Date(2026, 3, 28, 0, 0, 0, 0, berlin)},
+		{"сб", time.Date(2026, 3, 30, 12, 0, 0, 0, berlin), time.Date(2026, 3, 28, 0, 0, 0, 0, berlin)},
+		// 02:30 11 марта во Владивостоке - по UTC еще вторник, 10 марта
+		{"вчера", time.Date(2026, 3, 11, 2, 30, 0, 0, vladivostok), time.Date(2026, 3, 10, 0, 0, 0, 0, vladivostok)},
+		{"ср", time.Date(2026, 3, 11, 2, 30, 0, 0, vladivostok), time.Date(2026, 3, 11, 0, 0, 0, 0, vladivostok)},
+	})
+}
+
+func TestParseFuture(t *testing.T) {
+	moscow := location(t, "Europe/Moscow")
+	// Среда, 11 марта 2026
+	now := time.Date(2026, 3, 11, 15, 0, 0, 0, moscow)
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, moscow)
+	}
+	lastJanuary := time.Date(2026, 1, 31, 23, 0, 0, 0, moscow)
+
+	checkParse(t, "ParseFuture", ParseFuture, []parseCase{
+		{"сегодня", now, day(2026, 3, 11)},
+		{"завтра", now, day(2026, 3, 12)},
+		{"Послезавтра", now, day(2026, 3, 13)},
+		{"пт", now, day(2026, 3, 13)},
+		{"ср", now, day(2026, 3, 11)},
+		{"пн", now, day(2026, 3, 16)},
+		{"12.03", now, day(2026, 3, 12)},
+		{"11.03", now, day(2026, 3, 11)},
+		// Прошедшая дата без года - в следующем году
+		{"05.03", now, day(2027, 3, 5)},
+		{"05.03.2027", now, day(2027, 3, 5)},
+		{"июль", now, day(2026, 7, 1)},
+		{"июлю", now, day(2026, 7, 1)},
+		// Текущий месяц уже начался: ближайшее первое число такого месяца - через год
+		{"марта", now, day(2027, 3, 1)},
+		// Конец месяца: 31 января + 1 месяц - это 1 февраля, а не 3 марта
+		{"февраль", lastJanuary, day(2026, 2, 1)},
+		{"январю", lastJanuary, day(2027, 1, 1)},
+		{"завтра", lastJanuary, day(2026, 2, 1)},
+		{"потом", now, time.Time{}},
+	})
+}
+
+func TestDay(t *testing.T) {
+	vladivostok := location(t, "Asia/Vladivostok")
+	// 23:30 UTC 31 марта - уже 1 апреля во Владивостоке
+	utc := time.Date(2026, 3, 31, 23, 30, 0, 0, time.UTC)
+
+	if got, want := Day(utc.In(vladivostok)), time.Date(2026, 4, 1, 0, 0, 0, 0, vladivostok); !got.Equal(want) {
+		t.Errorf("Day() in Vladivostok = %s, want %s", got, want)
+	}
+	if got, want := Day(utc), time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Day() in UTC = %s, want %s", got, want)
+	}
+}
+
+func TestLocation(t *testing.T) {
+	if got := Location("Asia/Vladivostok").String(); got != "Asia/Vladivostok" {
+		t.Errorf("Location(Asia/Vladivostok) = %s", got)
+	}
+	if got := Location("Mars/Olympus"); got != time.UTC {
+		t.Errorf("Location(unknown) = %s, want UTC", got)
+	}
+}
@@ -0,0 +1,87 @@
+package dates
+
+import "time"
+
+// Period - полуинтервал дат [From, To). Нулевой Period означает "за все время".
+type Period struct {
+	From time.Time
+	To   time.Time
+}
+
+// IsAll сообщает, что период не ограничен
+func (p Period) IsAll() bool {
+	return p.From.IsZero() && p.To.IsZero()
+}
+
+// Contains сообщает, попадает ли t в период
+func (p Period) Contains(t time.Time) bool {
+	return (p.From.IsZero() || !t.Before(p.From)) && (p.To.IsZero() || t.Before(p.To))
+}
+
+// Названия периодов, используемые в командах и кнопках
+const (
+	PeriodToday     = "today"
+	PeriodWeek      = "week"
+	PeriodMonth     = "month"
+	PeriodLastMonth = "last_month"
+	PeriodYear      = "year"
+	PeriodAll       = "all"
+)
+
+// PeriodByName возвращает период по названию относительно now
+func PeriodByName(name string, now time.Time) (Period, bool) {
+	today := Day(now)
+	switch name {
+	case PeriodToday:
+		return Period{From: today, To: today.AddDate(0, 0, 1)}, true
+	case PeriodWeek:
+		// Неделя начинается с понедельника
+		offset := (int(today.Weekday()) + 6) % 7
+		from := today.AddDate(0, 0, -offset)
+		return Period{From: from, To: from.AddDate(0, 0, 7)}, true
+	case PeriodMonth:
+		from := MonthStart(today)
+		return Period{From: from, To: from.AddDate(0, 1, 0)}, true
+	case PeriodLastMonth:
+		to := MonthStart(today)
+		return Period{From: to.AddDate(0, -1, 0), To: to}, true
+	case PeriodYear:
+		from := time.Date(today.Year(), time.January, 1, 0, 0, 0, 0, today.Location())
+		return Period{From: from, To: today.AddDate(0, 0, 1)}, true
+	case PeriodAll:
+		return Period{}, true
+	}
+	return Period{}, false
+}
+
+// MonthStart возвращает первый день месяца
+func MonthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// Shift сдвигает период на n таких же периодов вперед (n < 0 - назад).
+// Период из целых месяцев сдвигается на столько же месяцев, период с начала года
+// длиннее месяца - на год, остальные - на свою длину в днях. Период "за все время" не изменяется.
+func (p Period) Shift(n int) Period {
+	if p.IsAll() || p.From.IsZero() || p.To.IsZero() {
+		return p
+	}
+	if p.From.Equal(MonthStart(p.From)) && p.To.Equal(MonthStart(p.To)) {
+		months := (p.To.Year()-p.From.Year())*12 + int(p.To.Month()-p.From.Month())
+		return Period{From: p.From.AddDate(0, n*months, 0), To: p.To.AddDate(0, n*months, 0)}
+	}
+	if p.From.Month() == time.January && p.From.Day() == 1 && p.To.Year() == p.From.Year() && p.Days() > 31 {
+		return Period{From: p.From.AddDate(n, 0, 0), To: p.To.AddDate(n, 0, 0)}
+	}
+	days := p.Days()
+	return Period{From: p.From.AddDate(0, 0, n*days), To: p.To.AddDate(0, 0, n*days)}
+}
+
+// Days возвращает число дней в ограниченном периоде
+func (p Period) Days() int {
+	if p.From.IsZero() || p.To.IsZero() {
+		return 0
+	}
+	// Округление учитывает переход на летнее время
+	return int((p.To.Sub(p.From) + 12*time.Hour) / (24 * time.Hour))
+}
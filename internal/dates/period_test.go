@@ -0,0 +1,109 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeriodShift(t *testing.T) {
+	berlin := location(t, "Europe/Berlin")
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		period Period
+		n      int
+		want   Period
+	}{
+		{"next month", Period{date(2026, 1, 1), date(2026, 2, 1)}, 1, Period{date(2026, 2, 1), date(2026, 3, 1)}},
+		{"previous month over new year", Period{date(2026, 1, 1), date(2026, 2, 1)}, -1, Period{date(2025, 12, 1), date(2026, 1, 1)}},
+		{"quarter", Period{date(2026, 1, 1), date(2026, 4, 1)}, 1, Period{date(2026, 4, 1), date(2026, 7, 1)}},
+		{"year to date", Period{date(2026, 1, 1), date(2026, 3, 12)}, -1, Period{date(2025, 1, 1), date(2025, 3, 12)}},
+		{"week", Period{date(2026, 3, 9), date(2026, 3, 16)}, -1, Period{date(2026, 3, 2), date(2026, 3, 9)}},
+		{"arbitrary days", Period{date(2026, 3, 5), date(2026, 3, 15)}, 2, Period{date(2026, 3, 25), date(2026, 4, 4)}},
+		// Период с 31 января не выровнен по месяцам и сдвигается на свою длину в днях
+		{"from month end", Period{date(2026, 1, 31), date(2026, 2, 28)}, 1, Period{date(2026, 2, 28), date(2026, 3, 28)}},
+		{"day at month end", Period{date(2026, 1, 31), date(2026, 2, 1)}, 1, Period{date(2026, 2, 1), date(2026, 2, 2)}},
+		{"all time", Period{}, 1, Period{}},
+		{"open end", Period{From: date(2026, 1, 1)}, 1, Period{From: date(2026, 1, 1)}},
+		// В день перехода на летнее время 23 часа, сдвиг остается на целые сутки
+		{
+			"day across DST",
+			Period{time.Date(2026, 3, 28, 0, 0, 0, 0, berlin), time.Date(2026, 3, 29, 0, 0, 0, 0, berlin)}, 1,
+			Period{time.Date(2026, 3, 29, 0, 0, 0, 0, berlin), time.Date(2026, 3, 30, 0, 0, 0, 0, berlin)},
+		},
+		{
+			"month with DST",
+			Period{time.Date(2026, 3, 1, 0, 0, 0, 0, berlin), time.Date(2026, 4, 1, 0, 0, 0, 0, berlin)}, 1,
+			Period{time.Date(2026, 4, 1, 0, 0, 0, 0, berlin), time.Date(2026, 5, 1, 0, 0, 0, 0, berlin)},
+		},
+		{
+			"week across DST",
+			Period{time.Date(2026, 3, 23, 0, 0, 0, 0, berlin), time.Date(2026, 3, 30, 0, 0, 0, 0, berlin)}, -1,
+			Period{time.Date(2026, 3, 16, 0, 0, 0, 0, berlin), time.Date(2026, 3, 23, 0, 0, 0, 0, berlin)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.period.Shift(tt.n)
+			if !got.From.Equal(tt.want.From) || !got.To.Equal(tt.want.To) {
+				t.Errorf("Shift(%d) = [%s, %s), want [%s, %s)", tt.n, got.From, got.To, tt.want.From, tt.want.To)
+			}
+		})
+	}
+}
+
+func TestPeriodDaysAcrossDST(t *testing.T) {
+	berlin := location(t, "Europe/Berlin")
+	p := Period{time.Date(2026, 3, 29, 0, 0, 0, 0, berlin), time.Date(2026, 3, 30, 0, 0, 0, 0, berlin)}
+	if got := p.Days(); got != 1 {
+		t.Errorf("Days() of the 23-hour DST day = %d, want 1", got)
+	}
+	p = Period{time.Date(2026, 10, 25, 0, 0, 0, 0, berlin), time.Date(2026, 10, 26, 0, 0, 0, 0, berlin)}
+	if got := p.Days(); got != 1 {
+		t.Errorf("Days() of the 25-hour DST day = %d, want 1", got)
+	}
+}
+
+func TestPeriodByName(t *testing.T) {
+	vladivostok := location(t, "Asia/Vladivostok")
+	// 23:30 UTC 31 марта - уже среда, 1 апреля во Владивостоке
+	now := time.Date(2026, 3, 31, 23, 30, 0, 0, time.UTC).In(vladivostok)
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, vladivostok)
+	}
+
+	tests := []struct {
+		name string
+		want Period
+	}{
+		{PeriodToday, Period{date(2026, 4, 1), date(2026, 4, 2)}},
+		{PeriodWeek, Period{date(2026, 3, 30), date(2026, 4, 6)}},
+		{PeriodMonth, Period{date(2026, 4, 1), date(2026, 5, 1)}},
+		{PeriodLastMonth, Period{date(2026, 3, 1), date(2026, 4, 1)}},
+		{PeriodYear, Period{date(2026, 1, 1), date(2026, 4, 2)}},
+		{PeriodAll, Period{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := PeriodByName(tt.name, now)
+			if !ok {
+				t.Fatalf("PeriodByName(%q) not found", tt.name)
+			}
+			if !got.From.Equal(tt.want.From) || !got.To.Equal(tt.want.To) {
+				t.Errorf("PeriodByName(%q) = [%s, %s), want [%s, %s)", tt.name, got.From, got.To, tt.want.From, tt.want.To)
+			}
+		})
+	}
+	if _, ok := PeriodByName("decade", now); ok {
+		t.Error("PeriodByName(decade) found, want unknown")
+	}
+
+	// Следующий месяц после 31 января - февраль целиком
+	month, _ := PeriodByName(PeriodMonth, time.Date(2026, 1, 31, 23, 0, 0, 0, time.UTC))
+	if next := month.Shift(1); !next.From.Equal(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)) || !next.To.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("month after January = [%s, %s), want February", next.From, next.To)
+	}
+}
@@ -0,0 +1,93 @@
+// Package digest вычисляет время отправки регулярных сводок и период, который они охватывают.
+// Сводка всегда описывает последний завершившийся период: ежедневная - вчерашний день,
+// еженедельная (по понедельникам) - прошлую неделю, ежемесячная (1-го числа) - прошлый месяц.
+package digest
+
+import (
+	"finuchet-bot/internal/dates"
+	"strings"
+	"time"
+)
+
+// Периодичность сводок
+const (
+	Daily   = "daily"
+	Weekly  = "weekly"
+	Monthly = "monthly"
+)
+
+// Названия периодичности в командах бота
+var frequencyWords = map[string]string{
+	"daily": Daily, "ежедневно": Daily, "день": Daily,
+	"weekly": Weekly, "еженедельно": Weekly, "неделя": Weekly,
+	"monthly": Monthly, "ежемесячно": Monthly, "месяц": Monthly,
+}
+
+// ParseFrequency распознает периодичность сводки: "ежедневно", "weekly"
+func ParseFrequency(word string) (string, bool) {
+	frequency, ok := frequencyWords[strings.ToLower(strings.TrimSpace(word))]
+	return frequency, ok
+}
+
+// ParseTime разбирает время отправки "9:00", "21:30" и возвращает минуты от полуночи
+func ParseTime(s string) (int, bool) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// Schedule - расписание сводки в часовом поясе пользователя
+type Schedule struct {
+	Frequency string
+	Minute    int // Минуты от полуночи по местному времени
+	Location  *time.Location
+}
+
+// Next возвращает первый момент отправки строго после after
+func (s Schedule) Next(after time.Time) time.Time {
+	local := after.In(s.Location)
+	day := dates.Day(local)
+	switch s.Frequency {
+	case Weekly:
+		day = day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7)) // Понедельник
+	case Monthly:
+		day = dates.MonthStart(day)
+	}
+	for {
+		run := s.at(day)
+		if run.After(after) {
+			return run
+		}
+		day = s.step(day)
+	}
+}
+
+// Period возвращает период, который описывает сводка, отправленная в момент run
+func (s Schedule) Period(run time.Time) dates.Period {
+	day := dates.Day(run.In(s.Location))
+	switch s.Frequency {
+	case Weekly:
+		return dates.Period{From: day.AddDate(0, 0, -7), To: day}
+	case Monthly:
+		return dates.Period{From: day.AddDate(0, -1, 0), To: day}
+	}
+	return dates.Period{From: day.AddDate(0, 0, -1), To: day}
+}
+
+// Момент отправки в день day по местному времени. При переходе на летнее время
+// несуществующее время сдвигается вперед, как это делает time.Date.
+func (s Schedule) at(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), s.Minute/60, s.Minute%60, 0, 0, s.Location)
+}
+
+func (s Schedule) step(day time.Time) time.Time {
+	switch s.Frequency {
+	case Weekly:
+		return day.AddDate(0, 0, 7)
+	case Monthly:
+		return day.AddDate(0, 1, 0)
+	}
+	return day.AddDate(0, 0, 1)
+}
@@ -0,0 +1,204 @@
+// Package export записывает операции в CSV, XLSX и JSON построчно,
+// не накапливая всю историю в памяти.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"io"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Поддерживаемые форматы
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+	FormatJSON = "json"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+var header = []string{"date", "type", "category", "amount", "currency", "note", "merchant", "tags", "account", "member"}
+
+// Writer записывает операции по одной; Close дописывает окончание файла
+type Writer interface {
+	Write(t *models.Transaction) error
+	Close() error
+}
+
+// NewWriter создает Writer для формата
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	case FormatJSON:
+		return &jsonWriter{w: w}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// Supported сообщает, поддерживается ли формат
+func Supported(format string) bool {
+	return format == FormatCSV || format == FormatXLSX || format == FormatJSON
+}
+
+// Значения колонок операции
+func row(t *models.Transaction) []string {
+	return []string{
+		t.Date.Format("2006-01-02"),
+		t.Type,
+		t.Category,
+		t.Amount.String(),
+		t.Currency,
+		t.Note,
+		t.Merchant,
+		strings.Join(t.Tags, " "),
+		t.Account,
+		t.Member,
+	}
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	// BOM, чтобы Excel открыл кириллицу в UTF-8
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return nil, err
+	}
+	cw := &csvWriter{w: csv.NewWriter(w)}
+	return cw, cw.w.Write(header)
+}
+
+func (cw *csvWriter) Write(t *models.Transaction) error {
+	return cw.w.Write(row(t))
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+type jsonWriter struct {
+	w     io.Writer
+	count int
+}
+
+type jsonTransaction struct {
+	Date     string       `json:"date"`
+	Type     string       `json:"type"`
+	Category string       `json:"category"`
+	Amount   money.Amount `json:"amount"`
+	Currency string       `json:"currency"`
+	Note     string       `json:"note"`
+	Merchant string       `json:"merchant"`
+	Tags     []string     `json:"tags"`
+	Account  string       `json:"account"`
+	Member   string       `json:"member"`
+}
+
+func (jw *jsonWriter) Write(t *models.Transaction) error {
+	data, err := json.Marshal(jsonTransaction{
+		Date:     t.Date.Format("2006-01-02"),
+		Type:     t.Type,
+		Category: t.Category,
+		Amount:   t.Amount,
+		Currency: t.Currency,
+		Note:     t.Note,
+		Merchant: t.Merchant,
+		Tags:     t.Tags,
+		Account:  t.Account,
+		Member:   t.Member,
+	})
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n  "
+	if jw.count == 0 {
+		sep = "[\n  "
+	}
+	jw.count++
+	if _, err := io.WriteString(jw.w, sep); err != nil {
+		return err
+	}
+	_, err = jw.w.Write(data)
+	return err
+}
+
+func (jw *jsonWriter) Close() error {
+	end := "\n]\n"
+	if jw.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(jw.w, end)
+	return err
+}
+
+// xlsxWriter использует потоковую запись excelize: строки сбрасываются
+// во временный файл, а не держатся в памяти
+type xlsxWriter struct {
+	w    io.Writer
+	file *excelize.File
+	sw   *excelize.StreamWriter
+	next int
+}
+
+const xlsxSheet = "Sheet1"
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+	sw, err := file.NewStreamWriter(xlsxSheet)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	xw := &xlsxWriter{w: w, file: file, sw: sw, next: 1}
+
+	cells := make([]interface{}, len(header))
+	for i, h := range header {
+		cells[i] = h
+	}
+	return xw, xw.writeRow(cells)
+}
+
+func (xw *xlsxWriter) Write(t *models.Transaction) error {
+	return xw.writeRow([]interface{}{
+		t.Date.Format("2006-01-02"),
+		t.Type,
+		t.Category,
+		t.Amount.Float64(), // Число в ячейке, чтобы в Excel работали формулы
+		t.Currency,
+		t.Note,
+		t.Merchant,
+		strings.Join(t.Tags, " "),
+		t.Account,
+		t.Member,
+	})
+}
+
+func (xw *xlsxWriter) writeRow(cells []interface{}) error {
+	cell, err := excelize.CoordinatesToCellName(1, xw.next)
+	if err != nil {
+		return err
+	}
+	xw.next++
+	return xw.sw.SetRow(cell, cells)
+}
+
+func (xw *xlsxWriter) Close() error {
+	defer xw.file.Close()
+	if err := xw.sw.Flush(); err != nil {
+		return err
+	}
+	_, err := xw.file.WriteTo(xw.w)
+	return err
+}
@@ -0,0 +1,234 @@
+// Package forecast прогнозирует доходы и расходы текущего месяца до его конца.
+// Прогноз категории складывается из уже записанных операций, запланированных повторяющихся
+// и ожидаемых остальных: темп текущего месяца смешивается со средним за три прошлых месяца,
+// поправленным на сезонность по тому же месяцу прошлого года. Чем больше дней прошло,
+// тем больше вес темпа. Пакет не обращается к базе: все суммы передаются готовыми.
+package forecast
+
+import (
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"sort"
+	"time"
+)
+
+const (
+	averageMonths  = 3   // За сколько прошлых месяцев считается среднее
+	TrendThreshold = 0.1 // Насколько прогноз должен превысить среднее, чтобы категория считалась растущей
+	minSeasonal    = 0.5 // Пределы сезонного коэффициента: один необычный месяц
+	maxSeasonal    = 2.0 // прошлого года не должен искажать прогноз в разы
+)
+
+// Исходные данные прогноза. Суммы - в одной валюте.
+type Input struct {
+	Today   time.Time                    // Сегодняшний день; прогноз строится до конца его месяца
+	Current []*models.CategoryMonthTotal // Операции текущего месяца
+	History []*models.CategoryMonthTotal // Завершившиеся месяцы; для сезонности нужны 12 последних
+	Planned []*models.CategoryMonthTotal // Повторяющиеся операции, которые еще будут в этом месяце (Recurring)
+}
+
+// Прогноз по категории
+type Category struct {
+	CategoryID int64
+	Category   string
+	Emoji      string
+	Type       string
+	Actual     money.Amount // Записано с начала месяца, включая повторяющиеся
+	Planned    money.Amount // Запланированные повторяющиеся до конца месяца
+	Projected  money.Amount // Ожидаемая сумма за весь месяц
+	Average    money.Amount // Среднее за прошлые месяцы; 0 - истории нет
+	Seasonal   float64      // Сезонный коэффициент месяца; 1 - без поправки
+}
+
+// Trending сообщает, что прогноз расходов категории заметно выше ее среднего за прошлые месяцы
+func (c *Category) Trending() bool {
+	return c.Type == "expense" && c.Average > 0 && c.Projected.Float64() > c.Average.Float64()*(1+TrendThreshold)
+}
+
+// Прогноз на месяц
+type Forecast struct {
+	Month            time.Time // Первый день месяца
+	DaysElapsed      int       // Прошедшие дни, включая сегодняшний
+	DaysTotal        int
+	Income           money.Amount // Записано с начала месяца
+	Expense          money.Amount
+	ProjectedIncome  money.Amount // Ожидается за весь месяц
+	ProjectedExpense money.Amount
+	Categories       []*Category // По типу, затем по убыванию прогноза
+}
+
+// Balance - ожидаемая разница доходов и расходов за месяц
+func (f *Forecast) Balance() money.Amount {
+	return f.ProjectedIncome - f.ProjectedExpense
+}
+
+// Remaining - ожидаемое изменение остатка с сегодняшнего дня до конца месяца
+func (f *Forecast) Remaining() money.Amount {
+	return (f.ProjectedIncome - f.Income) - (f.ProjectedExpense - f.Expense)
+}
+
+// Trending возвращает категории расходов, прогноз которых выше их среднего
+func (f *Forecast) Trending() []*Category {
+	var result []*Category
+	for _, c := range f.Categories {
+		if c.Trending() {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+type key struct {
+	Type       string
+	CategoryID int64
+}
+
+// Project строит прогноз. Расходы без прошлых месяцев прогнозируются только по темпу; доходы по темпу
+// не продлеваются (зарплата приходит разом), а ожидаются не меньше среднего за прошлые месяцы.
+func Project(in Input) *Forecast {
+	today := time.Date(in.Today.Year(), in.Today.Month(), in.Today.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	f := &Forecast{
+		Month:       month,
+		DaysElapsed: today.Day(),
+		DaysTotal:   month.AddDate(0, 1, -1).Day(),
+	}
+	current := monthIndex(month)
+
+	categories := make(map[key]*Category)
+	category := func(t *models.CategoryMonthTotal) *Category {
+		k := key{Type: t.Type, CategoryID: t.CategoryID}
+		c, ok := categories[k]
+		if !ok {
+			c = &Category{CategoryID: t.CategoryID, Category: t.Category, Emoji: t.Emoji, Type: t.Type, Seasonal: 1}
+			categories[k] = c
+		}
+		return c
+	}
+
+	// Остальные (не повторяющиеся) операции текущего месяца - основа темпа
+	variable := make(map[*Category]money.Amount)
+	for _, t := range in.Current {
+		c := category(t)
+		c.Actual += t.Amount + t.Recurring
+		variable[c] += t.Amount
+	}
+	for _, t := range in.Planned {
+		category(t).Planned += t.Recurring
+	}
+
+	// История по месяцам: номер месяца относительно текущего (1 - прошлый)
+	first := make(map[key]int) // Самый давний месяц истории каждой категории
+	history := make(map[*Category]map[int]*models.CategoryMonthTotal)
+	for _, t := range in.History {
+		ago := current - monthIndex(t.Month)
+		if ago < 1 {
+			continue
+		}
+		k := key{Type: t.Type, CategoryID: t.CategoryID}
+		if ago > first[k] {
+			first[k] = ago
+		}
+		if ago > 12 {
+			continue
+		}
+		c := category(t)
+		if history[c] == nil {
+			history[c] = make(map[int]*models.CategoryMonthTotal)
+		}
+		history[c][ago] = t
+	}
+
+	elapsed, days := f.DaysElapsed, f.DaysTotal
+	for k, c := range categories {
+		// Среднее считается с первого месяца, в котором у категории есть данные, но не больше
+		// чем за averageMonths: у новой категории пустые месяцы до ее появления не занижают среднее
+		months := min(first[k], averageMonths)
+		var baseline money.Amount // Ожидаемые остальные операции за весь месяц
+		if months > 0 {
+			var total, other money.Amount
+			for ago := 1; ago <= months; ago++ {
+				if t := history[c][ago]; t != nil {
+					total += t.Amount + t.Recurring
+					other += t.Amount
+				}
+			}
+			c.Average = total.Div(int64(months))
+			if first[k] >= 12 {
+				c.Seasonal = seasonal(history[c])
+			}
+			baseline = other.Div(int64(months)).Mul(c.Seasonal)
+		}
+
+		spent := variable[c]
+		var rest money.Amount // Ожидаемые остальные операции до конца месяца
+		switch {
+		case c.Type == "income":
+			if baseline > spent {
+				rest = baseline - spent
+			}
+		case baseline == 0:
+			rest = spent.Mul(float64(days-elapsed) / float64(elapsed))
+		default:
+			weight := float64(elapsed) / float64(days)
+			pace := spent.Mul(float64(days-elapsed) / float64(elapsed))
+			expected := baseline.Mul(float64(days-elapsed) / float64(days))
+			rest = pace.Mul(weight) + expected.Mul(1-weight)
+		}
+		c.Projected = c.Actual + c.Planned + rest
+
+		if c.Type == "income" {
+			f.Income += c.Actual
+			f.ProjectedIncome += c.Projected
+		} else {
+			f.Expense += c.Actual
+			f.ProjectedExpense += c.Projected
+		}
+		if c.Projected > 0 || c.Average > 0 {
+			f.Categories = append(f.Categories, c)
+		}
+	}
+
+	sort.Slice(f.Categories, func(i, j int) bool {
+		a, b := f.Categories[i], f.Categories[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Projected != b.Projected {
+			return a.Projected > b.Projected
+		}
+		return a.Category < b.Category
+	})
+	return f
+}
+
+// Сезонный коэффициент: остальные операции того же месяца прошлого года относительно среднего
+// за 12 прошлых месяцев. Без операций за год поправки нет.
+func seasonal(history map[int]*models.CategoryMonthTotal) float64 {
+	var year money.Amount
+	for ago := 1; ago <= 12; ago++ {
+		if t := history[ago]; t != nil {
+			year += t.Amount
+		}
+	}
+	if year <= 0 {
+		return 1
+	}
+	var same money.Amount
+	if t := history[12]; t != nil {
+		same = t.Amount
+	}
+	k := same.Float64() / year.Div(12).Float64()
+	if k < minSeasonal {
+		return minSeasonal
+	}
+	if k > maxSeasonal {
+		return maxSeasonal
+	}
+	return k
+}
+
+// Порядковый номер месяца для сравнения дат из разных часовых поясов
+func monthIndex(t time.Time) int {
+	return t.Year()*12 + int(t.Month()) - 1
+}
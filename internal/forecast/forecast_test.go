@@ -0,0 +1,315 @@
+package forecast
+
+import (
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"testing"
+	"time"
+)
+
+const (
+	food   = 1
+	rent   = 2
+	salary = 3
+)
+
+func month(year int, m time.Month) time.Time {
+	return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func day(year int, m time.Month, d int) time.Time {
+	return time.Date(year, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func expense(m time.Time, categoryID int64, amount, recurring money.Amount) *models.CategoryMonthTotal {
+	return &models.CategoryMonthTotal{Month: m, CategoryID: categoryID, Type: "expense", Amount: amount, Recurring: recurring}
+}
+
+func income(m time.Time, categoryID int64, amount money.Amount) *models.CategoryMonthTotal {
+	return &models.CategoryMonthTotal{Month: m, CategoryID: categoryID, Type: "income", Amount: amount}
+}
+
+// История расходов категории за ago = 1..len(amounts) месяцев до апреля 2026
+func expenseHistory(categoryID int64, amounts ...money.Amount) []*models.CategoryMonthTotal {
+	var history []*models.CategoryMonthTotal
+	for i, amount := range amounts {
+		history = append(history, expense(month(2026, time.April).AddDate(0, -(i+1), 0), categoryID, amount, 0))
+	}
+	return history
+}
+
+func findCategory(t *testing.T, f *Forecast, typ string, categoryID int64) *Category {
+	t.Helper()
+	for _, c := range f.Categories {
+		if c.Type == typ && c.CategoryID == categoryID {
+			return c
+		}
+	}
+	t.Fatalf("no %s category %d in forecast", typ, categoryID)
+	return nil
+}
+
+func TestProject(t *testing.T) {
+	april := month(2026, time.April)
+
+	// Двенадцать месяцев истории: апрель прошлого года (ago = 12) в amounts[11]
+	year := func(sameMonth money.Amount) []*models.CategoryMonthTotal {
+		amounts := make([]money.Amount, 12)
+		for i := range amounts {
+			amounts[i] = money.New(1000, 0)
+		}
+		amounts[11] = sameMonth
+		return expenseHistory(food, amounts...)
+	}
+
+	tests := []struct {
+		name         string
+		in           Input
+		typ          string
+		categoryID   int64
+		wantProject  money.Amount
+		wantAverage  money.Amount
+		wantSeasonal float64
+	}{
+		{
+			name: "no history extrapolates pace",
+			in: Input{Today: day(2026, time.April, 10),
+				Current: []*models.CategoryMonthTotal{expense(april, food, money.New(10000, 0), 0)}},
+			typ: "expense", categoryID: food,
+			wantProject: money.New(30000, 0), wantSeasonal: 1,
+		},
+		{
+			name: "recurring is not extrapolated",
+			in: Input{Today: day(2026, time.April, 10),
+				Current: []*models.CategoryMonthTotal{expense(april, rent, 0, money.New(30000, 0))},
+				Planned: []*models.CategoryMonthTotal{expense(april, rent, 0, money.New(5000, 0))}},
+			typ: "expense", categoryID: rent,
+			wantProject: money.New(35000, 0), wantSeasonal: 1,
+		},
+		{
+			name: "income without history is not extrapolated",
+			in: Input{Today: day(2026, time.April, 10),
+				Current: []*models.CategoryMonthTotal{income(april, salary, money.New(50000, 0))}},
+			typ: "income", categoryID: salary,
+			wantProject: money.New(50000, 0), wantSeasonal: 1,
+		},
+		{
+			name: "income expected up to the average",
+			in: Input{Today: day(2026, time.April, 10),
+				Current: []*models.CategoryMonthTotal{income(april, salary, money.New(50000, 0))},
+				History: []*models.CategoryMonthTotal{
+					income(month(2026, time.March), salary, money.New(90000, 0)),
+					income(month(2026, time.February), salary, money.New(90000, 0)),
+					income(month(2026, time.January), salary, money.New(90000, 0)),
+				}},
+			typ: "income", categoryID: salary,
+			wantProject: money.New(90000, 0), wantAverage: money.New(90000, 0), wantSeasonal: 1,
+		},
+		{
+			name: "income above the average stays as recorded",
+			in: Input{Today: day(2026, time.April, 10),
+				Current: []*models.CategoryMonthTotal{income(april, salary, money.New(120000, 0))},
+				History: []*models.CategoryMonthTotal{income(month(2026, time.March), salary, money.New(90000, 0))}},
+			typ: "income", categoryID: salary,
+			wantProject: money.New(120000, 0), wantAverage: money.New(90000, 0), wantSeasonal: 1,
+		},
+		{
+			name: "pace blended with average",
+			in: Input{Today: day(2026, time.April, 10),
+				Current: []*models.CategoryMonthTotal{expense(april, food, money.New(500, 0), 0)},
+				History: expenseHistory(food, money.New(1000, 0), money.New(1000, 0), money.New(1000, 0))},
+			typ: "expense", categoryID: food,
+			// Темп 1000 до конца месяца с весом 1/3, среднее 1000 × 20/30 = 666.67 с весом 2/3
+			wantProject: money.New(500, 0) + money.New(333, 33) + money.New(444, 45),
+			wantAverage: money.New(1000, 0), wantSeasonal: 1,
+		},
+		{
+			name: "seasonal coefficient clamped at 2.0",
+			in: Input{Today: day(2026, time.April, 10),
+				Current: []*models.CategoryMonthTotal{expense(april, food, money.New(500, 0), 0)},
+				History: year(money.New(12000, 0))},
+			typ: "expense", categoryID: food,
+			// Ожидаемые 2000 × 20/30 = 1333.33 с весом 2/3 и темп 1000 с весом 1/3
+			wantProject: money.New(500, 0) + money.New(333, 33) + money.New(888, 89),
+			wantAverage: money.New(1000, 0), wantSeasonal: 2,
+		},
+		{
+			name: "seasonal coefficient clamped at 0.5",
+			in: Input{Today: day(2026, time.April, 10),
+				Current: []*models.CategoryMonthTotal{expense(april, food, money.New(500, 0), 0)},
+				History: year(money.New(10, 0))},
+			typ: "expense", categoryID: food,
+			// Ожидаемые 500 × 20/30 = 333.33 с весом 2/3 и темп 1000 с весом 1/3
+			wantProject: money.New(500, 0) + money.New(333, 33) + money.New(222, 22),
+			wantAverage: money.New(1000, 0), wantSeasonal: 0.5,
+		},
+		{
+			name: "no seasonality with less than 12 months of history",
+			in: Input{Today: day(2026, time.April, 10),
+				Current: []*models.CategoryMonthTotal{expense(april, food, money.New(500, 0), 0)},
+				History: year(money.New(12000, 0))[:11]},
+			typ: "expense", categoryID: food,
+			wantProject: money.New(500, 0) + money.New(333, 33) + money.New(444, 45),
+			wantAverage: money.New(1000, 0), wantSeasonal: 1,
+		},
+		{
+			name: "no seasonality for a category younger than a year",
+			in: Input{Today: day(2026, time.April, 10),
+				Current: []*models.CategoryMonthTotal{expense(april, rent, money.New(500, 0), 0)},
+				// Данные за год есть только у продуктов: отсутствие аренды в апреле прошлого года
+				// не означает сезонного спада
+				History: append(year(money.New(1000, 0)),
+					expenseHistory(rent, money.New(1000, 0), money.New(1000, 0), money.New(1000, 0))...)},
+			typ: "expense", categoryID: rent,
+			wantProject: money.New(500, 0) + money.New(333, 33) + money.New(444, 45),
+			wantAverage: money.New(1000, 0), wantSeasonal: 1,
+		},
+		{
+			name: "new category averages over its own months",
+			in: Input{Today: day(2026, time.April, 10),
+				Current: []*models.CategoryMonthTotal{expense(april, rent, money.New(1000, 0), 0)},
+				// Аренда появилась в прошлом месяце, у продуктов история за три месяца
+				History: append(expenseHistory(food, money.New(1000, 0), money.New(1000, 0), money.New(1000, 0)),
+					expense(month(2026, time.March), rent, money.New(3000, 0), 0))},
+			typ: "expense", categoryID: rent,
+			// Темп 2000 с весом 1/3 и среднее 3000 × 20/30 = 2000 с весом 2/3
+			wantProject: money.New(1000, 0) + money.New(666, 67) + money.New(1333, 33),
+			wantAverage: money.New(3000, 0), wantSeasonal: 1,
+		},
+		{
+			name: "first day of month",
+			in: Input{Today: day(2026, time.April, 1),
+				Current: []*models.CategoryMonthTotal{expense(april, food, money.New(100, 0), 0)}},
+			typ: "expense", categoryID: food,
+			wantProject: money.New(3000, 0), wantSeasonal: 1,
+		},
+		{
+			name: "first day of month with history leans on the average",
+			in: Input{Today: day(2026, time.April, 1),
+				Current: []*models.CategoryMonthTotal{expense(april, food, money.New(300, 0), 0)},
+				History: expenseHistory(food, money.New(3000, 0))},
+			typ: "expense", categoryID: food,
+			// Темп 300 × 29 с весом 1/30 и среднее 3000 × 29/30 с весом 29/30
+			wantProject: money.New(300, 0) + money.New(290, 0) + money.New(2803, 33),
+			wantAverage: money.New(3000, 0), wantSeasonal: 1,
+		},
+		{
+			name: "last day of month adds nothing",
+			in: Input{Today: day(2026, time.April, 30),
+				Current: []*models.CategoryMonthTotal{expense(april, food, money.New(1234, 56), 0)},
+				History: expenseHistory(food, money.New(3000, 0))},
+			typ: "expense", categoryID: food,
+			wantProject: money.New(1234, 56), wantAverage: money.New(3000, 0), wantSeasonal: 1,
+		},
+		{
+			name: "category with history and no spending yet",
+			in: Input{Today: day(2026, time.April, 10),
+				History: expenseHistory(food, money.New(3000, 0))},
+			typ: "expense", categoryID: food,
+			wantProject: money.New(1333, 33), wantAverage: money.New(3000, 0), wantSeasonal: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := Project(tt.in)
+			c := findCategory(t, f, tt.typ, tt.categoryID)
+			if c.Projected != tt.wantProject {
+				t.Errorf("Projected = %s, want %s", c.Projected, tt.wantProject)
+			}
+			if c.Average != tt.wantAverage {
+				t.Errorf("Average = %s, want %s", c.Average, tt.wantAverage)
+			}
+			if c.Seasonal != tt.wantSeasonal {
+				t.Errorf("Seasonal = %v, want %v", c.Seasonal, tt.wantSeasonal)
+			}
+		})
+	}
+}
+
+func TestProjectTotals(t *testing.T) {
+	april := month(2026, time.April)
+	f := Project(Input{
+		Today: day(2026, time.April, 10),
+		Current: []*models.CategoryMonthTotal{
+			expense(april, food, money.New(9000, 0), 0),
+			expense(april, rent, 0, money.New(30000, 0)),
+			income(april, salary, money.New(90000, 0)),
+		},
+	})
+
+	if f.DaysElapsed != 10 || f.DaysTotal != 30 || !f.Month.Equal(april) {
+		t.Errorf("month %s, %d of %d days", f.Month.Format(time.DateOnly), f.DaysElapsed, f.DaysTotal)
+	}
+	if f.Expense != money.New(39000, 0) || f.ProjectedExpense != money.New(57000, 0) {
+		t.Errorf("expense %s, projected %s", f.Expense, f.ProjectedExpense)
+	}
+	if f.Income != money.New(90000, 0) || f.ProjectedIncome != money.New(90000, 0) {
+		t.Errorf("income %s, projected %s", f.Income, f.ProjectedIncome)
+	}
+	if got := f.Balance(); got != money.New(33000, 0) {
+		t.Errorf("Balance() = %s, want 33000.00", got)
+	}
+	if got := f.Remaining(); got != -money.New(18000, 0) {
+		t.Errorf("Remaining() = %s, want -18000.00", got)
+	}
+
+	// Расходы перед доходами, внутри типа - по убыванию прогноза
+	want := []int64{rent, food, salary}
+	for i, c := range f.Categories {
+		if c.CategoryID != want[i] {
+			t.Fatalf("category #%d is %d, want order %v", i, c.CategoryID, want)
+		}
+	}
+}
+
+func TestProjectNewCategoryIsNotTrending(t *testing.T) {
+	f := Project(Input{
+		Today:   day(2026, time.April, 10),
+		Current: []*models.CategoryMonthTotal{expense(month(2026, time.April), rent, money.New(1000, 0), 0)},
+		History: append(expenseHistory(food, money.New(1000, 0), money.New(1000, 0), money.New(1000, 0)),
+			expense(month(2026, time.March), rent, money.New(3000, 0), 0)),
+	})
+	// Аренда тратится тем же темпом, что и в единственном прошлом месяце
+	if got := f.Trending(); len(got) != 0 {
+		t.Errorf("Trending() = %d categories, want none", len(got))
+	}
+}
+
+func TestProjectIgnoresOldAndFutureHistory(t *testing.T) {
+	f := Project(Input{
+		Today: day(2026, time.April, 10),
+		History: []*models.CategoryMonthTotal{
+			expense(month(2026, time.April), food, money.New(5000, 0), 0), // Текущий месяц не история
+			expense(month(2026, time.March), food, money.New(3000, 0), 0),
+		},
+	})
+	if c := findCategory(t, f, "expense", food); c.Average != money.New(3000, 0) {
+		t.Errorf("Average = %s, want 3000.00", c.Average)
+	}
+}
+
+func TestTrending(t *testing.T) {
+	tests := []struct {
+		name     string
+		category Category
+		want     bool
+	}{
+		{"exactly 10% above", Category{Type: "expense", Average: money.New(100, 0), Projected: money.New(110, 0)}, false},
+		{"just above 10%", Category{Type: "expense", Average: money.New(100, 0), Projected: money.New(110, 1)}, true},
+		{"below average", Category{Type: "expense", Average: money.New(100, 0), Projected: money.New(90, 0)}, false},
+		{"no history", Category{Type: "expense", Projected: money.New(500, 0)}, false},
+		{"income", Category{Type: "income", Average: money.New(100, 0), Projected: money.New(200, 0)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.category.Trending(); got != tt.want {
+				t.Errorf("Trending() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	f := &Forecast{Categories: []*Category{&tests[0].category, &tests[1].category, &tests[4].category}}
+	if got := f.Trending(); len(got) != 1 || got[0] != &tests[1].category {
+		t.Errorf("Forecast.Trending() = %v, want only the category above the threshold", got)
+	}
+}
@@ -0,0 +1,423 @@
+package handlers
+
+import (
+	"errors"
+	"finuchet-bot/internal/currency"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"finuchet-bot/internal/quickentry"
+	"finuchet-bot/internal/services"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const accountUsage = "Новый счет: /accounts Карта 15000 (название и начальный остаток, можно с валютой: /accounts Вклад 1000 USD).\n" +
+	"Перевод: /transfer 5000 Карта Наличные или /transfer 5000 и выбор счетов кнопками."
+
+const transferUsage = "Формат: /transfer 5000 Карта Наличные. Для счетов в разных валютах можно указать " +
+	"зачисленную сумму: /transfer 100 Доллары Карта 9250"
+
+// Команда /accounts: без аргументов - счета с остатками, иначе "<название> [остаток] [валюта]".
+// Для существующего счета с указанным остатком меняется начальный остаток.
+func (h *BotHandler) handleAccountsCommand(chatID int64, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		h.showAccounts(chatID, 0)
+		return
+	}
+
+	code := ""
+	if c, ok := currency.Parse(fields[len(fields)-1]); ok && len(fields) > 1 {
+		code, fields = c, fields[:len(fields)-1]
+	}
+	var opening money.Amount
+	hasOpening := false
+	if len(fields) > 1 {
+		amount, err := parseOpeningBalance(fields[len(fields)-1])
+		switch {
+		case err == nil:
+			opening, hasOpening, fields = amount, true, fields[:len(fields)-1]
+		case !errors.Is(err, quickentry.ErrInvalidAmount):
+			h.bot.Send(tgbotapi.NewMessage(chatID, amountErrorText(err)))
+			return
+		}
+	}
+	name := strings.Join(fields, " ")
+
+	accounts, err := h.service.GetAccounts(chatID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении счетов.", err)
+		return
+	}
+	if account := findAccount(accounts, name); account != nil {
+		if hasOpening {
+			if err := h.service.SetOpeningBalance(chatID, account.ID, opening); err != nil {
+				h.sendError(chatID, "Ошибка при изменении счета.", err)
+				return
+			}
+		}
+		h.showAccountCard(chatID, 0, account.ID)
+		return
+	}
+
+	account, err := h.service.CreateAccount(chatID, name, code, opening)
+	switch {
+	case errors.Is(err, services.ErrInvalidAccountName):
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Название счета должно быть от 1 до 50 символов.\n"+accountUsage))
+		return
+	case errors.Is(err, services.ErrAccountExists):
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Счет с таким названием уже есть в архиве."))
+		return
+	case errors.Is(err, money.ErrOverflow):
+		h.bot.Send(tgbotapi.NewMessage(chatID, amountErrorText(err)))
+		return
+	case err != nil:
+		h.sendError(chatID, "Ошибка при создании счета.", err)
+		return
+	}
+	h.showAccountCard(chatID, 0, account.ID)
+}
+
+// Кнопки счетов: "acc:list", "acc:add", "acc:<действие>:<id счета>",
+// "acc:tx:<id операции>:<id счета>" - перенос операции на счет
+func (h *BotHandler) handleAccountAction(chatID int64, messageID int, args string) {
+	action, param, _ := strings.Cut(args, ":")
+
+	switch action {
+	case "list":
+		h.showAccounts(chatID, messageID)
+		return
+	case "add":
+		h.bot.Send(tgbotapi.NewMessage(chatID, accountUsage))
+		return
+	case "tx":
+		transactionParam, accountParam, _ := strings.Cut(param, ":")
+		transactionID, err1 := strconv.ParseInt(transactionParam, 10, 64)
+		accountID, err2 := strconv.ParseInt(accountParam, 10, 64)
+		if err1 != nil || err2 != nil {
+			return
+		}
+		err := h.service.UpdateTransactionAccount(chatID, transactionID, accountID)
+		h.bot.Send(tgbotapi.NewDeleteMessage(chatID, messageID))
+		h.finishTransactionEdit(chatID, transactionID, err)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return
+	}
+	switch action {
+	case "open":
+	case "default":
+		err = h.service.SetDefaultAccount(chatID, accountID)
+	case "archive":
+		err = h.service.ArchiveAccount(chatID, accountID, true)
+		if errors.Is(err, services.ErrDefaultAccount) {
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Основной счет нельзя убрать в архив: сначала сделайте основным другой счет."))
+			return
+		}
+		if err == nil {
+			h.showAccounts(chatID, messageID)
+			return
+		}
+	default:
+		return
+	}
+	if err != nil {
+		h.sendError(chatID, "Ошибка при изменении счета.", err)
+		return
+	}
+	h.showAccountCard(chatID, messageID, accountID)
+}
+
+// Счета с текущими остатками
+func (h *BotHandler) showAccounts(chatID int64, messageID int) {
+	accounts, err := h.service.GetAccounts(chatID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении счетов.", err)
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("Счета и остатки:\n")
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, a := range accounts {
+		b.WriteString(accountLine(a) + "\n")
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(a.Name, fmt.Sprintf("acc:open:%d", a.ID)),
+		))
+	}
+	b.WriteString("\n" + accountUsage)
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("➕ Счет", "acc:add"),
+		tgbotapi.NewInlineKeyboardButtonData("🔄 Переводы", "trf:list"),
+	))
+	h.sendOrEdit(chatID, messageID, b.String(), tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// Карточка счета
+func (h *BotHandler) showAccountCard(chatID int64, messageID int, accountID int64) {
+	accounts, err := h.service.GetAccounts(chatID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении счета.", err)
+		return
+	}
+	var account *models.Account
+	for _, a := range accounts {
+		if a.ID == accountID {
+			account = a
+		}
+	}
+	if account == nil {
+		h.showAccounts(chatID, messageID)
+		return
+	}
+
+	text := fmt.Sprintf("💳 %s\nОстаток: %s\nНачальный остаток: %s\nВалюта: %s", account.Name,
+		amountWithCurrency(account.Balance, account.Currency), amountWithCurrency(account.OpeningBalance, account.Currency), account.Currency)
+	if account.IsDefault {
+		text += "\nОсновной счет: на него записываются операции, если счет не выбран."
+	}
+	text += fmt.Sprintf("\n\nИзменить начальный остаток: /accounts %s 15000", account.Name)
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if !account.IsDefault {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⭐ Сделать основным", fmt.Sprintf("acc:default:%d", account.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("🗄 В архив", fmt.Sprintf("acc:archive:%d", account.ID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("⬅️ К счетам", "acc:list")))
+	h.sendOrEdit(chatID, messageID, text, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// Выбор счета для операции из карточки истории
+func (h *BotHandler) askTransactionAccount(chatID, transactionID int64) {
+	accounts, err := h.service.GetAccounts(chatID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении счетов.", err)
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, "Выберите счет операции:")
+	msg.ReplyMarkup = accountsKeyboard(accounts, 0, func(a *models.Account) string {
+		return fmt.Sprintf("acc:tx:%d:%d", transactionID, a.ID)
+	})
+	h.bot.Send(msg)
+}
+
+// Команда /transfer: "<сумма> <откуда> <куда> [зачислено]"; только сумма - выбор счетов кнопками
+func (h *BotHandler) handleTransferCommand(chatID int64, args string) {
+	var words []string
+	for _, word := range strings.Fields(args) {
+		if word != "→" && word != "->" {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		h.showTransfers(chatID, 0)
+		return
+	}
+	amount, err := quickentry.ParseAmount(words[0])
+	if err != nil {
+		h.bot.Send(tgbotapi.NewMessage(chatID, amountErrorText(err)+"\n"+transferUsage))
+		return
+	}
+	accounts, err := h.service.GetAccounts(chatID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении счетов.", err)
+		return
+	}
+
+	words = words[1:]
+	if len(words) == 0 {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Перевод %s. С какого счета?", formatMoney(amount)))
+		msg.ReplyMarkup = accountsKeyboard(accounts, 0, func(a *models.Account) string {
+			return fmt.Sprintf("trf:f:%d:%d", amount, a.ID)
+		})
+		h.bot.Send(msg)
+		return
+	}
+
+	// Последнее слово может быть зачисленной суммой для счетов в разных валютах
+	if len(words) > 2 {
+		if toAmount, err := quickentry.ParseAmount(words[len(words)-1]); err == nil {
+			if from, to, ok := splitAccounts(accounts, words[:len(words)-1]); ok {
+				h.transfer(chatID, 0, from.ID, to.ID, amount, toAmount)
+				return
+			}
+		}
+	}
+	from, to, ok := splitAccounts(accounts, words)
+	if !ok {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Не удалось найти счета. "+transferUsage))
+		return
+	}
+	h.transfer(chatID, 0, from.ID, to.ID, amount, 0)
+}
+
+// Кнопки переводов: "trf:list", "trf:f:<сумма в копейках>:<откуда>" - выбор счета-получателя,
+// "trf:t:<сумма в копейках>:<откуда>:<куда>" - перевод, "trf:del:<id>", "trf:delok:<id>"
+func (h *BotHandler) handleTransferAction(chatID int64, messageID int, args string) {
+	parts := strings.Split(args, ":")
+	ids := make([]int64, len(parts)-1)
+	for i, part := range parts[1:] {
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return
+		}
+		ids[i] = id
+	}
+
+	switch {
+	case parts[0] == "list":
+		h.showTransfers(chatID, messageID)
+	case parts[0] == "f" && len(ids) == 2:
+		accounts, err := h.service.GetAccounts(chatID)
+		if err != nil {
+			h.sendError(chatID, "Ошибка при получении счетов.", err)
+			return
+		}
+		h.sendOrEdit(chatID, messageID, fmt.Sprintf("Перевод %s. На какой счет?", formatMoney(money.Amount(ids[0]))),
+			accountsKeyboard(accounts, ids[1], func(a *models.Account) string {
+				return fmt.Sprintf("trf:t:%d:%d:%d", ids[0], ids[1], a.ID)
+			}))
+	case parts[0] == "t" && len(ids) == 3:
+		h.transfer(chatID, messageID, ids[1], ids[2], money.Amount(ids[0]), 0)
+	case parts[0] == "del" && len(ids) == 1:
+		h.bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🗑 Да, отменить перевод", fmt.Sprintf("trf:delok:%d", ids[0])),
+				tgbotapi.NewInlineKeyboardButtonData("Нет", "trf:list"),
+			),
+		)))
+	case parts[0] == "delok" && len(ids) == 1:
+		if err := h.service.DeleteTransfer(chatID, ids[0]); err != nil {
+			h.sendError(chatID, "Ошибка при отмене перевода.", err)
+			return
+		}
+		h.showTransfers(chatID, messageID)
+	}
+}
+
+func (h *BotHandler) transfer(chatID int64, messageID int, fromID, toID int64, amount, toAmount money.Amount) {
+	transfer, err := h.service.Transfer(chatID, fromID, toID, amount, toAmount, time.Time{})
+	switch {
+	case errors.Is(err, services.ErrSameAccount):
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Выберите два разных счета."))
+		return
+	case errors.Is(err, services.ErrNoRate):
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Нет курса для пересчета между валютами счетов. Укажите зачисленную сумму. "+transferUsage))
+		return
+	case errors.Is(err, services.ErrInvalidAmount), errors.Is(err, money.ErrOverflow):
+		h.bot.Send(tgbotapi.NewMessage(chatID, amountErrorText(err)))
+		return
+	case err != nil:
+		h.sendError(chatID, "Ошибка при переводе.", err)
+		return
+	}
+	h.sendOrEdit(chatID, messageID, "🔄 Переведено: "+transferLine(transfer), tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("💳 Счета", "acc:list")),
+	))
+}
+
+// Последние переводы с кнопками отмены
+func (h *BotHandler) showTransfers(chatID int64, messageID int) {
+	transfers, err := h.service.GetTransfers(chatID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении переводов.", err)
+		return
+	}
+
+	text := "Последние переводы. Нажмите на перевод, чтобы отменить его:"
+	if len(transfers) == 0 {
+		text = "Переводов пока нет."
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, t := range transfers {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(t.Date.Format("02.01")+" "+transferLine(t), fmt.Sprintf("trf:del:%d", t.ID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("⬅️ К счетам", "acc:list")))
+	h.sendOrEdit(chatID, messageID, text+"\n\n"+transferUsage, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// Кнопки выбора счета, кроме exceptID
+func accountsKeyboard(accounts []*models.Account, exceptID int64, data func(*models.Account) string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, a := range accounts {
+		if a.ID == exceptID {
+			continue
+		}
+		button := tgbotapi.NewInlineKeyboardButtonData(a.Name, data(a))
+		if len(rows) > 0 && len(rows[len(rows)-1]) < 2 {
+			rows[len(rows)-1] = append(rows[len(rows)-1], button)
+		} else {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+		}
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// Строка счета: "⭐ Карта — 15 000.00 RUB"
+func accountLine(a *models.Account) string {
+	line := "💳 " + a.Name
+	if a.IsDefault {
+		line = "⭐ " + a.Name
+	}
+	return line + " — " + formatMoney(a.Balance) + " " + a.Currency
+}
+
+// Строка перевода: "Карта → Наличные 5 000.00 RUB"; для разных валют - обе суммы
+func transferLine(t *models.Transfer) string {
+	line := fmt.Sprintf("%s → %s %s %s", t.FromAccount, t.ToAccount, formatMoney(t.Amount), t.FromCurrency)
+	if t.FromCurrency != t.ToCurrency {
+		line += fmt.Sprintf(" = %s %s", formatMoney(t.ToAmount), t.ToCurrency)
+	}
+	return line
+}
+
+// Счет по названию без учета регистра
+func findAccount(accounts []*models.Account, name string) *models.Account {
+	name = strings.Join(strings.Fields(name), " ")
+	for _, a := range accounts {
+		if strings.EqualFold(a.Name, name) {
+			return a
+		}
+	}
+	return nil
+}
+
+// Разбиение слов на названия двух разных счетов: "Кредитная карта Наличные"
+func splitAccounts(accounts []*models.Account, words []string) (from, to *models.Account, ok bool) {
+	for i := 1; i < len(words); i++ {
+		from = findAccount(accounts, strings.Join(words[:i], " "))
+		to = findAccount(accounts, strings.Join(words[i:], " "))
+		if from != nil && to != nil && from.ID != to.ID {
+			return from, to, true
+		}
+	}
+	return nil, nil, false
+}
+
+// Начальный остаток: как сумма операции, но может быть отрицательным или нулевым
+func parseOpeningBalance(s string) (money.Amount, error) {
+	rest, negative := strings.CutPrefix(s, "-")
+	if !negative {
+		rest, negative = strings.CutPrefix(s, "−")
+	}
+	if rest == "0" {
+		return 0, nil
+	}
+	amount, err := quickentry.ParseAmount(rest)
+	if negative {
+		amount = -amount
+	}
+	return amount, err
+}
@@ -1,21 +1,40 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"finuchet-bot/config"
+	"finuchet-bot/internal/bankimport"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/quickentry"
 	"finuchet-bot/internal/repository"
+	"finuchet-bot/internal/scheduler"
 	"finuchet-bot/internal/services"
+	"fmt"
 	"log"
-	"strconv"
 	"strings"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 type BotHandler struct {
-	bot         *tgbotapi.BotAPI
-	service     *services.FinanceService
-	userStates  map[int64]string  // Состояние пользователя
-	userAmounts map[int64]float64 // Временное хранение суммы для пользователя
+	bot      *tgbotapi.BotAPI
+	service  *services.FinanceService
+	states   repository.StateStore // Состояния диалогов и введенные суммы
+	mode     string                // "polling" или "webhook"
+	webhook  config.WebhookConfig
+	dispatch config.DispatchConfig
+	layouts  []bankimport.Layout // Шаблоны банковских выписок для импорта
+
+	clock             scheduler.Clock // Часы фоновых задач
+	recurringInterval time.Duration   // Период проверки повторяющихся операций
+	digestInterval    time.Duration   // Период проверки сводок к отправке
+
+	shutdownTimeout time.Duration // Сколько ждать обработки принятых обновлений при остановке
+
+	sender int64 // Отправитель обрабатываемого обновления; задается в копии обработчика (см. handleUpdate)
 }
 
 const (
@@ -24,40 +43,147 @@ const (
 	StateWaitingExpense  = "waiting_expense"  // Состояние ожидания суммы для расхода
 	StateIncomeCategory  = "income_category"  // Состояние ожидания категории дохода
 	StateExpenseCategory = "expense_category" // Состояние ожидания категории расхода
+	StateEntryDate       = "entry_date"       // Состояние ожидания даты новой операции
+	StateQuickConfirm    = "quick_confirm"    // Состояние подтверждения операции из быстрого ввода
+	StateCategoryCreate  = "category_create"  // Состояние ожидания названия новой категории
+	StateCategoryRename  = "category_rename"  // Состояние ожидания нового названия категории
+	StateCategoryEmoji   = "category_emoji"   // Состояние ожидания эмодзи категории
+	StateEditAmount      = "edit_amount"      // Состояние ожидания новой суммы операции
+	StateEditCategory    = "edit_category"    // Состояние ожидания новой категории операции
+	StateEditDate        = "edit_date"        // Состояние ожидания новой даты операции
+	StateEditNote        = "edit_note"        // Состояние ожидания новой заметки операции
+	StateEditTags        = "edit_tags"        // Состояние ожидания новых тегов операции
+	StateEditMerchant    = "edit_merchant"    // Состояние ожидания нового продавца операции
+	StateImportLayout    = "import_layout"    // Состояние ожидания формата загруженной выписки
+	StateImportConfirm   = "import_confirm"   // Состояние подтверждения импорта выписки
+	StateBudgetCategory  = "budget_category"  // Состояние ожидания категории нового бюджета
+	StateBudgetAmount    = "budget_amount"    // Состояние ожидания лимита бюджета
+	StateDebtDue         = "debt_due"         // Состояние ожидания нового срока возврата долга
+	StateGoalAmount      = "goal_amount"      // Состояние ожидания суммы взноса в цель
+	StateGoalDue         = "goal_due"         // Состояние ожидания нового срока цели
 )
 
-func NewBotHandler(token string, db *sql.DB) (*BotHandler, error) {
-	bot, err := tgbotapi.NewBotAPI(token)
+func NewBotHandler(cfg *config.Config, db *sql.DB, states repository.StateStore) (*BotHandler, error) {
+	bot, err := tgbotapi.NewBotAPI(cfg.BotToken)
 	if err != nil {
 		return nil, err
 	}
 
+	layouts := bankimport.DefaultLayouts()
+	if cfg.ImportLayoutsFile != "" {
+		extra, err := bankimport.LoadLayouts(cfg.ImportLayoutsFile)
+		if err != nil {
+			return nil, err
+		}
+		layouts = append(layouts, extra...)
+	}
+
 	repo := repository.NewPostgresRepository(db)
 	service := services.NewFinanceService(repo)
 
 	return &BotHandler{
-		bot:         bot,
-		service:     service,
-		userStates:  make(map[int64]string),
-		userAmounts: make(map[int64]float64),
+		bot:      bot,
+		service:  service,
+		states:   states,
+		mode:     cfg.UpdateMode,
+		webhook:  cfg.Webhook,
+		dispatch: cfg.Dispatch,
+		layouts:  layouts,
+
+		clock:             scheduler.SystemClock{},
+		recurringInterval: cfg.RecurringInterval,
+		digestInterval:    cfg.DigestInterval,
+		shutdownTimeout:   cfg.ShutdownTimeout,
 	}, nil
 }
 
-func (h *BotHandler) Start() {
+// Start получает обновления выбранным способом и передает их в общий конвейер обработки.
+// После отмены ctx прекращает прием обновлений и дожидается обработки уже принятых
+// и завершения фоновых задач, но не дольше shutdownTimeout.
+func (h *BotHandler) Start(ctx context.Context) error {
+	d := newDispatcher(h.dispatch.Workers, h.dispatch.QueueSize, h.handleUpdate)
+
+	// Фоновые задачи останавливаются вместе с приемом обновлений, а Start дожидается
+	// их завершения: после возврата вызывающий закрывает базу и хранилище состояний
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	defer stopJobs()
+	var jobs scheduler.Group
+	jobs.Go(func() { scheduler.Run(jobsCtx, h.clock, h.recurringInterval, h.processRecurring) })
+	jobs.Go(func() { scheduler.Run(jobsCtx, h.clock, h.digestInterval, h.sendDigests) })
+	jobs.Go(func() { scheduler.Run(jobsCtx, h.clock, h.digestInterval, h.sendDebtReminders) })
+
+	var err error
+	switch h.mode {
+	case "webhook":
+		err = h.serveWebhook(ctx, d)
+	case "polling":
+		err = h.pollUpdates(ctx, d)
+	default:
+		err = fmt.Errorf("unknown update mode: %q", h.mode)
+	}
+
+	log.Printf("Прием обновлений остановлен, завершаем обработку принятых")
+	drainCtx, cancel := context.WithTimeout(context.Background(), h.shutdownTimeout)
+	defer cancel()
+	stopJobs()
+	if drainErr := d.Close(drainCtx); drainErr != nil && err == nil {
+		err = drainErr
+	}
+	if jobsErr := jobs.Wait(drainCtx); jobsErr != nil && err == nil {
+		err = ErrShutdownTimeout
+	}
+	return err
+}
+
+// Получение обновлений через long polling
+func (h *BotHandler) pollUpdates(ctx context.Context, d *dispatcher) error {
+	// Long polling не работает, пока у бота зарегистрирован webhook
+	info, err := h.bot.GetWebhookInfo()
+	if err != nil {
+		return err
+	}
+	if info.IsSet() {
+		if _, err := h.bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+			return err
+		}
+	}
+
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
 
 	updates := h.bot.GetUpdatesChan(u)
 
-	for update := range updates {
-		if update.CallbackQuery != nil {
-			h.handleCallbackQuery(update.CallbackQuery)
-		} else if update.Message != nil {
-			h.handleTransactionInput(update.Message)
+	for {
+		select {
+		case <-ctx.Done():
+			// Необработанные обновления не подтверждены и будут получены при следующем запуске
+			h.bot.StopReceivingUpdates()
+			return nil
+		case update, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			d.Dispatch(update)
 		}
 	}
 }
 
+// Обработка одного обновления; вызывается из воркеров диспетчера.
+// Обновление обрабатывает копия обработчика со своим отправителем: по нему в группе
+// разделяются диалоги участников, а операции записываются на того, кто их подтвердил.
+func (h *BotHandler) handleUpdate(update tgbotapi.Update) {
+	u := *h
+	if from := update.SentFrom(); from != nil {
+		u.sender = from.ID
+	}
+
+	if update.CallbackQuery != nil {
+		u.handleCallbackQuery(update.CallbackQuery)
+	} else if update.Message != nil {
+		u.handleTransactionInput(update.Message)
+	}
+}
+
 // Обработка ввода данных в зависимости от состояния
 func (h *BotHandler) handleTransactionInput(msg *tgbotapi.Message) {
 	chatID := msg.Chat.ID
@@ -73,18 +199,66 @@ func (h *BotHandler) handleTransactionInput(msg *tgbotapi.Message) {
 		text = strings.TrimSpace(text) // Убираем лишние пробелы после удаления упоминания
 	}
 
-	switch text {
-	case "/start":
-		if err := h.service.RegisterUser(chatID); err != nil {
-			h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при регистрации, попробуйте позже."))
-			log.Printf("Ошибка регистрации пользователя: %v", err)
-		} else {
-			h.sendMainMenu(chatID)
+	// Файл выписки банка для импорта
+	if msg.Document != nil {
+		if h.allowed(chatID, msg.From, accessEdit) {
+			h.handleDocument(chatID, msg.Document)
 		}
+		return
+	}
+
+	// Команда и ее аргументы: "/report 2026-01-01 2026-03-31"
+	command, args, _ := strings.Cut(text, " ")
+
+	if !h.allowed(chatID, msg.From, h.inputAccess(chatID, command, args, text)) {
+		return
+	}
+
+	switch command {
+	case "/start":
+		h.handleStartCommand(msg, args)
+	case "/members":
+		h.showMembers(chatID, 0, memberOf(msg.From, chatID).ID)
+	case "/ledger":
+		h.showLedgers(chatID, 0, memberOf(msg.From, chatID).ID)
 	case "/menu":
 		h.sendMainMenu(chatID)
 	case "/options":
 		h.sendOptionMenu(chatID)
+	case "/categories":
+		h.sendCategoryTypeMenu(chatID)
+	case "/history":
+		h.showHistory(chatID, 0, 0)
+	case "/search":
+		h.handleSearchCommand(chatID, args)
+	case "/export":
+		h.sendExportMenu(chatID)
+	case "/import":
+		h.sendImportHelp(chatID)
+	case "/report":
+		h.handleReportCommand(chatID, args)
+	case "/budget":
+		h.handleBudgetCommand(chatID, args)
+	case "/currency":
+		h.handleCurrencyCommand(chatID, args)
+	case "/rate":
+		h.handleRateCommand(chatID, args)
+	case "/recurring":
+		h.handleRecurringCommand(chatID, args)
+	case "/digest":
+		h.handleDigestCommand(chatID, args)
+	case "/settings":
+		h.handleSettingsCommand(chatID, args)
+	case "/accounts":
+		h.handleAccountsCommand(chatID, args)
+	case "/transfer":
+		h.handleTransferCommand(chatID, args)
+	case "/debt":
+		h.handleDebtCommand(chatID, args)
+	case "/goal":
+		h.handleGoalCommand(chatID, args)
+	case "/forecast":
+		h.showForecast(chatID, 0)
 	case "/cancel":
 		h.resetState(chatID) // Сброс состояния пользователя
 		h.bot.Send(tgbotapi.NewMessage(chatID, "Действие отменено. Вы возвращены в главное меню."))
@@ -92,27 +266,51 @@ func (h *BotHandler) handleTransactionInput(msg *tgbotapi.Message) {
 		return
 	}
 
-	currentState := h.userStates[chatID]
+	currentState := h.getState(chatID)
 
-	switch currentState {
+	switch currentState.State {
 	case StateWaitingIncome, StateWaitingExpense:
-		amount, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
-		if err != nil || amount <= 0 {
-			h.bot.Send(tgbotapi.NewMessage(chatID, "Укажите корректную сумму."))
+		amount, err := quickentry.ParseAmount(text)
+		if err != nil {
+			h.bot.Send(tgbotapi.NewMessage(chatID, amountErrorText(err)))
 			return
 		}
 
-		h.userAmounts[chatID] = amount
-		if currentState == StateWaitingIncome {
-			h.userStates[chatID] = StateIncomeCategory
+		// Валюта и долг задаются при погашении долга из его карточки
+		next := &models.ChatState{State: StateExpenseCategory, Amount: amount, MemberID: memberOf(msg.From, chatID).ID,
+			Currency: currentState.Currency, DebtID: currentState.DebtID}
+		if currentState.State == StateWaitingIncome {
+			next.State = StateIncomeCategory
+			h.setState(chatID, next)
 			h.sendIncomeCategories(chatID)
 		} else {
-			h.userStates[chatID] = StateExpenseCategory
+			h.setState(chatID, next)
 			h.sendExpenseCategories(chatID)
 		}
 
-		// default:
-		// 	h.sendMainMenu(chatID)
+	case StateEntryDate:
+		h.handleEntryDateInput(chatID, currentState, text)
+
+	case StateCategoryCreate, StateCategoryRename, StateCategoryEmoji:
+		h.handleCategoryInput(chatID, currentState, text)
+
+	case StateEditAmount, StateEditDate, StateEditNote, StateEditTags, StateEditMerchant:
+		h.handleTransactionEdit(chatID, currentState, text)
+
+	case StateBudgetAmount:
+		h.handleBudgetInput(chatID, currentState, text)
+
+	case StateDebtDue:
+		h.handleDebtDueInput(chatID, currentState, text)
+
+	case StateGoalAmount, StateGoalDue:
+		h.handleGoalInput(chatID, currentState, text)
+
+	default:
+		// Операция одной строкой: "350 кафе обед", "+50000 зп"
+		if quickentry.LooksLikeEntry(text) {
+			h.handleQuickEntry(chatID, memberOf(msg.From, chatID).ID, msg.MessageID, text)
+		}
 	}
 }
 
@@ -136,29 +334,141 @@ func (h *BotHandler) sendMainMenu(chatID int64) {
 // Обработка CallbackQuery
 func (h *BotHandler) handleCallbackQuery(callbackQuery *tgbotapi.CallbackQuery) {
 	chatID := callbackQuery.Message.Chat.ID
+	messageID := callbackQuery.Message.MessageID
 	data := callbackQuery.Data
 	// text := callbackQuery.Message.Text
 
-	switch data {
+	// Данные кнопок имеют вид "действие" или "действие:параметры"
+	action, args, _ := strings.Cut(data, ":")
+
+	if !h.allowed(chatID, callbackQuery.From, callbackAccess(action, args)) {
+		h.bot.Request(tgbotapi.NewCallback(callbackQuery.ID, ""))
+		return
+	}
+
+	switch action {
 	case "income":
-		h.userStates[chatID] = StateWaitingIncome
+		h.setState(chatID, &models.ChatState{State: StateWaitingIncome})
 		h.bot.Send(tgbotapi.NewMessage(chatID, "Введите сумму дохода:"))
 
 	case "expense":
-		h.userStates[chatID] = StateWaitingExpense
+		h.setState(chatID, &models.ChatState{State: StateWaitingExpense})
 		h.bot.Send(tgbotapi.NewMessage(chatID, "Введите сумму расхода:"))
 
 	case "report":
-		h.handleReportCommand(chatID)
+		h.handleReportCommand(chatID, "")
+
+	case "rep":
+		h.handleReportAction(chatID, messageID, args)
+
+	case "chart":
+		h.handleChartAction(chatID, args)
 
 	case "clear":
 		h.handleClearData(chatID)
 
-	case "salary", "debit", "invest", "deposit":
-		h.addIncome(chatID, data)
+	case "qe_ok":
+		h.confirmQuickEntry(chatID, messageID, args)
+
+	case "qe_category":
+		h.changeQuickEntryCategory(chatID, args)
+
+	case "qe_cancel":
+		h.cancelQuickEntry(chatID, messageID, args)
+
+	case "cat":
+		h.handleCategoryChoice(chatID, args)
+
+	case "catpage":
+		h.handleCategoryPage(chatID, messageID, args)
+
+	case "categories":
+		h.sendCategoryTypeMenu(chatID)
+
+	case "cm":
+		h.handleCategoryManagement(chatID, messageID, args)
+
+	case "edit":
+		h.showHistory(chatID, 0, 0)
+
+	case "export":
+		h.sendExportMenu(chatID)
+
+	case "exp":
+		h.handleExportAction(chatID, messageID, args)
+
+	case "tx":
+		h.handleHistoryAction(chatID, messageID, args)
+
+	case "import":
+		h.sendImportHelp(chatID)
+
+	case "imp":
+		h.handleImportAction(chatID, messageID, args)
+
+	case "budgets":
+		h.showBudgets(chatID, 0)
+
+	case "bud":
+		h.handleBudgetAction(chatID, messageID, args)
+
+	case "recurring":
+		h.showRecurringRules(chatID, 0)
+
+	case "rec":
+		h.handleRecurringAction(chatID, messageID, args)
 
-	case "shop", "service", "cafe", "link", "educ":
-		h.addExpense(chatID, data)
+	case "digests":
+		h.showDigests(chatID, 0)
+
+	case "dig":
+		h.handleDigestAction(chatID, messageID, args)
+
+	case "day":
+		h.handleDateAction(chatID, messageID, args)
+
+	case "accounts":
+		h.showAccounts(chatID, 0)
+
+	case "acc":
+		h.handleAccountAction(chatID, messageID, args)
+
+	case "trf":
+		h.handleTransferAction(chatID, messageID, args)
+
+	case "members":
+		h.showMembers(chatID, 0, memberOf(callbackQuery.From, chatID).ID)
+
+	case "ledgers":
+		h.showLedgers(chatID, 0, memberOf(callbackQuery.From, chatID).ID)
+
+	case "mem":
+		h.handleMemberAction(chatID, messageID, callbackQuery.From, args)
+
+	case "debts":
+		h.showDebts(chatID, 0, false)
+	case "debt":
+		h.handleDebtAction(chatID, messageID, args)
+	case "goals":
+		h.showGoals(chatID, 0)
+	case "goal":
+		h.handleGoalAction(chatID, messageID, args)
+	case "forecast":
+		h.showForecast(chatID, messageID)
+	case "ldg":
+		h.handleLedgerAction(chatID, messageID, callbackQuery.From, args)
+
+	case "qe_account":
+		h.askQuickEntryAccount(chatID, messageID, args)
+
+	case "qe_acc":
+		h.setQuickEntryAccount(chatID, messageID, args)
+
+	case "settings":
+		h.sendSettings(chatID, 0)
+
+	case "tz":
+		h.handleTimezoneAction(chatID, messageID, args)
 	}
 
 	// Отметим callback как обработанный
@@ -173,11 +483,35 @@ func (h *BotHandler) sendOptionMenu(chatID int64) {
 	buttons := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("Редактирование 📝", "edit"),
+			tgbotapi.NewInlineKeyboardButtonData("Категории 🗂", "categories"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("Выгрузка 📤", "export"),
+			tgbotapi.NewInlineKeyboardButtonData("Импорт 📥", "import"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Бюджеты 🎯", "budgets"),
+			tgbotapi.NewInlineKeyboardButtonData("Повторяющиеся 🔁", "recurring"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Сводки 📬", "digests"),
+			tgbotapi.NewInlineKeyboardButtonData("Настройки ⚙️", "settings"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Счета 💳", "accounts"),
 			tgbotapi.NewInlineKeyboardButtonData("Очистка 🧹", "clear"),
 		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Участники 👥", "members"),
+			tgbotapi.NewInlineKeyboardButtonData("Учеты 📚", "ledgers"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Долги 🤝", "debts"),
+			tgbotapi.NewInlineKeyboardButtonData("Цели 🏆", "goals"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Прогноз 🔮", "forecast"),
+		),
 	)
 
 	msg := tgbotapi.NewMessage(chatID, "Выберите действие:")
@@ -188,123 +522,98 @@ func (h *BotHandler) sendOptionMenu(chatID int64) {
 // Функция для очистки данных
 func (h *BotHandler) handleClearData(chatID int64) {
 	if err := h.service.ClearData(chatID); err != nil {
-		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при очистке данных."))
-		log.Printf("Ошибка при очистке данных: %v", err)
+		h.sendError(chatID, "Ошибка при очистке данных.", err)
 	} else {
 		h.bot.Send(tgbotapi.NewMessage(chatID, "Данные успешно очищены."))
 	}
 }
 
-// Функция для выгрузки данных
-// func (h *BotHandler) handleExportData(chatID int64) {
-// 	data, err := h.service.ExportData(chatID)
-// 	if err != nil {
-// 		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при выгрузке данных."))
-// 		log.Printf("Ошибка при выгрузке данных: %v", err)
-// 	} else {
-// 		h.bot.Send(tgbotapi.NewMessage(chatID, "Ваши данные:\n"+data))
-// 	}
-// }
-
 // Отправка кнопок категорий для доходов
 func (h *BotHandler) sendIncomeCategories(chatID int64) {
-	buttons := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("З/п 💸", "salary"),
-			tgbotapi.NewInlineKeyboardButtonData("Дебитор 🫴", "debit"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Премия 💰", "prize"),
-			tgbotapi.NewInlineKeyboardButtonData("Подработка 🤑", "addinc"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Инвест 💹", "invest"),
-			tgbotapi.NewInlineKeyboardButtonData("Вклад 🏦", "deposit"),
-		),
-	)
-
-	msg := tgbotapi.NewMessage(chatID, "Выберите категорию дохода:")
-	msg.ReplyMarkup = buttons
-	h.bot.Send(msg)
+	h.sendEntryCategories(chatID, "income", "Выберите категорию дохода:")
 }
 
 // Отправка кнопок категорий для расходов
 func (h *BotHandler) sendExpenseCategories(chatID int64) {
-	categories := [][]string{
-		{"Аптеки 🏥", "phar"}, {"Авиабилеты 🛫", "avia"},
-		{"Аксессуары 🕶️", "access"}, {"Анализы 💉", "analys"},
-		{"Аренда 🔑", "rent"}, {"БытХим 🧹", "household"},
-		{"Витамины 💊", "vitamin"}, {"Госуслуги 🏢", "state"},
-		{"Дом и ремонт 🛠️", "repair"}, {"Ж/д билеты 🚂", "rail"},
-		{"Животные 🐾", "animal"}, {"ЖКХ 👾", "service"},
-		{"Инвестиции 💹", "invest"}, {"Интернет 🌐", "network"},
-		{"Канцтовары 📝", "office"}, {"Каршеринг 🏎️", "carsh"},
-		{"Книги 📚", "book"}, {"Красота 😻", "beauty"},
-		{"Кредиты 💸", "Loan"}, {"Медицина 🩺", "medic"},
-		{"Моб. связь 📞", "mobile"}, {"Наличные 🗞️", "cash"},
-		{"Образование 🎓", "educ"}, {"Одежда и обувь👟", "clothes"},
-		{"Переводы 📤", "trans"}, {"Подарки 🎁", "gift"},
-		{"Подписки 🤳", "subscript"}, {"Развлечения 🎢", "fun"},
-		{"Еда 🍜", "eat"}, {"Супермаркет 🛒", "mall"},
-		{"Такси 🚕", "taxi"}, {"Топливо ⛽️", "oil"},
-		{"Транспорт 🚌", "transport"}, {"Цветы 💐", "flowers"},
-		{"Спорт 💪", "sport"}, {"Остальное 🙉", "other"},
-	}
+	h.sendEntryCategories(chatID, "expense", "Выберите категорию расхода:")
+}
 
-	var rows [][]tgbotapi.InlineKeyboardButton
-	for i := 0; i < len(categories); i += 2 {
-		row := tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(categories[i][0], categories[i][1]),
-			tgbotapi.NewInlineKeyboardButtonData(categories[i+1][0], categories[i+1][1]),
-		)
-		rows = append(rows, row)
+// Сохранение операции из накопленного состояния диалога
+func (h *BotHandler) saveTransaction(chatID int64, state *models.ChatState, transactionType string, categoryID int64) (*models.Transaction, error) {
+	transaction := &models.Transaction{
+		Amount:     state.Amount,
+		Currency:   state.Currency,
+		CategoryID: categoryID,
+		Type:       transactionType,
+		Note:       state.Note,
+		Tags:       state.Tags,
+		Merchant:   state.Merchant,
+		AccountID:  state.AccountID,
+		MemberID:   state.MemberID,
+		DebtID:     state.DebtID,
+	}
+	// Автор - участник, который завершил ввод, а не сохраненный в состоянии диалога
+	if h.sender != 0 {
+		transaction.MemberID = h.sender
+	}
+	if state.Date != "" {
+		date, err := time.Parse(dateLayout, state.Date)
+		if err != nil {
+			return nil, err
+		}
+		transaction.Date = date
 	}
+	return transaction, h.service.AddTransaction(chatID, transaction)
+}
 
-	buttons := tgbotapi.NewInlineKeyboardMarkup(rows...)
-	msg := tgbotapi.NewMessage(chatID, "Выберите категорию расхода:")
-	msg.ReplyMarkup = buttons
-	h.bot.Send(msg)
+// Участник, которому принадлежит диалог: в группе у каждого отправителя свой диалог,
+// иначе один участник может завершить или подменить операцию, начатую другим.
+// В личном чате диалог один на чат.
+func (h *BotHandler) dialogMember(chatID int64) int64 {
+	if chatID < 0 { // ID групп отрицательные
+		return h.sender
+	}
+	return 0
 }
 
-func (h *BotHandler) addIncome(chatID int64, category string) {
-	amount := h.userAmounts[chatID]
-	if err := h.service.AddIncome(chatID, amount, category); err != nil {
-		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при добавлении дохода."))
-		log.Printf("Ошибка добавления дохода: %v", err)
-	} else {
-		h.bot.Send(tgbotapi.NewMessage(chatID, "Доход успешно добавлен."))
+// Получение состояния пользователя; при ошибке хранилища считаем, что диалога нет
+func (h *BotHandler) getState(chatID int64) *models.ChatState {
+	state, err := h.states.GetState(chatID, h.dialogMember(chatID))
+	if err != nil {
+		log.Printf("Ошибка получения состояния чата %d: %v", chatID, err)
+	}
+	if state == nil {
+		return &models.ChatState{State: StateNone}
 	}
-	h.resetState(chatID)
-	h.sendMainMenu(chatID)
+	return state
 }
 
-func (h *BotHandler) addExpense(chatID int64, category string) {
-	amount := h.userAmounts[chatID]
-	if err := h.service.AddExpense(chatID, amount, category); err != nil {
-		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при добавлении расхода."))
-		log.Printf("Ошибка добавления расхода: %v", err)
-	} else {
-		h.bot.Send(tgbotapi.NewMessage(chatID, "Расход успешно добавлен."))
+// Сохранение состояния пользователя
+func (h *BotHandler) setState(chatID int64, state *models.ChatState) {
+	if err := h.states.SetState(chatID, h.dialogMember(chatID), state); err != nil {
+		log.Printf("Ошибка сохранения состояния чата %d: %v", chatID, err)
 	}
-	h.resetState(chatID)
-	h.sendMainMenu(chatID)
 }
 
 // Сброс состояния пользователя
 func (h *BotHandler) resetState(chatID int64) {
-	h.userStates[chatID] = StateNone
-	delete(h.userAmounts, chatID)
+	if err := h.states.DeleteState(chatID, h.dialogMember(chatID)); err != nil {
+		log.Printf("Ошибка сброса состояния чата %d: %v", chatID, err)
+	}
 }
 
-// Получение отчета
-func (h *BotHandler) handleReportCommand(chatID int64) {
-	report, err := h.service.GetReport(chatID)
-	if err != nil {
-		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении отчета."))
-		log.Printf("Ошибка при получении отчета: %v", err)
-	} else {
-		h.bot.Send(tgbotapi.NewMessage(chatID, report))
+// Сообщение об ошибке; незарегистрированному пользователю предлагаем выполнить /start
+func (h *BotHandler) sendError(chatID int64, text string, err error) {
+	if errors.Is(err, services.ErrUserNotFound) {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Сначала выполните /start."))
+		return
+	}
+	if errors.Is(err, services.ErrTransactionNotFound) {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Операция не найдена: возможно, ее уже удалили."))
+		return
 	}
+	h.bot.Send(tgbotapi.NewMessage(chatID, text))
+	log.Printf("%s %v", text, err)
 }
 
 // package handlers
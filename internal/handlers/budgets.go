@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"errors"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"finuchet-bot/internal/quickentry"
+	"finuchet-bot/internal/services"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Необязательный суффикс периода в команде "/budget Кафе 15000/мес"
+var budgetPeriodSuffixes = []string{"/month", "/мес", "/м"}
+
+const budgetUsage = "Формат: /budget Кафе 15000 — лимит на месяц для категории расходов."
+
+// Команда /budget: без аргументов - список бюджетов, иначе "<категория> <сумма>[/мес]"
+func (h *BotHandler) handleBudgetCommand(chatID int64, args string) {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		h.showBudgets(chatID, 0)
+		return
+	}
+
+	fields := strings.Fields(args)
+	amountArg := strings.ToLower(fields[len(fields)-1])
+	for _, suffix := range budgetPeriodSuffixes {
+		amountArg = strings.TrimSuffix(amountArg, suffix)
+	}
+	amount, err := quickentry.ParseAmount(amountArg)
+	if err != nil || len(fields) < 2 {
+		h.bot.Send(tgbotapi.NewMessage(chatID, budgetUsage))
+		return
+	}
+
+	categories, err := h.service.GetCategories(chatID, "expense", false)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении категорий.", err)
+		return
+	}
+	category, ok := quickentry.MatchCategory(strings.Join(fields[:len(fields)-1], " "), categories)
+	if !ok {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Категория расходов не найдена. "+budgetUsage))
+		return
+	}
+
+	if err := h.saveBudget(chatID, category.ID, amount); err != nil {
+		h.sendError(chatID, "Ошибка при сохранении бюджета.", err)
+		return
+	}
+	h.showBudgetCard(chatID, 0, category.ID)
+}
+
+// Кнопки бюджетов: "bud:<действие>[:<id категории>]"
+func (h *BotHandler) handleBudgetAction(chatID int64, messageID int, args string) {
+	action, param, _ := strings.Cut(args, ":")
+
+	switch action {
+	case "list":
+		h.showBudgets(chatID, messageID)
+		return
+	case "add":
+		h.setState(chatID, &models.ChatState{State: StateBudgetCategory})
+		h.sendEntryCategories(chatID, "expense", "Выберите категорию для бюджета:")
+		return
+	}
+
+	categoryID, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return
+	}
+
+	switch action {
+	case "open":
+	case "amount":
+		h.askBudgetAmount(chatID, categoryID)
+		return
+	case "rollover":
+		err = h.service.ToggleBudgetRollover(chatID, categoryID)
+	case "del":
+		if err := h.service.DeleteBudget(chatID, categoryID); err != nil {
+			h.sendError(chatID, "Ошибка при удалении бюджета.", err)
+			return
+		}
+		h.showBudgets(chatID, messageID)
+		return
+	default:
+		return
+	}
+	if err != nil {
+		h.sendError(chatID, "Ошибка при изменении бюджета.", err)
+		return
+	}
+	h.showBudgetCard(chatID, messageID, categoryID)
+}
+
+// Запрос лимита для выбранной категории
+func (h *BotHandler) askBudgetAmount(chatID, categoryID int64) {
+	h.setState(chatID, &models.ChatState{State: StateBudgetAmount, CategoryID: categoryID})
+	h.bot.Send(tgbotapi.NewMessage(chatID, "Введите лимит расходов на месяц (или /cancel):"))
+}
+
+// Ввод лимита бюджета
+func (h *BotHandler) handleBudgetInput(chatID int64, state *models.ChatState, text string) {
+	amount, err := quickentry.ParseAmount(text)
+	if err != nil {
+		h.bot.Send(tgbotapi.NewMessage(chatID, amountErrorText(err)))
+		return
+	}
+
+	h.resetState(chatID)
+	if err := h.saveBudget(chatID, state.CategoryID, amount); err != nil {
+		h.sendError(chatID, "Ошибка при сохранении бюджета.", err)
+		return
+	}
+	h.showBudgetCard(chatID, 0, state.CategoryID)
+}
+
+// Новый бюджет создается без переноса остатка; у существующего меняется только лимит
+func (h *BotHandler) saveBudget(chatID, categoryID int64, amount money.Amount) error {
+	err := h.service.SetBudgetAmount(chatID, categoryID, amount)
+	if errors.Is(err, services.ErrBudgetNotFound) {
+		err = h.service.SetBudget(chatID, categoryID, amount, false)
+	}
+	return err
+}
+
+// Список бюджетов текущего месяца
+func (h *BotHandler) showBudgets(chatID int64, messageID int) {
+	statuses, err := h.service.GetBudgetStatuses(chatID, h.now(chatID))
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении бюджетов.", err)
+		return
+	}
+
+	text := "Бюджеты на месяц:"
+	if len(statuses) == 0 {
+		text = "Бюджеты не заданы. Добавьте лимит кнопкой ниже или командой /budget Кафе 15000."
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, status := range statuses {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(budgetLine(status), fmt.Sprintf("bud:open:%d", status.CategoryID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("➕ Добавить", "bud:add"),
+	))
+	h.sendOrEdit(chatID, messageID, text, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// Карточка бюджета с кнопками управления
+func (h *BotHandler) showBudgetCard(chatID int64, messageID int, categoryID int64) {
+	status, err := h.service.GetBudgetStatus(chatID, categoryID, h.now(chatID))
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении бюджета.", err)
+		return
+	}
+
+	rollover := "выкл"
+	if status.Rollover {
+		rollover = "вкл"
+	}
+	text := fmt.Sprintf("Бюджет: %s\nЛимит: %s в месяц\nПеренос остатка: %s\n", budgetName(status.Budget), formatMoney(status.Amount), rollover)
+	if status.Carry > 0 {
+		text += fmt.Sprintf("Перенесено с прошлых месяцев: %s\n", formatMoney(status.Carry))
+	}
+	text += fmt.Sprintf("Потрачено в этом месяце: %s из %s (%.0f%%)\n", formatMoney(status.Spent), formatMoney(status.Available), status.Percent())
+	if remaining := status.Remaining(); remaining >= 0 {
+		text += "Осталось: " + formatMoney(remaining)
+	} else {
+		text += "Перерасход: " + formatMoney(-remaining)
+	}
+
+	markup := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✏️ Лимит", fmt.Sprintf("bud:amount:%d", categoryID)),
+			tgbotapi.NewInlineKeyboardButtonData("🔁 Перенос: "+rollover, fmt.Sprintf("bud:rollover:%d", categoryID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🗑 Удалить", fmt.Sprintf("bud:del:%d", categoryID)),
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ К списку", "bud:list"),
+		),
+	)
+	h.sendOrEdit(chatID, messageID, text, markup)
+}
+
+// Предупреждение о расходовании 80% или 100% бюджета после добавления расхода
+func (h *BotHandler) notifyBudget(chatID int64, transaction *models.Transaction) {
+	alert, err := h.service.CheckBudget(chatID, transaction)
+	if err != nil {
+		log.Printf("Ошибка проверки бюджета: %v", err)
+		return
+	}
+	if alert == nil {
+		return
+	}
+
+	var text string
+	if alert.Threshold >= 100 {
+		text = fmt.Sprintf("🚨 Бюджет «%s» превышен: потрачено %s из %s.",
+			budgetName(alert.Budget), formatMoney(alert.Spent), formatMoney(alert.Available))
+	} else {
+		text = fmt.Sprintf("⚠️ Израсходовано %.0f%% бюджета «%s»: осталось %s из %s.",
+			alert.Percent(), budgetName(alert.Budget), formatMoney(alert.Remaining()), formatMoney(alert.Available))
+	}
+	h.bot.Send(tgbotapi.NewMessage(chatID, text))
+}
+
+// Строка бюджета: "Кафе 🍔: 12 000.00 / 15 000.00 (80%)"
+func budgetLine(status *services.BudgetStatus) string {
+	return fmt.Sprintf("%s: %s / %s (%.0f%%)", budgetName(status.Budget), formatMoney(status.Spent), formatMoney(status.Available), status.Percent())
+}
+
+func budgetName(b *models.Budget) string {
+	return models.Category{Name: b.Category, Emoji: b.Emoji}.Label()
+}
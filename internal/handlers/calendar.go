@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/models"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	monthLayout      = "2006-01" // Формат месяца в кнопках календаря
+	calendarNoop     = "day:-"   // Кнопка без действия: заголовки и пустые клетки
+	entryDatePrompt  = "Выберите дату операции или напишите ее: «вчера», «пт», «05.03»."
+	editDatePrompt   = "Выберите новую дату или напишите ее: ДД.ММ.ГГГГ, «вчера», «пт» (или /cancel):"
+	unknownDateReply = "Не удалось распознать дату. Пример: 05.03.2026, «вчера» или «пт»"
+)
+
+var monthNames = [...]string{"Январь", "Февраль", "Март", "Апрель", "Май", "Июнь",
+	"Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь"}
+
+var weekdayHeader = [...]string{"Пн", "Вт", "Ср", "Чт", "Пт", "Сб", "Вс"}
+
+// Выбор даты новой операции после выбора категории. Операция из быстрого ввода
+// уже содержит дату и записывается сразу.
+func (h *BotHandler) askEntryDate(chatID int64, state *models.ChatState, categoryID int64) {
+	state.Type = "expense"
+	if state.State == StateIncomeCategory {
+		state.Type = "income"
+	}
+	state.CategoryID = categoryID
+	if date, err := time.ParseInLocation(dateLayout, state.Date, h.now(chatID).Location()); err == nil {
+		h.addEntry(chatID, state, date)
+		return
+	}
+	state.State = StateEntryDate
+	h.setState(chatID, state)
+
+	now := h.now(chatID)
+	msg := tgbotapi.NewMessage(chatID, entryDatePrompt)
+	msg.ReplyMarkup = calendarKeyboard(now, now)
+	h.bot.Send(msg)
+}
+
+// Дата новой операции, введенная текстом
+func (h *BotHandler) handleEntryDateInput(chatID int64, state *models.ChatState, text string) {
+	date, ok := dates.Parse(text, h.now(chatID))
+	if !ok {
+		h.bot.Send(tgbotapi.NewMessage(chatID, unknownDateReply))
+		return
+	}
+	h.addEntry(chatID, state, date)
+}
+
+// Кнопки календаря: "day:<ГГГГ-ММ-ДД>" - выбор дня, "day:m:<ГГГГ-ММ>" - другой месяц
+func (h *BotHandler) handleDateAction(chatID int64, messageID int, args string) {
+	state := h.getState(chatID)
+	if state.State != StateEntryDate && state.State != StateEditDate {
+		return
+	}
+
+	now := h.now(chatID)
+	if value, ok := strings.CutPrefix(args, "m:"); ok {
+		month, err := time.ParseInLocation(monthLayout, value, now.Location())
+		if err != nil {
+			return
+		}
+		h.bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, calendarKeyboard(month, now)))
+		return
+	}
+
+	date, err := time.ParseInLocation(dateLayout, args, now.Location())
+	if err != nil {
+		return // Пустая клетка или заголовок
+	}
+	// Календарь больше не нужен: оставляем в сообщении выбранную дату
+	h.bot.Send(tgbotapi.NewEditMessageText(chatID, messageID, "Дата: "+date.Format(displayDateLayout)))
+
+	if state.State == StateEditDate {
+		err := h.service.UpdateTransactionDate(chatID, state.TransactionID, date)
+		h.finishTransactionEdit(chatID, state.TransactionID, err)
+		return
+	}
+	h.addEntry(chatID, state, date)
+}
+
+// Сохранение операции на выбранную дату вместе с валютой и заметкой из состояния диалога
+func (h *BotHandler) addEntry(chatID int64, state *models.ChatState, date time.Time) {
+	suffix := ""
+	if !date.Equal(dates.Day(h.now(chatID))) {
+		suffix = " за " + date.Format(displayDateLayout)
+	}
+
+	state.Date = date.Format(dateLayout)
+	transaction, err := h.saveTransaction(chatID, state, state.Type, state.CategoryID)
+	switch {
+	case debtPaymentError(err) != "":
+		h.bot.Send(tgbotapi.NewMessage(chatID, debtPaymentError(err)))
+	case err != nil && state.Type == "income":
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при добавлении дохода."))
+		log.Printf("Ошибка добавления дохода: %v", err)
+	case err != nil:
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при добавлении расхода."))
+		log.Printf("Ошибка добавления расхода: %v", err)
+	case state.Type == "income":
+		h.sendEntryAdded(chatID, transaction, "Доход успешно добавлен"+suffix+".")
+	default:
+		h.sendEntryAdded(chatID, transaction, "Расход успешно добавлен"+suffix+".")
+		h.notifyBudget(chatID, transaction)
+	}
+	if err == nil && state.DebtID != 0 {
+		h.showDebtCard(chatID, 0, state.DebtID)
+	}
+	h.resetState(chatID)
+	h.sendMainMenu(chatID)
+}
+
+// Сообщение о добавленной операции с кнопками для заметки, тегов, продавца и счета;
+// доход можно сразу отложить в цель
+func (h *BotHandler) sendEntryAdded(chatID int64, transaction *models.Transaction, text string) {
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📝 Заметка", fmt.Sprintf("tx:note:%d", transaction.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("🏷 Теги", fmt.Sprintf("tx:tags:%d", transaction.ID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🏪 Продавец", fmt.Sprintf("tx:merchant:%d", transaction.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("💳 Счет", fmt.Sprintf("tx:account:%d", transaction.ID)),
+		),
+	}
+	if transaction.Type == "income" {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🎯 В цель", fmt.Sprintf("tx:goal:%d", transaction.ID)),
+		))
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	h.bot.Send(msg)
+}
+
+// Календарь месяца month для выбора даты не позже today: быстрые кнопки,
+// листание месяцев и дни по неделям с понедельника. Будущие дни не показываются.
+func calendarKeyboard(month, today time.Time) tgbotapi.InlineKeyboardMarkup {
+	today = dates.Day(today)
+	month = dates.MonthStart(month)
+
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			dayButton("Сегодня", today),
+			dayButton("Вчера", today.AddDate(0, 0, -1)),
+			dayButton("Позавчера", today.AddDate(0, 0, -2)),
+		),
+	}
+
+	next := tgbotapi.NewInlineKeyboardButtonData(" ", calendarNoop)
+	if month.Before(dates.MonthStart(today)) {
+		next = tgbotapi.NewInlineKeyboardButtonData("▶️", "day:m:"+month.AddDate(0, 1, 0).Format(monthLayout))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("◀️", "day:m:"+month.AddDate(0, -1, 0).Format(monthLayout)),
+		tgbotapi.NewInlineKeyboardButtonData(monthNames[month.Month()-1]+" "+strconv.Itoa(month.Year()), calendarNoop),
+		next,
+	))
+
+	var header []tgbotapi.InlineKeyboardButton
+	for _, name := range weekdayHeader {
+		header = append(header, tgbotapi.NewInlineKeyboardButtonData(name, calendarNoop))
+	}
+	rows = append(rows, header)
+
+	week := make([]tgbotapi.InlineKeyboardButton, 0, 7)
+	for i := 0; i < (int(month.Weekday())+6)%7; i++ {
+		week = append(week, tgbotapi.NewInlineKeyboardButtonData(" ", calendarNoop))
+	}
+	for day := month; day.Month() == month.Month() && !day.After(today); day = day.AddDate(0, 0, 1) {
+		week = append(week, dayButton(strconv.Itoa(day.Day()), day))
+		if len(week) == 7 {
+			rows = append(rows, week)
+			week = make([]tgbotapi.InlineKeyboardButton, 0, 7)
+		}
+	}
+	if len(week) > 0 {
+		for len(week) < 7 {
+			week = append(week, tgbotapi.NewInlineKeyboardButtonData(" ", calendarNoop))
+		}
+		rows = append(rows, week)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func dayButton(title string, day time.Time) tgbotapi.InlineKeyboardButton {
+	return tgbotapi.NewInlineKeyboardButtonData(title, "day:"+day.Format(dateLayout))
+}
@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"errors"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/services"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const categoriesPerPage = 10 // Пять рядов по две кнопки
+
+// Отправка клавиатуры выбора категории при вводе операции
+func (h *BotHandler) sendEntryCategories(chatID int64, transactionType, text string) {
+	categories, err := h.service.GetCategories(chatID, transactionType, false)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении категорий.", err)
+		return
+	}
+	if len(categories) == 0 {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Нет активных категорий. Добавьте их через /categories."))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = entryCategoryKeyboard(categories, transactionType, 0)
+	h.bot.Send(msg)
+}
+
+// Листание клавиатуры выбора категории: "catpage:<тип>:<страница>"
+func (h *BotHandler) handleCategoryPage(chatID int64, messageID int, args string) {
+	transactionType, pageArg, _ := strings.Cut(args, ":")
+	page, _ := strconv.Atoi(pageArg)
+
+	categories, err := h.service.GetCategories(chatID, transactionType, false)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении категорий.", err)
+		return
+	}
+	h.bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, entryCategoryKeyboard(categories, transactionType, page)))
+}
+
+// Выбор категории при вводе операции: "cat:<id>"
+func (h *BotHandler) handleCategoryChoice(chatID int64, args string) {
+	categoryID, err := strconv.ParseInt(args, 10, 64)
+	if err != nil {
+		return
+	}
+
+	switch state := h.getState(chatID); state.State {
+	case StateIncomeCategory, StateExpenseCategory:
+		h.askEntryDate(chatID, state, categoryID)
+	case StateEditCategory:
+		err := h.service.UpdateTransactionCategory(chatID, state.TransactionID, categoryID)
+		h.finishTransactionEdit(chatID, state.TransactionID, err)
+	case StateBudgetCategory:
+		h.askBudgetAmount(chatID, categoryID)
+	}
+}
+
+// Клавиатура категорий по две кнопки в ряд с листанием
+func entryCategoryKeyboard(categories []*models.Category, transactionType string, page int) tgbotapi.InlineKeyboardMarkup {
+	start, end, page, pages := paginate(len(categories), page, categoriesPerPage)
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i := start; i < end; i += 2 {
+		row := tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(categories[i].Label(), fmt.Sprintf("cat:%d", categories[i].ID)),
+		)
+		if i+1 < end {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(categories[i+1].Label(), fmt.Sprintf("cat:%d", categories[i+1].ID)))
+		}
+		rows = append(rows, row)
+	}
+	if nav := pageNavigation("catpage:"+transactionType, page, pages); nav != nil {
+		rows = append(rows, nav)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// Меню управления категориями
+func (h *BotHandler) sendCategoryTypeMenu(chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, "Какие категории настроить?")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Доходы 📈", "cm:list:income:0"),
+			tgbotapi.NewInlineKeyboardButtonData("Расходы 📉", "cm:list:expense:0"),
+		),
+	)
+	h.bot.Send(msg)
+}
+
+// Кнопки управления категориями: "cm:<действие>:<параметры>"
+func (h *BotHandler) handleCategoryManagement(chatID int64, messageID int, args string) {
+	parts := strings.Split(args, ":")
+	if len(parts) < 2 {
+		return
+	}
+	action, param := parts[0], parts[1]
+
+	switch action {
+	case "list":
+		page := 0
+		if len(parts) > 2 {
+			page, _ = strconv.Atoi(parts[2])
+		}
+		h.showCategoryList(chatID, messageID, param, page)
+
+	case "add":
+		h.setState(chatID, &models.ChatState{State: StateCategoryCreate, Type: param})
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Введите название новой категории (или /cancel):"))
+
+	default:
+		categoryID, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return
+		}
+		h.handleCategoryAction(chatID, messageID, action, categoryID)
+	}
+}
+
+// Действия с отдельной категорией
+func (h *BotHandler) handleCategoryAction(chatID int64, messageID int, action string, categoryID int64) {
+	var err error
+	switch action {
+	case "open":
+	case "rename":
+		h.setState(chatID, &models.ChatState{State: StateCategoryRename, CategoryID: categoryID})
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Введите новое название категории (или /cancel):"))
+		return
+	case "emoji":
+		h.setState(chatID, &models.ChatState{State: StateCategoryEmoji, CategoryID: categoryID})
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Отправьте эмодзи для категории или «-», чтобы убрать его:"))
+		return
+	case "up":
+		err = h.service.MoveCategory(chatID, categoryID, -1)
+	case "down":
+		err = h.service.MoveCategory(chatID, categoryID, 1)
+	case "archive":
+		err = h.service.ArchiveCategory(chatID, categoryID, true)
+	case "restore":
+		err = h.service.ArchiveCategory(chatID, categoryID, false)
+	default:
+		return
+	}
+	if err != nil {
+		h.sendError(chatID, "Ошибка при изменении категории.", err)
+		return
+	}
+	h.showCategoryCard(chatID, messageID, categoryID)
+}
+
+// Ввод названия или эмодзи категории
+func (h *BotHandler) handleCategoryInput(chatID int64, state *models.ChatState, text string) {
+	var err error
+	categoryID := state.CategoryID
+
+	switch state.State {
+	case StateCategoryCreate:
+		var category *models.Category
+		category, err = h.service.CreateCategory(chatID, state.Type, text)
+		if err == nil {
+			categoryID = category.ID
+		}
+	case StateCategoryRename:
+		err = h.service.RenameCategory(chatID, categoryID, text)
+	case StateCategoryEmoji:
+		if strings.TrimSpace(text) == "-" {
+			text = ""
+		}
+		err = h.service.SetCategoryEmoji(chatID, categoryID, text)
+	}
+
+	switch {
+	case errors.Is(err, services.ErrCategoryExists):
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Категория с таким названием уже есть. Введите другое:"))
+		return
+	case errors.Is(err, services.ErrInvalidCategoryName):
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Название должно содержать от 1 до 50 символов. Попробуйте еще раз:"))
+		return
+	case err != nil:
+		h.resetState(chatID)
+		h.sendError(chatID, "Ошибка при сохранении категории.", err)
+		return
+	}
+
+	h.resetState(chatID)
+	h.showCategoryCard(chatID, 0, categoryID)
+}
+
+// Список категорий с архивными; messageID = 0 означает новое сообщение
+func (h *BotHandler) showCategoryList(chatID int64, messageID int, transactionType string, page int) {
+	categories, err := h.service.GetCategories(chatID, transactionType, true)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении категорий.", err)
+		return
+	}
+
+	start, end, page, pages := paginate(len(categories), page, categoriesPerPage)
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, c := range categories[start:end] {
+		label := c.Label()
+		if c.Archived {
+			label += " (архив)"
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("cm:open:%d", c.ID)),
+		))
+	}
+	if nav := pageNavigation("cm:list:"+transactionType, page, pages); nav != nil {
+		rows = append(rows, nav)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("➕ Добавить", "cm:add:"+transactionType),
+	))
+
+	text := "Категории расходов:"
+	if transactionType == "income" {
+		text = "Категории доходов:"
+	}
+	h.sendOrEdit(chatID, messageID, text, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// Карточка категории с кнопками управления
+func (h *BotHandler) showCategoryCard(chatID int64, messageID int, categoryID int64) {
+	category, err := h.service.GetCategory(chatID, categoryID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении категории.", err)
+		return
+	}
+
+	status := "активна"
+	archiveButton := tgbotapi.NewInlineKeyboardButtonData("🗄 В архив", fmt.Sprintf("cm:archive:%d", category.ID))
+	if category.Archived {
+		status = "в архиве"
+		archiveButton = tgbotapi.NewInlineKeyboardButtonData("♻️ Восстановить", fmt.Sprintf("cm:restore:%d", category.ID))
+	}
+	text := fmt.Sprintf("Категория: %s\nСтатус: %s", category.Label(), status)
+
+	markup := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✏️ Название", fmt.Sprintf("cm:rename:%d", category.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("😀 Эмодзи", fmt.Sprintf("cm:emoji:%d", category.ID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⬆️ Выше", fmt.Sprintf("cm:up:%d", category.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("⬇️ Ниже", fmt.Sprintf("cm:down:%d", category.ID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(archiveButton),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ К списку", "cm:list:"+category.Type+":0"),
+		),
+	)
+	h.sendOrEdit(chatID, messageID, text, markup)
+}
+
+// Отправка нового сообщения или замена существующего (messageID != 0)
+func (h *BotHandler) sendOrEdit(chatID int64, messageID int, text string, markup tgbotapi.InlineKeyboardMarkup) {
+	if messageID == 0 {
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ReplyMarkup = markup
+		h.bot.Send(msg)
+		return
+	}
+	h.bot.Send(tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text, markup))
+}
+
+// Границы страницы; номер страницы ограничивается допустимым диапазоном
+func paginate(total, page, size int) (start, end, currentPage, pages int) {
+	pages = (total + size - 1) / size
+	if pages == 0 {
+		pages = 1
+	}
+	if page < 0 {
+		page = 0
+	}
+	if page >= pages {
+		page = pages - 1
+	}
+	start = page * size
+	end = start + size
+	if end > total {
+		end = total
+	}
+	return start, end, page, pages
+}
+
+// Ряд кнопок листания; nil, если страница одна
+func pageNavigation(prefix string, page, pages int) []tgbotapi.InlineKeyboardButton {
+	if pages <= 1 {
+		return nil
+	}
+	var row []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("◀️", fmt.Sprintf("%s:%d", prefix, page-1)))
+	}
+	row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d/%d", page+1, pages), fmt.Sprintf("%s:%d", prefix, page)))
+	if page < pages-1 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("▶️", fmt.Sprintf("%s:%d", prefix, page+1)))
+	}
+	return row
+}
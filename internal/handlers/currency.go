@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"finuchet-bot/internal/services"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	currencyUsage = "Формат: /currency USD — базовая валюта, в которую пересчитываются отчеты и бюджеты."
+	rateUsage     = "Формат: /rate USD 92.5 — сколько единиц базовой валюты стоит одна единица валюты."
+)
+
+// Команда /currency: без аргументов - текущая базовая валюта, иначе смена валюты
+func (h *BotHandler) handleCurrencyCommand(chatID int64, args string) {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		code, err := h.service.GetBaseCurrency(chatID)
+		if err != nil {
+			h.sendError(chatID, "Ошибка при получении валюты.", err)
+			return
+		}
+		h.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Базовая валюта: %s.\n%s", code, currencyUsage)))
+		return
+	}
+
+	code, err := h.service.SetBaseCurrency(chatID, args)
+	if errors.Is(err, services.ErrUnknownCurrency) {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Неизвестная валюта. "+currencyUsage))
+		return
+	}
+	if err != nil {
+		h.sendError(chatID, "Ошибка при смене валюты.", err)
+		return
+	}
+	h.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Базовая валюта: %s. Отчеты и бюджеты пересчитываются в нее по курсу на дату операции.", code)))
+}
+
+// Команда /rate: без аргументов - известные курсы, иначе "<валюта> <курс>"
+func (h *BotHandler) handleRateCommand(chatID int64, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		h.showRates(chatID)
+		return
+	}
+
+	if len(fields) != 2 {
+		h.bot.Send(tgbotapi.NewMessage(chatID, rateUsage))
+		return
+	}
+	// Курс может быть точнее копеек: 0.0108 AMD
+	value, err := strconv.ParseFloat(strings.Replace(fields[1], ",", ".", 1), 64)
+	if err != nil {
+		h.bot.Send(tgbotapi.NewMessage(chatID, rateUsage))
+		return
+	}
+
+	rate, err := h.service.SetRate(chatID, fields[0], value)
+	switch {
+	case errors.Is(err, services.ErrUnknownCurrency):
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Неизвестная валюта или она совпадает с базовой. "+rateUsage))
+		return
+	case errors.Is(err, services.ErrInvalidRate):
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Курс должен быть положительным числом."))
+		return
+	case err != nil:
+		h.sendError(chatID, "Ошибка при сохранении курса.", err)
+		return
+	}
+	h.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Курс с %s: 1 %s = %s %s.",
+		rate.Date.Format(displayDateLayout), rate.Currency, formatRate(rate.Rate), rate.Quote)))
+}
+
+// Последние известные курсы к базовой валюте
+func (h *BotHandler) showRates(chatID int64) {
+	rates, err := h.service.GetRates(chatID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении курсов.", err)
+		return
+	}
+	if len(rates) == 0 {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Курсы валют не заданы. "+rateUsage))
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("Курсы валют:\n")
+	for _, rate := range rates {
+		fmt.Fprintf(&b, "1 %s = %s %s (%s)\n", rate.Currency, formatRate(rate.Rate), rate.Quote, rate.Date.Format(displayDateLayout))
+	}
+	b.WriteString(rateUsage)
+	h.bot.Send(tgbotapi.NewMessage(chatID, b.String()))
+}
+
+// Курс без лишних нулей: 92.5, 0.0108
+func formatRate(rate float64) string {
+	s := fmt.Sprintf("%.6f", rate)
+	return strings.TrimRight(strings.TrimRight(s, "0"), ".")
+}
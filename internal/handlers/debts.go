@@ -0,0 +1,426 @@
+package handlers
+
+import (
+	"errors"
+	"finuchet-bot/internal/currency"
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"finuchet-bot/internal/quickentry"
+	"finuchet-bot/internal/services"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const debtUsage = "Новый долг: /debt дал Иван 5000 до 15.11 на ремонт или /debt взял Петр 300 USD.\n" +
+	"Погашения записываются обычными доходами и расходами: кнопкой «💰 Погашение» в карточке долга " +
+	"или кнопкой «🤝 Долг» в карточке операции."
+
+// Первое слово команды /debt: кто кому дал в долг
+var debtDirections = map[string]string{
+	"дал": services.DebtLent, "дала": services.DebtLent, "одолжил": services.DebtLent, "одолжила": services.DebtLent,
+	"взял": services.DebtBorrowed, "взяла": services.DebtBorrowed, "занял": services.DebtBorrowed, "заняла": services.DebtBorrowed,
+}
+
+// Команда /debt: без аргументов - итоги по людям и открытые долги, иначе новый долг
+func (h *BotHandler) handleDebtCommand(chatID int64, args string) {
+	if strings.TrimSpace(args) == "" {
+		h.showDebts(chatID, 0, false)
+		return
+	}
+
+	debt, problem := parseDebt(args, h.now(chatID))
+	if problem != "" {
+		h.bot.Send(tgbotapi.NewMessage(chatID, problem+"\n"+debtUsage))
+		return
+	}
+
+	err := h.service.CreateDebt(chatID, debt)
+	switch {
+	case errors.Is(err, services.ErrInvalidCounterparty):
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Имя должно быть от 1 до 100 символов.\n"+debtUsage))
+		return
+	case errors.Is(err, services.ErrNoteTooLong):
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Заметка слишком длинная, сократите ее до 255 символов."))
+		return
+	case errors.Is(err, services.ErrInvalidAmount), errors.Is(err, money.ErrOverflow):
+		h.bot.Send(tgbotapi.NewMessage(chatID, amountErrorText(err)))
+		return
+	case err != nil:
+		h.sendError(chatID, "Ошибка при сохранении долга.", err)
+		return
+	}
+	h.showDebtCard(chatID, 0, debt.ID)
+}
+
+// Разбор долга: "дал|взял <имя> <сумма> [валюта] [до <дата>] [заметка]".
+// problem - сообщение пользователю, если долг разобрать не удалось.
+func parseDebt(args string, now time.Time) (debt *models.Debt, problem string) {
+	fields := strings.Fields(args)
+	direction, ok := debtDirections[strings.ToLower(fields[0])]
+	if !ok {
+		return nil, "Укажите, дали вы в долг или взяли."
+	}
+
+	// Имя - все слова до суммы
+	i := 1
+	var amount money.Amount
+	for ; i < len(fields); i++ {
+		parsed, err := quickentry.ParseAmount(fields[i])
+		if err == nil {
+			amount = parsed
+			break
+		}
+		if !errors.Is(err, quickentry.ErrInvalidAmount) {
+			return nil, amountErrorText(err)
+		}
+	}
+	if i == 1 || i == len(fields) {
+		return nil, "Укажите имя и сумму долга."
+	}
+
+	debt = &models.Debt{Direction: direction, Counterparty: strings.Join(fields[1:i], " "), Amount: amount}
+	rest := fields[i+1:]
+	if len(rest) > 0 {
+		if code, ok := currency.Parse(rest[0]); ok {
+			debt.Currency, rest = code, rest[1:]
+		}
+	}
+	if len(rest) > 1 && strings.ToLower(rest[0]) == "до" {
+		due, ok := dates.ParseFuture(rest[1], now)
+		if !ok {
+			return nil, fmt.Sprintf("Не удалось распознать дату %q.", rest[1])
+		}
+		debt.DueDate, rest = due, rest[2:]
+	}
+	debt.Note = strings.Join(rest, " ")
+	return debt, ""
+}
+
+// Кнопки долгов: "debt:list", "debt:all", "debt:add", "debt:<действие>:<id долга>",
+// "debt:tx:<id операции>:<id долга>" - погашение долга существующей операцией, 0 - отмена погашения
+func (h *BotHandler) handleDebtAction(chatID int64, messageID int, args string) {
+	action, param, _ := strings.Cut(args, ":")
+
+	switch action {
+	case "list":
+		h.showDebts(chatID, messageID, false)
+		return
+	case "all":
+		h.showDebts(chatID, messageID, true)
+		return
+	case "add":
+		h.bot.Send(tgbotapi.NewMessage(chatID, debtUsage))
+		return
+	case "tx":
+		transactionParam, debtParam, _ := strings.Cut(param, ":")
+		transactionID, err1 := strconv.ParseInt(transactionParam, 10, 64)
+		debtID, err2 := strconv.ParseInt(debtParam, 10, 64)
+		if err1 != nil || err2 != nil {
+			return
+		}
+		err := h.service.SetTransactionDebt(chatID, transactionID, debtID)
+		if text := debtPaymentError(err); text != "" {
+			h.bot.Send(tgbotapi.NewMessage(chatID, text))
+			return
+		}
+		h.bot.Send(tgbotapi.NewDeleteMessage(chatID, messageID))
+		h.finishTransactionEdit(chatID, transactionID, err)
+		return
+	}
+
+	debtID, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return
+	}
+	switch action {
+	case "open":
+	case "pay":
+		h.askDebtPayment(chatID, debtID)
+		return
+	case "due":
+		h.setState(chatID, &models.ChatState{State: StateDebtDue, DebtID: debtID})
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Введите срок возврата: «пт», «15.11», «15.11.2026» или «-», чтобы убрать срок (или /cancel):"))
+		return
+	case "close":
+		err = h.service.CloseDebt(chatID, debtID, true)
+	case "reopen":
+		err = h.service.CloseDebt(chatID, debtID, false)
+	case "del":
+		h.bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🗑 Да, удалить", fmt.Sprintf("debt:delok:%d", debtID)),
+				tgbotapi.NewInlineKeyboardButtonData("Нет", fmt.Sprintf("debt:open:%d", debtID)),
+			),
+		)))
+		return
+	case "delok":
+		if err := h.service.DeleteDebt(chatID, debtID); err != nil {
+			h.sendError(chatID, "Ошибка при удалении долга.", err)
+			return
+		}
+		h.showDebts(chatID, messageID, false)
+		return
+	default:
+		return
+	}
+	if err != nil && !errors.Is(err, services.ErrDebtNotFound) {
+		h.sendError(chatID, "Ошибка при изменении долга.", err)
+		return
+	}
+	h.showDebtCard(chatID, messageID, debtID)
+}
+
+// Ввод суммы погашения: дальше операция проходит обычный выбор категории и даты
+func (h *BotHandler) askDebtPayment(chatID, debtID int64) {
+	debt, err := h.service.GetDebt(chatID, debtID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении долга.", err)
+		return
+	}
+	if !debt.IsOpen() {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Долг уже погашен или закрыт."))
+		return
+	}
+	state := StateWaitingIncome
+	if services.PaymentType(debt.Direction) == "expense" {
+		state = StateWaitingExpense
+	}
+	h.setState(chatID, &models.ChatState{State: state, DebtID: debt.ID, Currency: debt.Currency})
+	h.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Погашение долга (%s): осталось %s %s. Введите сумму (или /cancel):",
+		debt.Counterparty, formatMoney(debt.Remaining()), debt.Currency)))
+}
+
+// Новый срок возврата, введенный текстом
+func (h *BotHandler) handleDebtDueInput(chatID int64, state *models.ChatState, text string) {
+	var due time.Time
+	if strings.TrimSpace(text) != "-" {
+		var ok bool
+		due, ok = dates.ParseFuture(text, h.now(chatID))
+		if !ok {
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Не удалось распознать дату. Пример: 15.11, «пт» или «завтра»"))
+			return
+		}
+	}
+	h.resetState(chatID)
+	if err := h.service.SetDebtDueDate(chatID, state.DebtID, due); err != nil {
+		h.sendError(chatID, "Ошибка при изменении долга.", err)
+		return
+	}
+	h.showDebtCard(chatID, 0, state.DebtID)
+}
+
+// Итоги по людям и список долгов; withClosed добавляет погашенные и закрытые
+func (h *BotHandler) showDebts(chatID int64, messageID int, withClosed bool) {
+	debts, err := h.service.GetDebts(chatID, withClosed)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении долгов.", err)
+		return
+	}
+
+	var b strings.Builder
+	balances := services.DebtBalances(debts)
+	if len(balances) == 0 {
+		b.WriteString("Открытых долгов нет.\n")
+	} else {
+		b.WriteString("Итоги по людям:\n")
+		for _, balance := range balances {
+			b.WriteString(debtBalanceLine(balance) + "\n")
+		}
+	}
+	b.WriteString("\n" + debtUsage)
+
+	today := dates.Day(h.now(chatID))
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, d := range debts {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(debtLine(d, today), fmt.Sprintf("debt:open:%d", d.ID)),
+		))
+	}
+	toggle := tgbotapi.NewInlineKeyboardButtonData("📜 Все долги", "debt:all")
+	if withClosed {
+		toggle = tgbotapi.NewInlineKeyboardButtonData("📋 Только открытые", "debt:list")
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("➕ Долг", "debt:add"),
+		toggle,
+	))
+	h.sendOrEdit(chatID, messageID, b.String(), tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// Карточка долга с погашениями и кнопками
+func (h *BotHandler) showDebtCard(chatID int64, messageID int, debtID int64) {
+	debt, err := h.service.GetDebt(chatID, debtID)
+	if errors.Is(err, services.ErrDebtNotFound) {
+		h.showDebts(chatID, messageID, false)
+		return
+	}
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении долга.", err)
+		return
+	}
+
+	today := dates.Day(h.now(chatID))
+	title := "🤝 Вы дали в долг: "
+	if debt.Direction == services.DebtBorrowed {
+		title = "🤝 Вы взяли в долг: "
+	}
+	text := fmt.Sprintf("%s%s\nСумма: %s\nПогашено: %s\nОсталось: %s\nДата: %s", title, debt.Counterparty,
+		amountWithCurrency(debt.Amount, debt.Currency), amountWithCurrency(debt.Repaid, debt.Currency),
+		amountWithCurrency(debt.Remaining(), debt.Currency), debt.Date.Format(displayDateLayout))
+	switch {
+	case debt.DueDate.IsZero():
+		text += "\nСрок: не указан"
+	case debt.Overdue(today):
+		text += "\nСрок: " + debt.DueDate.Format(displayDateLayout) + " ⚠️ просрочен"
+	default:
+		text += "\nСрок: " + debt.DueDate.Format(displayDateLayout)
+	}
+	if debt.Note != "" {
+		text += "\nЗаметка: " + debt.Note
+	}
+	switch {
+	case debt.Closed:
+		text += "\n\nДолг закрыт."
+	case !debt.IsOpen():
+		text += "\n\n✅ Долг погашен."
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if debt.IsOpen() {
+		rows = append(rows,
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("💰 Погашение", fmt.Sprintf("debt:pay:%d", debt.ID)),
+				tgbotapi.NewInlineKeyboardButtonData("📅 Срок", fmt.Sprintf("debt:due:%d", debt.ID)),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("✅ Закрыть", fmt.Sprintf("debt:close:%d", debt.ID)),
+				tgbotapi.NewInlineKeyboardButtonData("🗑 Удалить", fmt.Sprintf("debt:del:%d", debt.ID)),
+			),
+		)
+	} else {
+		row := tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("🗑 Удалить", fmt.Sprintf("debt:del:%d", debt.ID)))
+		if debt.Closed {
+			row = append([]tgbotapi.InlineKeyboardButton{
+				tgbotapi.NewInlineKeyboardButtonData("↩️ Открыть", fmt.Sprintf("debt:reopen:%d", debt.ID)),
+			}, row...)
+		}
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("⬅️ К долгам", "debt:list")))
+	h.sendOrEdit(chatID, messageID, text, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// Выбор долга, который погашает операция из карточки истории: подходят открытые долги
+// того же направления и валюты
+func (h *BotHandler) askTransactionDebt(chatID, transactionID int64) {
+	transaction, err := h.service.GetTransaction(chatID, transactionID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении операции.", err)
+		return
+	}
+	debts, err := h.service.GetDebts(chatID, false)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении долгов.", err)
+		return
+	}
+
+	today := dates.Day(h.now(chatID))
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, d := range debts {
+		if services.PaymentType(d.Direction) != transaction.Type || d.Currency != transaction.Currency || d.ID == transaction.DebtID {
+			continue
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(debtLine(d, today), fmt.Sprintf("debt:tx:%d:%d", transaction.ID, d.ID)),
+		))
+	}
+	if transaction.DebtID != 0 {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✖️ Не погашение долга", fmt.Sprintf("debt:tx:%d:0", transaction.ID)),
+		))
+	}
+	if len(rows) == 0 {
+		kind := "доходом"
+		if transaction.Type == "expense" {
+			kind = "расходом"
+		}
+		h.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Нет открытых долгов в %s, которые погашаются %s.", transaction.Currency, kind)))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, "Какой долг погашает операция?")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	h.bot.Send(msg)
+}
+
+// Напоминания о просроченных долгах; вызывается планировщиком
+func (h *BotHandler) sendDebtReminders(now time.Time) {
+	debts, err := h.service.ClaimOverdueDebts(now)
+	if err != nil {
+		log.Printf("Ошибка выбора просроченных долгов: %v", err)
+	}
+	for _, d := range debts {
+		text := fmt.Sprintf("⏰ Срок возврата долга истек %s.\n%s", d.DueDate.Format(displayDateLayout), debtLine(d, time.Time{}))
+		msg := tgbotapi.NewMessage(d.ChatID, text)
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🤝 Открыть долг", fmt.Sprintf("debt:open:%d", d.ID)),
+		))
+		h.bot.Send(msg)
+	}
+}
+
+// Сообщение пользователю о погашении, которое не подходит к долгу; пустое для остальных ошибок
+func debtPaymentError(err error) string {
+	switch {
+	case errors.Is(err, services.ErrDebtOverpaid):
+		return "Сумма больше остатка долга или долг уже закрыт."
+	case errors.Is(err, services.ErrDebtCurrency):
+		return "Валюта операции не совпадает с валютой долга."
+	case errors.Is(err, services.ErrDebtPaymentType):
+		return "Долг, который дали вы, погашается доходом, а взятый вами - расходом."
+	}
+	return ""
+}
+
+// Строка долга: "Иван: должен(на) вам 3 000.00 RUB · до 15.11.2026"; у погашенного и закрытого
+// долга - вся сумма. today отмечает просрочку, нулевой today - без отметки.
+func debtLine(d *models.Debt, today time.Time) string {
+	line := d.Counterparty + ": должен(на) вам "
+	if d.Direction == services.DebtBorrowed {
+		line = d.Counterparty + ": вы должны "
+	}
+	amount := d.Remaining()
+	if !d.IsOpen() {
+		amount = d.Amount
+	}
+	line += formatMoney(amount) + " " + d.Currency
+	if !d.DueDate.IsZero() {
+		line += " · до " + d.DueDate.Format(displayDateLayout)
+	}
+	switch {
+	case d.Closed:
+		line += " · закрыт"
+	case !d.IsOpen():
+		line += " · погашен"
+	case !today.IsZero() && d.Overdue(today):
+		line = "⚠️ " + line
+	}
+	return line
+}
+
+// Итог по человеку: "Иван: должен вам 3 000.00 RUB"
+func debtBalanceLine(b *services.DebtBalance) string {
+	switch {
+	case b.Amount > 0:
+		return fmt.Sprintf("🟢 %s: должен(на) вам %s %s", b.Counterparty, formatMoney(b.Amount), b.Currency)
+	case b.Amount < 0:
+		return fmt.Sprintf("🔴 %s: вы должны %s %s", b.Counterparty, formatMoney(-b.Amount), b.Currency)
+	}
+	return fmt.Sprintf("⚪ %s: в расчете (%s)", b.Counterparty, b.Currency)
+}
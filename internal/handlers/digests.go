@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"errors"
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/digest"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/services"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const digestUsage = "Формат: /digest ежедневно 21:00\n" +
+	"Периодичность: ежедневно, еженедельно (по понедельникам), ежемесячно (1-го числа). " +
+	"Отписаться: /digest ежедневно выкл"
+
+// Время отправки по умолчанию: 9:00
+const defaultDigestMinute = 9 * 60
+
+var digestFrequencies = []string{digest.Daily, digest.Weekly, digest.Monthly}
+
+// Отправка наступивших сводок; вызывается планировщиком
+func (h *BotHandler) sendDigests(now time.Time) {
+	runs, err := h.service.ClaimDueDigests(now)
+	if err != nil {
+		log.Printf("Ошибка выбора сводок к отправке: %v", err)
+	}
+	for _, run := range runs {
+		report, err := h.service.GetReport(run.ChatID, run.Period)
+		if err != nil {
+			log.Printf("Ошибка построения сводки %d: %v", run.ID, err)
+			continue
+		}
+		text := bold("📬 "+digestTitle(run.Frequency)) + "\n"
+		if run.Missed > 0 {
+			text += italic("Бот был недоступен, это сводка за последний завершившийся период") + "\n"
+		}
+		h.sendOrEditFormatted(run.ChatID, 0, text+reportText(report), reportKeyboard(run.Period, now.In(dates.Location(run.Timezone)), models.TransactionFilter{}))
+	}
+}
+
+// Команда /digest: без аргументов - подписки, иначе подписка или отписка
+func (h *BotHandler) handleDigestCommand(chatID int64, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		h.showDigests(chatID, 0)
+		return
+	}
+
+	frequency, ok := digest.ParseFrequency(fields[0])
+	if !ok || len(fields) > 2 {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Не удалось распознать периодичность.\n"+digestUsage))
+		return
+	}
+
+	minute := defaultDigestMinute
+	if len(fields) == 2 {
+		switch word := strings.ToLower(fields[1]); word {
+		case "выкл", "off", "нет":
+			if err := h.service.DeleteDigest(chatID, frequency); err != nil {
+				h.sendError(chatID, "Ошибка при отмене подписки.", err)
+				return
+			}
+			h.showDigests(chatID, 0)
+			return
+		default:
+			if minute, ok = digest.ParseTime(word); !ok {
+				h.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Не удалось распознать время %q.\n%s", fields[1], digestUsage)))
+				return
+			}
+		}
+	}
+
+	h.setDigest(chatID, 0, frequency, minute)
+}
+
+// Кнопки сводок: "dig:list", "dig:add:<периодичность>", "dig:del:<периодичность>"
+func (h *BotHandler) handleDigestAction(chatID int64, messageID int, args string) {
+	action, frequency, _ := strings.Cut(args, ":")
+	switch action {
+	case "list":
+		h.showDigests(chatID, messageID)
+	case "add":
+		h.setDigest(chatID, messageID, frequency, defaultDigestMinute)
+	case "del":
+		if err := h.service.DeleteDigest(chatID, frequency); err != nil {
+			h.sendError(chatID, "Ошибка при отмене подписки.", err)
+			return
+		}
+		h.showDigests(chatID, messageID)
+	}
+}
+
+func (h *BotHandler) setDigest(chatID int64, messageID int, frequency string, minute int) {
+	_, err := h.service.SetDigest(chatID, frequency, minute)
+	switch {
+	case errors.Is(err, services.ErrDigestSchedule):
+		h.bot.Send(tgbotapi.NewMessage(chatID, digestUsage))
+		return
+	case err != nil:
+		h.sendError(chatID, "Ошибка при сохранении подписки.", err)
+		return
+	}
+	h.showDigests(chatID, messageID)
+}
+
+// Подписки пользователя с кнопками отписки и подписки на остальные сводки
+func (h *BotHandler) showDigests(chatID int64, messageID int) {
+	digests, err := h.service.GetDigests(chatID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении подписок.", err)
+		return
+	}
+
+	subscribed := make(map[string]*models.Digest, len(digests))
+	var b strings.Builder
+	if len(digests) == 0 {
+		b.WriteString("Подписок на сводки нет.\n")
+	} else {
+		b.WriteString("Сводки:\n")
+	}
+	for _, d := range digests {
+		subscribed[d.Frequency] = d
+		fmt.Fprintf(&b, "📬 %s в %02d:%02d, следующая %s\n", digestTitle(d.Frequency), d.Minute/60, d.Minute%60,
+			d.NextRun.In(dates.Location(d.Timezone)).Format(displayDateLayout))
+	}
+	if len(digests) > 0 {
+		fmt.Fprintf(&b, "Время указано по часовому поясу %s.\n", digests[0].Timezone)
+	}
+	b.WriteString("\n" + digestUsage)
+
+	var row []tgbotapi.InlineKeyboardButton
+	for _, frequency := range digestFrequencies {
+		if _, ok := subscribed[frequency]; ok {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("❌ "+digestShortTitle(frequency), "dig:del:"+frequency))
+		} else {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("➕ "+digestShortTitle(frequency), "dig:add:"+frequency))
+		}
+	}
+	h.sendOrEdit(chatID, messageID, b.String(), tgbotapi.NewInlineKeyboardMarkup(row))
+}
+
+func digestTitle(frequency string) string {
+	switch frequency {
+	case digest.Weekly:
+		return "Еженедельная сводка"
+	case digest.Monthly:
+		return "Ежемесячная сводка"
+	}
+	return "Ежедневная сводка"
+}
+
+func digestShortTitle(frequency string) string {
+	switch frequency {
+	case digest.Weekly:
+		return "Неделя"
+	case digest.Monthly:
+		return "Месяц"
+	}
+	return "День"
+}
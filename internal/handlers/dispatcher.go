@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"runtime/debug"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// dispatcher распределяет обновления по пулу воркеров.
+// Обновления одного чата всегда попадают в одну и ту же очередь, поэтому
+// обрабатываются строго по порядку, а разные чаты не ждут друг друга.
+// Очереди ограничены: когда очередь воркера заполнена, Dispatch блокируется
+// и перестает забирать новые обновления у Telegram.
+type dispatcher struct {
+	queues  []chan tgbotapi.Update
+	handle  func(tgbotapi.Update)
+	wg      sync.WaitGroup // Работающие воркеры
+	sending sync.WaitGroup // Вызовы Dispatch, которые еще отправляют обновление в очередь
+	quit    chan struct{}  // Закрывается при остановке и прерывает ожидание места в очереди
+	mu      sync.RWMutex   // Защищает closed; удерживается только на время проверки флага
+	closed  bool
+}
+
+// ErrShutdownTimeout возвращается, если принятые обновления или фоновые задачи
+// не успели завершиться до дедлайна остановки
+var ErrShutdownTimeout = errors.New("shutdown timeout exceeded while draining updates")
+
+func newDispatcher(workers, queueSize int, handle func(tgbotapi.Update)) *dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
+	d := &dispatcher{
+		queues: make([]chan tgbotapi.Update, workers),
+		handle: handle,
+		quit:   make(chan struct{}),
+	}
+	for i := range d.queues {
+		d.queues[i] = make(chan tgbotapi.Update, queueSize)
+		d.wg.Add(1)
+		go d.work(d.queues[i])
+	}
+	return d
+}
+
+// Dispatch ставит обновление в очередь воркера, отвечающего за чат.
+// Возвращает false, если диспетчер уже остановлен и обновление не принято,
+// в том числе если остановка началась, пока Dispatch ждал места в очереди.
+func (d *dispatcher) Dispatch(update tgbotapi.Update) bool {
+	d.mu.RLock()
+	if d.closed {
+		d.mu.RUnlock()
+		return false
+	}
+	d.sending.Add(1)
+	d.mu.RUnlock()
+	defer d.sending.Done()
+
+	queue := d.queues[d.shard(update)]
+	select {
+	case queue <- update:
+		return true
+	default:
+	}
+
+	log.Printf("Очередь обработки обновлений заполнена, ожидаем освобождения (update %d)", update.UpdateID)
+	select {
+	case queue <- update:
+		return true
+	case <-d.quit:
+		return false
+	}
+}
+
+// Close перестает принимать обновления и дожидается обработки уже поставленных в очередь.
+// Если ctx истекает раньше, возвращает ErrShutdownTimeout; воркеры при этом продолжают работу.
+func (d *dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.quit)
+		// Очереди закрываются только после того, как все начатые Dispatch вышли,
+		// иначе отправка в закрытый канал вызовет панику
+		go func() {
+			d.sending.Wait()
+			for _, queue := range d.queues {
+				close(queue)
+			}
+		}()
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ErrShutdownTimeout
+	}
+}
+
+func (d *dispatcher) shard(update tgbotapi.Update) int {
+	chat := update.FromChat()
+	if chat == nil {
+		return 0
+	}
+	id := chat.ID
+	if id < 0 {
+		id = -id // ID групп отрицательные
+	}
+	return int(id % int64(len(d.queues)))
+}
+
+func (d *dispatcher) work(queue <-chan tgbotapi.Update) {
+	defer d.wg.Done()
+	for update := range queue {
+		d.safeHandle(update)
+	}
+}
+
+// Паника в обработчике одного обновления не должна останавливать воркер
+func (d *dispatcher) safeHandle(update tgbotapi.Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Паника при обработке обновления %d: %v\n%s", update.UpdateID, r, debug.Stack())
+		}
+	}()
+	d.handle(update)
+}
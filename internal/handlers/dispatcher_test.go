@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func chatUpdate(updateID int, chatID int64) tgbotapi.Update {
+	return tgbotapi.Update{
+		UpdateID: updateID,
+		Message:  &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: chatID}},
+	}
+}
+
+func closeWithin(t *testing.T, d *dispatcher, timeout time.Duration) error {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return d.Close(ctx)
+}
+
+func TestDispatcherPreservesPerChatOrder(t *testing.T) {
+	const perChat = 200
+	chats := []int64{1, 2, 3, -1001, 42, 777}
+
+	var mu sync.Mutex
+	seen := make(map[int64][]int)
+	d := newDispatcher(3, 4, func(u tgbotapi.Update) {
+		mu.Lock()
+		defer mu.Unlock()
+		chatID := u.FromChat().ID
+		seen[chatID] = append(seen[chatID], u.UpdateID)
+	})
+
+	// Обновления разных чатов перемешаны, как при получении от Telegram
+	for i := 0; i < perChat; i++ {
+		for _, chatID := range chats {
+			if !d.Dispatch(chatUpdate(i, chatID)) {
+				t.Fatalf("Dispatch(%d, chat %d) = false before Close", i, chatID)
+			}
+		}
+	}
+	if err := closeWithin(t, d, 5*time.Second); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+
+	for _, chatID := range chats {
+		got := seen[chatID]
+		if len(got) != perChat {
+			t.Fatalf("chat %d: handled %d updates, want %d", chatID, len(got), perChat)
+		}
+		for i, id := range got {
+			if id != i {
+				t.Fatalf("chat %d: update #%d is %d, want %d", chatID, i, id, i)
+			}
+		}
+	}
+}
+
+func TestDispatcherShard(t *testing.T) {
+	d := &dispatcher{queues: make([]chan tgbotapi.Update, 4)}
+
+	tests := []struct {
+		name   string
+		update tgbotapi.Update
+		want   int
+	}{
+		{"private chat", chatUpdate(1, 6), 2},
+		{"group chat uses absolute ID", chatUpdate(1, -6), 2},
+		{"supergroup", chatUpdate(1, -1001234567891), 3},
+		{"callback from chat", tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+			Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 5}},
+		}}, 1},
+		{"no chat", tgbotapi.Update{UpdateID: 1}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.shard(tt.update); got != tt.want {
+				t.Errorf("shard() = %d, want %d", got, tt.want)
+			}
+			// Один и тот же чат всегда попадает в одну очередь
+			if again := d.shard(tt.update); again != tt.want {
+				t.Errorf("second shard() = %d, want %d", again, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatcherOtherChatsDoNotWait(t *testing.T) {
+	release := make(chan struct{})
+	handled := make(chan int64, 1)
+	d := newDispatcher(2, 1, func(u tgbotapi.Update) {
+		chatID := u.FromChat().ID
+		if chatID == 2 {
+			<-release
+			return
+		}
+		handled <- chatID
+	})
+
+	// Чаты 2 и 1 попадают в разные очереди из двух
+	d.Dispatch(chatUpdate(1, 2))
+	d.Dispatch(chatUpdate(2, 1))
+
+	select {
+	case got := <-handled:
+		if got != 1 {
+			t.Fatalf("handled chat %d, want 1", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("chat 1 is blocked by a slow handler of chat 2")
+	}
+
+	close(release)
+	if err := closeWithin(t, d, 5*time.Second); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+}
+
+func TestDispatcherCloseDrainsQueued(t *testing.T) {
+	const total = 50
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var once sync.Once
+	var mu sync.Mutex
+	handled := 0
+	d := newDispatcher(1, total, func(tgbotapi.Update) {
+		once.Do(func() { close(started) })
+		<-release
+		mu.Lock()
+		handled++
+		mu.Unlock()
+	})
+
+	for i := 0; i < total; i++ {
+		d.Dispatch(chatUpdate(i, 1))
+	}
+	<-started
+
+	closed := make(chan error, 1)
+	go func() { closed <- closeWithin(t, d, 5*time.Second) }()
+	<-d.quit // Close уже отметил диспетчер остановленным
+
+	// Пока обработчик занят, Close должен ждать, а новые обновления — отклоняться
+	select {
+	case err := <-closed:
+		t.Fatalf("Close() returned %v before queued updates were handled", err)
+	default:
+	}
+	if d.Dispatch(chatUpdate(total, 1)) {
+		t.Error("Dispatch() after Close = true, want false")
+	}
+
+	close(release)
+	if err := <-closed; err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+	if handled != total {
+		t.Errorf("handled %d updates before Close returned, want %d", handled, total)
+	}
+}
+
+// queueFullSignal закрывает канал, когда Dispatch пишет в журнал, что ждет места в очереди
+type queueFullSignal struct {
+	once sync.Once
+	full chan struct{}
+}
+
+func (s *queueFullSignal) Write(p []byte) (int, error) {
+	if bytes.Contains(p, []byte("заполнена")) {
+		s.once.Do(func() { close(s.full) })
+	}
+	return len(p), nil
+}
+
+func TestDispatcherCloseRespectsDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{}, 1)
+	d := newDispatcher(1, 1, func(tgbotapi.Update) {
+		started <- struct{}{}
+		<-release
+	})
+
+	d.Dispatch(chatUpdate(1, 1)) // Занимает воркер
+	<-started
+	d.Dispatch(chatUpdate(2, 1)) // Заполняет очередь
+
+	signal := &queueFullSignal{full: make(chan struct{})}
+	log.SetOutput(signal)
+	defer log.SetOutput(os.Stderr)
+
+	// Третье обновление ждет места в очереди
+	dispatched := make(chan bool, 1)
+	go func() { dispatched <- d.Dispatch(chatUpdate(3, 1)) }()
+	<-signal.full
+
+	start := time.Now()
+	err := closeWithin(t, d, 50*time.Millisecond)
+	if !errors.Is(err, ErrShutdownTimeout) {
+		t.Fatalf("Close() = %v, want ErrShutdownTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Close() took %v, the drain deadline was ignored", elapsed)
+	}
+
+	select {
+	case ok := <-dispatched:
+		if ok {
+			t.Error("blocked Dispatch() = true after Close, want false")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Dispatch() stays blocked after Close")
+	}
+}
+
+func TestDispatcherRecoversFromPanic(t *testing.T) {
+	handled := make(chan int, 2)
+	d := newDispatcher(1, 2, func(u tgbotapi.Update) {
+		if u.UpdateID == 1 {
+			panic("boom")
+		}
+		handled <- u.UpdateID
+	})
+
+	d.Dispatch(chatUpdate(1, 1))
+	d.Dispatch(chatUpdate(2, 1))
+	if err := closeWithin(t, d, 5*time.Second); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+	if got := <-handled; got != 2 {
+		t.Errorf("handled update %d after panic, want 2", got)
+	}
+}
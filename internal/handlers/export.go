@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/export"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Периоды выгрузки в порядке отображения
+var exportPeriods = []struct{ name, label string }{
+	{dates.PeriodMonth, "Этот месяц"},
+	{dates.PeriodLastMonth, "Прошлый месяц"},
+	{dates.PeriodYear, "С начала года"},
+	{dates.PeriodAll, "Все время"},
+}
+
+// Выбор формата выгрузки
+func (h *BotHandler) sendExportMenu(chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, "Выберите формат выгрузки:")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("CSV", "exp:"+export.FormatCSV),
+			tgbotapi.NewInlineKeyboardButtonData("XLSX", "exp:"+export.FormatXLSX),
+			tgbotapi.NewInlineKeyboardButtonData("JSON", "exp:"+export.FormatJSON),
+		),
+	)
+	h.bot.Send(msg)
+}
+
+// Кнопки выгрузки: "exp:<формат>" - выбор периода, "exp:<формат>:<период>" - отправка файла
+func (h *BotHandler) handleExportAction(chatID int64, messageID int, args string) {
+	format, period, hasPeriod := strings.Cut(args, ":")
+	if !hasPeriod {
+		var rows [][]tgbotapi.InlineKeyboardButton
+		for _, p := range exportPeriods {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(p.label, "exp:"+format+":"+p.name),
+			))
+		}
+		h.bot.Send(tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, "Выберите период выгрузки:", tgbotapi.NewInlineKeyboardMarkup(rows...)))
+		return
+	}
+
+	h.handleExportData(chatID, format, period)
+}
+
+// Функция для выгрузки данных
+func (h *BotHandler) handleExportData(chatID int64, format, periodName string) {
+	period, ok := dates.PeriodByName(periodName, h.now(chatID))
+	if !ok {
+		return
+	}
+
+	data, filename, err := h.service.ExportData(chatID, format, period)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при выгрузке данных.", err)
+		return
+	}
+	defer data.Close()
+
+	h.bot.Send(tgbotapi.NewChatAction(chatID, tgbotapi.ChatUploadDocument))
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileReader{Name: filename, Reader: data})
+	if _, err := h.bot.Send(doc); err != nil {
+		h.sendError(chatID, "Ошибка при выгрузке данных.", err)
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"finuchet-bot/internal/forecast"
+	"finuchet-bot/internal/services"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Сколько категорий каждого типа показывать в прогнозе; растущие расходы показываются всегда
+const forecastCategoriesLimit = 8
+
+// Прогноз на конец месяца с кнопкой обновления
+func (h *BotHandler) showForecast(chatID int64, messageID int) {
+	f, err := h.service.GetForecast(chatID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при построении прогноза.", err)
+		return
+	}
+	h.sendOrEditFormatted(chatID, messageID, forecastText(f), tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("🔄 Обновить", "forecast")),
+	))
+}
+
+// Текст прогноза: итоги месяца сейчас и к концу, остаток счетов и прогноз по категориям
+func forecastText(f *services.Forecast) string {
+	var b strings.Builder
+	b.WriteString(bold(fmt.Sprintf("Прогноз на %s %d", strings.ToLower(monthNames[f.Month.Month()-1]), f.Month.Year())) + "\n")
+	b.WriteString(italic(fmt.Sprintf("Прошло дней: %d из %d. Суммы в %s", f.DaysElapsed, f.DaysTotal, f.Currency)) + "\n\n")
+
+	b.WriteString(escape(fmt.Sprintf("Доходы: %s → %s\n", formatMoney(f.Income), formatMoney(f.ProjectedIncome))))
+	b.WriteString(escape(fmt.Sprintf("Расходы: %s → %s\n", formatMoney(f.Expense), formatMoney(f.ProjectedExpense))))
+	b.WriteString(escape("Баланс месяца: "+formatMoney(f.Forecast.Balance())) + "\n")
+	b.WriteString(escape(fmt.Sprintf("Остаток на счетах: %s → %s", formatMoney(f.Balance), formatMoney(f.EndBalance()))) + "\n")
+
+	writeForecastCategories(&b, "Расходы по категориям", f.Categories, "expense")
+	writeForecastCategories(&b, "Доходы по категориям", f.Categories, "income")
+
+	if trending := f.Trending(); len(trending) > 0 {
+		names := make([]string, len(trending))
+		for i, c := range trending {
+			names[i] = fmt.Sprintf("%s (+%.0f%%)", forecastCategoryName(c), (c.Projected - c.Average).Percent(c.Average))
+		}
+		b.WriteString("\n" + escape(fmt.Sprintf("⚠️ Выше среднего за 3 месяца: %s", strings.Join(names, ", "))) + "\n")
+	}
+	if len(f.MissingRates) > 0 {
+		b.WriteString("\n" + escape(fmt.Sprintf("⚠️ Нет курса для %s: эти суммы не вошли в прогноз. Задайте курс командой /rate %s 90.5",
+			strings.Join(f.MissingRates, ", "), f.MissingRates[0])) + "\n")
+	}
+	b.WriteString("\n" + italic("Прогноз учитывает темп трат в этом месяце, повторяющиеся операции и прошлые месяцы.") + "\n")
+	return b.String()
+}
+
+// Строки прогноза категорий одного типа: "Продукты 🛒 — 12 000.00 → 30 500.00 (ср. 27 000.00)"
+func writeForecastCategories(b *strings.Builder, title string, categories []*forecast.Category, kind string) {
+	var lines []string
+	for _, c := range categories {
+		if c.Type != kind || (len(lines) >= forecastCategoriesLimit && !c.Trending()) {
+			continue
+		}
+		line := fmt.Sprintf("%s — %s → %s", forecastCategoryName(c), formatMoney(c.Actual), formatMoney(c.Projected))
+		if c.Average > 0 {
+			line += " (ср. " + formatMoney(c.Average) + ")"
+		}
+		if c.Trending() {
+			line = "⚠️ " + line
+		}
+		lines = append(lines, escape(line))
+	}
+	if len(lines) == 0 {
+		return
+	}
+	b.WriteString("\n" + bold(title) + "\n" + strings.Join(lines, "\n") + "\n")
+}
+
+func forecastCategoryName(c *forecast.Category) string {
+	switch {
+	case c.Category == "":
+		return "Без категории"
+	case c.Emoji != "":
+		return c.Category + " " + c.Emoji
+	}
+	return c.Category
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"finuchet-bot/internal/money"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Разметка форматированных сообщений. Весь пользовательский текст в них проходит через
+// escape, а оформление - через bold/italic, поэтому смена режима затрагивает только этот файл.
+const parseMode = tgbotapi.ModeHTML
+
+var (
+	htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	// В MarkdownV2 экранируется и сама обратная косая черта
+	markdownV2Escaper = strings.NewReplacer(
+		"\\", "\\\\", "_", "\\_", "*", "\\*", "[", "\\[", "]", "\\]", "(", "\\(", ")", "\\)",
+		"~", "\\~", "`", "\\`", ">", "\\>", "#", "\\#", "+", "\\+", "-", "\\-", "=", "\\=",
+		"|", "\\|", "{", "\\{", "}", "\\}", ".", "\\.", "!", "\\!",
+	)
+)
+
+// Экранирование текста для parseMode
+func escape(s string) string {
+	if parseMode == tgbotapi.ModeMarkdownV2 {
+		return markdownV2Escaper.Replace(s)
+	}
+	return htmlEscaper.Replace(s)
+}
+
+// Жирный текст; s экранируется
+func bold(s string) string {
+	if parseMode == tgbotapi.ModeMarkdownV2 {
+		return "*" + escape(s) + "*"
+	}
+	return "<b>" + escape(s) + "</b>"
+}
+
+// Курсив; s экранируется
+func italic(s string) string {
+	if parseMode == tgbotapi.ModeMarkdownV2 {
+		return "_" + escape(s) + "_"
+	}
+	return "<i>" + escape(s) + "</i>"
+}
+
+// Отправка форматированного сообщения или замена текста существующего
+func (h *BotHandler) sendOrEditFormatted(chatID int64, messageID int, text string, markup tgbotapi.InlineKeyboardMarkup) {
+	if messageID == 0 {
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ParseMode = parseMode
+		msg.ReplyMarkup = markup
+		h.bot.Send(msg)
+		return
+	}
+	edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text, markup)
+	edit.ParseMode = parseMode
+	h.bot.Send(edit)
+}
+
+// Сумма с разделителями разрядов: 12 345.67
+func formatMoney(amount money.Amount) string {
+	s := amount.String()
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, fraction, _ := strings.Cut(s, ".")
+
+	var b strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteRune(' ')
+		}
+		b.WriteRune(digit)
+	}
+	return sign + b.String() + "." + fraction
+}
+
+// Сообщение о неверно введенной сумме
+func amountErrorText(err error) string {
+	switch {
+	case errors.Is(err, money.ErrPrecision):
+		return "Укажите не больше двух знаков после запятой."
+	case errors.Is(err, money.ErrOverflow):
+		return "Слишком большая сумма: не больше " + formatMoney(money.Max) + "."
+	}
+	return "Укажите корректную сумму."
+}
+
+// Полоса прогресса из 10 делений: "▓▓▓░░░░░░░"; больше 100% отображается полной полосой
+func progressBar(percent float64) string {
+	filled := int(percent / 10)
+	if filled < 0 {
+		filled = 0
+	}
+	if filled > 10 {
+		filled = 10
+	}
+	return strings.Repeat("▓", filled) + strings.Repeat("░", 10-filled)
+}
@@ -0,0 +1,401 @@
+package handlers
+
+import (
+	"errors"
+	"finuchet-bot/internal/currency"
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"finuchet-bot/internal/quickentry"
+	"finuchet-bot/internal/services"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const goalUsage = "Новая цель: /goal Отпуск 150000 к июлю (можно с валютой и датой: /goal Ноутбук 1500 USD до 01.03.2027).\n" +
+	"Взнос: /goal Отпуск +5000 или кнопка «➕ Взнос». Часть дохода или отдельную операцию можно внести " +
+	"в цель кнопкой «🎯 Цель» в карточке операции."
+
+// Сколько последних взносов показывать в карточке цели
+const goalContributionsShown = 5
+
+// Команда /goal: без аргументов - цели с прогрессом; "<название> <сумма> [валюта] [к|до <срок>]" -
+// новая цель или новые сумма и срок существующей; "<название> +<сумма>" - взнос в цель
+func (h *BotHandler) handleGoalCommand(chatID int64, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		h.showGoals(chatID, 0)
+		return
+	}
+
+	var deadline time.Time
+	if n := len(fields); n > 2 && (strings.EqualFold(fields[n-2], "к") || strings.EqualFold(fields[n-2], "до")) {
+		date, ok := dates.ParseFuture(fields[n-1], h.now(chatID))
+		if !ok {
+			h.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Не удалось распознать срок %q.\n%s", fields[n-1], goalUsage)))
+			return
+		}
+		deadline, fields = date, fields[:n-2]
+	}
+	code := ""
+	if c, ok := currency.Parse(fields[len(fields)-1]); ok && len(fields) > 2 {
+		code, fields = c, fields[:len(fields)-1]
+	}
+	var amount money.Amount
+	contribution := false
+	if len(fields) > 1 {
+		word, plus := strings.CutPrefix(fields[len(fields)-1], "+")
+		parsed, err := quickentry.ParseAmount(word)
+		switch {
+		case err == nil:
+			amount, contribution, fields = parsed, plus, fields[:len(fields)-1]
+		case plus || !errors.Is(err, quickentry.ErrInvalidAmount):
+			h.bot.Send(tgbotapi.NewMessage(chatID, amountErrorText(err)))
+			return
+		}
+	}
+	name := strings.Join(fields, " ")
+
+	goals, err := h.service.GetGoals(chatID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении целей.", err)
+		return
+	}
+	goal := findGoal(goals, name)
+	switch {
+	case goal != nil && contribution:
+		completed, err := h.service.Contribute(chatID, goal.ID, amount)
+		h.finishGoalChange(chatID, goal.ID, completed, err)
+	case goal != nil:
+		completed := false
+		if amount != 0 {
+			completed, err = h.service.SetGoalTarget(chatID, goal.ID, amount)
+		}
+		if err == nil && !deadline.IsZero() {
+			err = h.service.SetGoalDeadline(chatID, goal.ID, deadline)
+		}
+		h.finishGoalChange(chatID, goal.ID, completed, err)
+	case contribution:
+		h.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Цель «%s» не найдена.\n%s", name, goalUsage)))
+	case amount == 0:
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Укажите сумму цели.\n"+goalUsage))
+	default:
+		h.createGoal(chatID, &models.Goal{Name: name, Target: amount, Currency: code, Deadline: deadline})
+	}
+}
+
+func (h *BotHandler) createGoal(chatID int64, goal *models.Goal) {
+	err := h.service.CreateGoal(chatID, goal)
+	switch {
+	case errors.Is(err, services.ErrInvalidGoalName):
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Название цели должно быть от 1 до 100 символов.\n"+goalUsage))
+		return
+	case errors.Is(err, services.ErrGoalExists):
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Цель с таким названием уже есть."))
+		return
+	case errors.Is(err, services.ErrInvalidAmount), errors.Is(err, money.ErrOverflow):
+		h.bot.Send(tgbotapi.NewMessage(chatID, amountErrorText(err)))
+		return
+	case err != nil:
+		h.sendError(chatID, "Ошибка при создании цели.", err)
+		return
+	}
+	h.showGoalCard(chatID, 0, goal.ID)
+}
+
+// Кнопки целей: "goal:list", "goal:add", "goal:<действие>:<id цели>",
+// "goal:tx:<id операции>:<id цели>" - взнос из операции
+func (h *BotHandler) handleGoalAction(chatID int64, messageID int, args string) {
+	action, param, _ := strings.Cut(args, ":")
+
+	switch action {
+	case "list":
+		h.showGoals(chatID, messageID)
+		return
+	case "add":
+		h.bot.Send(tgbotapi.NewMessage(chatID, goalUsage))
+		return
+	case "tx":
+		transactionParam, goalParam, _ := strings.Cut(param, ":")
+		transactionID, err1 := strconv.ParseInt(transactionParam, 10, 64)
+		goalID, err2 := strconv.ParseInt(goalParam, 10, 64)
+		if err1 != nil || err2 != nil {
+			return
+		}
+		h.contributeFromTransaction(chatID, messageID, transactionID, goalID)
+		return
+	}
+
+	goalID, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return
+	}
+	switch action {
+	case "open":
+	case "pay":
+		h.setState(chatID, &models.ChatState{State: StateGoalAmount, GoalID: goalID})
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Введите сумму взноса в валюте цели (или /cancel):"))
+		return
+	case "due":
+		h.setState(chatID, &models.ChatState{State: StateGoalDue, GoalID: goalID})
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Введите срок цели: «июль», «01.07.2027» или «-», чтобы убрать срок (или /cancel):"))
+		return
+	case "undo":
+		err = h.service.UndoGoalContribution(chatID, goalID)
+	case "del":
+		h.bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🗑 Да, удалить", fmt.Sprintf("goal:delok:%d", goalID)),
+				tgbotapi.NewInlineKeyboardButtonData("Нет", fmt.Sprintf("goal:open:%d", goalID)),
+			),
+		)))
+		return
+	case "delok":
+		if err := h.service.DeleteGoal(chatID, goalID); err != nil {
+			h.sendError(chatID, "Ошибка при удалении цели.", err)
+			return
+		}
+		h.showGoals(chatID, messageID)
+		return
+	default:
+		return
+	}
+	if err != nil && !errors.Is(err, services.ErrGoalNotFound) {
+		h.sendError(chatID, "Ошибка при изменении цели.", err)
+		return
+	}
+	h.showGoalCard(chatID, messageID, goalID)
+}
+
+// Взнос из операции: отдельная операция вносится целиком, для дохода спрашивается, сколько отложить
+func (h *BotHandler) contributeFromTransaction(chatID int64, messageID int, transactionID, goalID int64) {
+	transaction, err := h.service.GetTransaction(chatID, transactionID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении операции.", err)
+		return
+	}
+	h.bot.Send(tgbotapi.NewDeleteMessage(chatID, messageID))
+	if transaction.Type == "income" {
+		h.setState(chatID, &models.ChatState{State: StateGoalAmount, GoalID: goalID, TransactionID: transaction.ID})
+		h.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Сколько отложить из дохода %s? Введите сумму или «все» (или /cancel):",
+			amountWithCurrency(transaction.Amount, transaction.Currency))))
+		return
+	}
+	completed, err := h.service.ContributeFromTransaction(chatID, goalID, transaction.ID, 0)
+	if text := goalContributionError(err); text != "" {
+		h.bot.Send(tgbotapi.NewMessage(chatID, text))
+		return
+	}
+	h.finishGoalChange(chatID, goalID, completed, err)
+}
+
+// Ввод взноса или нового срока цели
+func (h *BotHandler) handleGoalInput(chatID int64, state *models.ChatState, text string) {
+	if state.State == StateGoalDue {
+		var deadline time.Time
+		if strings.TrimSpace(text) != "-" {
+			var ok bool
+			deadline, ok = dates.ParseFuture(text, h.now(chatID))
+			if !ok {
+				h.bot.Send(tgbotapi.NewMessage(chatID, "Не удалось распознать дату. Пример: 01.07.2027 или «июль»"))
+				return
+			}
+		}
+		h.resetState(chatID)
+		h.finishGoalChange(chatID, state.GoalID, false, h.service.SetGoalDeadline(chatID, state.GoalID, deadline))
+		return
+	}
+
+	var amount money.Amount // Ноль - весь нераспределенный доход
+	if word := strings.ToLower(strings.TrimSpace(text)); state.TransactionID == 0 || (word != "все" && word != "всё") {
+		var err error
+		if amount, err = quickentry.ParseAmount(text); err != nil {
+			h.bot.Send(tgbotapi.NewMessage(chatID, amountErrorText(err)))
+			return
+		}
+	}
+	var completed bool
+	var err error
+	if state.TransactionID != 0 {
+		completed, err = h.service.ContributeFromTransaction(chatID, state.GoalID, state.TransactionID, amount)
+	} else {
+		completed, err = h.service.Contribute(chatID, state.GoalID, amount)
+	}
+	if text := goalContributionError(err); text != "" {
+		h.bot.Send(tgbotapi.NewMessage(chatID, text))
+		return // Состояние сохраняется: можно ввести другую сумму
+	}
+	h.resetState(chatID)
+	h.finishGoalChange(chatID, state.GoalID, completed, err)
+}
+
+// Завершение изменения цели: поздравление, если цель только что достигнута, и новая карточка
+func (h *BotHandler) finishGoalChange(chatID, goalID int64, completed bool, err error) {
+	if err != nil {
+		h.sendError(chatID, "Ошибка при изменении цели.", err)
+		return
+	}
+	if completed {
+		if goal, err := h.service.GetGoal(chatID, goalID); err == nil {
+			h.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("🎉 Цель «%s» достигнута: накоплено %s из %s!",
+				goal.Name, formatMoney(goal.Saved), formatMoney(goal.Target)+" "+goal.Currency)))
+		}
+	}
+	h.showGoalCard(chatID, 0, goalID)
+}
+
+// Цели с прогрессом и нужными ежемесячными взносами
+func (h *BotHandler) showGoals(chatID int64, messageID int) {
+	goals, err := h.service.GetGoals(chatID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении целей.", err)
+		return
+	}
+
+	today := dates.Day(h.now(chatID))
+	var b strings.Builder
+	if len(goals) == 0 {
+		b.WriteString("Целей пока нет.\n")
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, g := range goals {
+		b.WriteString(goalSummary(g, today) + "\n\n")
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🎯 "+g.Name, fmt.Sprintf("goal:open:%d", g.ID)),
+		))
+	}
+	b.WriteString("\n" + goalUsage)
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("➕ Цель", "goal:add")))
+	h.sendOrEdit(chatID, messageID, strings.TrimLeft(b.String(), "\n"), tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// Карточка цели с последними взносами
+func (h *BotHandler) showGoalCard(chatID int64, messageID int, goalID int64) {
+	goal, err := h.service.GetGoal(chatID, goalID)
+	if errors.Is(err, services.ErrGoalNotFound) {
+		h.showGoals(chatID, messageID)
+		return
+	}
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении цели.", err)
+		return
+	}
+	contributions, err := h.service.GetGoalContributions(chatID, goalID, goalContributionsShown)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении взносов.", err)
+		return
+	}
+
+	text := goalSummary(goal, dates.Day(h.now(chatID)))
+	if goal.Remaining() > 0 {
+		text += "\nОсталось: " + formatMoney(goal.Remaining()) + " " + goal.Currency
+	}
+	if len(contributions) > 0 {
+		text += "\n\nПоследние взносы:"
+		for _, c := range contributions {
+			text += fmt.Sprintf("\n%s +%s", c.Date.Format("02.01"), formatMoney(c.Amount))
+			if c.TransactionID != 0 {
+				text += " из операции"
+			}
+		}
+	}
+
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("➕ Взнос", fmt.Sprintf("goal:pay:%d", goal.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("📅 Срок", fmt.Sprintf("goal:due:%d", goal.ID)),
+		),
+	}
+	last := tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("🗑 Удалить", fmt.Sprintf("goal:del:%d", goal.ID)))
+	if len(contributions) > 0 {
+		last = append([]tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("↩️ Отменить взнос", fmt.Sprintf("goal:undo:%d", goal.ID)),
+		}, last...)
+	}
+	rows = append(rows, last, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("⬅️ К целям", "goal:list")))
+	h.sendOrEdit(chatID, messageID, text, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// Выбор цели для взноса из операции: подходят недостигнутые цели в валюте операции
+func (h *BotHandler) askTransactionGoal(chatID, transactionID int64) {
+	transaction, err := h.service.GetTransaction(chatID, transactionID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении операции.", err)
+		return
+	}
+	goals, err := h.service.GetGoals(chatID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении целей.", err)
+		return
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, g := range goals {
+		if g.Currency != transaction.Currency || !g.CompletedAt.IsZero() {
+			continue
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🎯 %s (%.0f%%)", g.Name, g.Saved.Percent(g.Target)),
+				fmt.Sprintf("goal:tx:%d:%d", transaction.ID, g.ID)),
+		))
+	}
+	if len(rows) == 0 {
+		h.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Нет целей в %s.\n%s", transaction.Currency, goalUsage)))
+		return
+	}
+
+	text := "В какую цель внести операцию?"
+	if transaction.Type == "income" {
+		text = "В какую цель отложить часть дохода?"
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	h.bot.Send(msg)
+}
+
+// Сообщение пользователю о взносе, который нельзя сделать; пустое для остальных ошибок
+func goalContributionError(err error) string {
+	switch {
+	case errors.Is(err, services.ErrGoalAllocated):
+		return "Эта сумма уже внесена в цели: можно отложить не больше нераспределенной части операции."
+	case errors.Is(err, services.ErrGoalCurrency):
+		return "Валюта операции не совпадает с валютой цели."
+	case errors.Is(err, services.ErrInvalidAmount), errors.Is(err, money.ErrOverflow):
+		return amountErrorText(err)
+	}
+	return ""
+}
+
+// Прогресс цели: название и срок, полоса, накоплено и нужный ежемесячный взнос
+func goalSummary(g *models.Goal, today time.Time) string {
+	text := "🎯 " + g.Name
+	if !g.Deadline.IsZero() {
+		text += " — к " + g.Deadline.Format(displayDateLayout)
+	}
+	percent := g.Saved.Percent(g.Target)
+	text += fmt.Sprintf("\n%s %.0f%%\nНакоплено: %s из %s", progressBar(percent), percent,
+		formatMoney(g.Saved), formatMoney(g.Target)+" "+g.Currency)
+	switch rate, ok := services.MonthlyRate(g, today); {
+	case !g.CompletedAt.IsZero():
+		text += "\n🎉 Цель достигнута " + g.CompletedAt.Format(displayDateLayout)
+	case ok:
+		text += fmt.Sprintf("\nНужно откладывать: %s %s в месяц", formatMoney(rate), g.Currency)
+	}
+	return text
+}
+
+// Цель по названию без учета регистра
+func findGoal(goals []*models.Goal, name string) *models.Goal {
+	name = strings.Join(strings.Fields(name), " ")
+	for _, g := range goals {
+		if strings.EqualFold(g.Name, name) {
+			return g
+		}
+	}
+	return nil
+}
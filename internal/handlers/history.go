@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"errors"
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"finuchet-bot/internal/quickentry"
+	"finuchet-bot/internal/services"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const historyPerPage = 10
+
+// Список последних операций; messageID = 0 означает новое сообщение
+func (h *BotHandler) showHistory(chatID int64, messageID int, page int) {
+	if page < 0 {
+		page = 0
+	}
+	transactions, hasMore, err := h.service.GetRecentTransactions(chatID, historyPerPage, page*historyPerPage)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении истории.", err)
+		return
+	}
+	if len(transactions) == 0 && page == 0 {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Операций пока нет."))
+		return
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, t := range transactions {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(transactionLine(t), fmt.Sprintf("tx:open:%d", t.ID)),
+		))
+	}
+	var nav []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("◀️ Новее", fmt.Sprintf("tx:page:%d", page-1)))
+	}
+	if hasMore {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("Старее ▶️", fmt.Sprintf("tx:page:%d", page+1)))
+	}
+	if nav != nil {
+		rows = append(rows, nav)
+	}
+
+	h.sendOrEdit(chatID, messageID, "Последние операции. Выберите операцию для изменения:", tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// Кнопки истории и карточки операции: "tx:<действие>:<id или страница>"
+func (h *BotHandler) handleHistoryAction(chatID int64, messageID int, args string) {
+	action, param, _ := strings.Cut(args, ":")
+	id, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return
+	}
+
+	switch action {
+	case "page":
+		h.showHistory(chatID, messageID, int(id))
+	case "open":
+		h.showTransactionCard(chatID, messageID, id)
+	case "amount":
+		h.setState(chatID, &models.ChatState{State: StateEditAmount, TransactionID: id})
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Введите новую сумму (или /cancel):"))
+	case "date":
+		h.setState(chatID, &models.ChatState{State: StateEditDate, TransactionID: id})
+		now := h.now(chatID)
+		msg := tgbotapi.NewMessage(chatID, editDatePrompt)
+		msg.ReplyMarkup = calendarKeyboard(now, now)
+		h.bot.Send(msg)
+	case "note":
+		h.setState(chatID, &models.ChatState{State: StateEditNote, TransactionID: id})
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Введите заметку или «-», чтобы удалить ее (или /cancel):"))
+	case "tags":
+		h.setState(chatID, &models.ChatState{State: StateEditTags, TransactionID: id})
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Введите теги через пробел, например «#отпуск #море», или «-», чтобы удалить их (или /cancel):"))
+	case "merchant":
+		h.setState(chatID, &models.ChatState{State: StateEditMerchant, TransactionID: id})
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Введите продавца или «-», чтобы удалить его (или /cancel):"))
+	case "account":
+		h.askTransactionAccount(chatID, id)
+	case "debt":
+		h.askTransactionDebt(chatID, id)
+	case "goal":
+		h.askTransactionGoal(chatID, id)
+	case "cat":
+		transaction, err := h.service.GetTransaction(chatID, id)
+		if err != nil {
+			h.sendError(chatID, "Ошибка при получении операции.", err)
+			return
+		}
+		h.setState(chatID, &models.ChatState{State: StateEditCategory, TransactionID: id})
+		h.sendEntryCategories(chatID, transaction.Type, "Выберите новую категорию:")
+	case "del":
+		h.bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🗑 Да, удалить", fmt.Sprintf("tx:delok:%d", id)),
+				tgbotapi.NewInlineKeyboardButtonData("Нет", fmt.Sprintf("tx:open:%d", id)),
+			),
+		)))
+	case "delok":
+		if err := h.service.DeleteTransaction(chatID, id); err != nil {
+			h.sendError(chatID, "Ошибка при удалении операции.", err)
+			return
+		}
+		h.bot.Send(tgbotapi.NewEditMessageText(chatID, messageID, "🗑 Операция удалена."))
+	}
+}
+
+// Команда /search: операции с тегом "#отпуск", продавцом или словом в заметке и категории
+func (h *BotHandler) handleSearchCommand(chatID int64, query string) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Формат: /search #отпуск, /search @Магнит или /search кофе"))
+		return
+	}
+	if tag, ok := quickentry.Tag(query); ok {
+		query = "#" + tag
+	} else if merchant, ok := quickentry.Merchant(query); ok {
+		query = merchant
+	}
+	transactions, err := h.service.SearchTransactions(chatID, query)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при поиске операций.", err)
+		return
+	}
+	if len(transactions) == 0 {
+		h.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("По запросу «%s» ничего не найдено.", query)))
+		return
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, t := range transactions {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(transactionLine(t), fmt.Sprintf("tx:open:%d", t.ID)),
+		))
+	}
+	text := fmt.Sprintf("Последние операции по запросу «%s»:", query)
+	h.sendOrEdit(chatID, 0, text, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// Ввод нового значения поля операции
+func (h *BotHandler) handleTransactionEdit(chatID int64, state *models.ChatState, text string) {
+	var err error
+	switch state.State {
+	case StateEditAmount:
+		var amount money.Amount
+		amount, err = quickentry.ParseAmount(text)
+		if err != nil {
+			h.bot.Send(tgbotapi.NewMessage(chatID, amountErrorText(err)))
+			return
+		}
+		err = h.service.UpdateTransactionAmount(chatID, state.TransactionID, amount)
+	case StateEditDate:
+		date, ok := dates.Parse(text, h.now(chatID))
+		if !ok {
+			h.bot.Send(tgbotapi.NewMessage(chatID, unknownDateReply))
+			return
+		}
+		err = h.service.UpdateTransactionDate(chatID, state.TransactionID, date)
+	case StateEditNote:
+		if strings.TrimSpace(text) == "-" {
+			text = ""
+		}
+		err = h.service.UpdateTransactionNote(chatID, state.TransactionID, text)
+		if errors.Is(err, services.ErrNoteTooLong) {
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Заметка слишком длинная, сократите ее до 255 символов."))
+			return
+		}
+	case StateEditTags:
+		var tags []string
+		if strings.TrimSpace(text) != "-" {
+			tags = quickentry.Tags(text)
+		}
+		err = h.service.UpdateTransactionTags(chatID, state.TransactionID, tags)
+		if errors.Is(err, services.ErrTagTooLong) {
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Тег слишком длинный, сократите его до 50 символов."))
+			return
+		}
+	case StateEditMerchant:
+		if strings.TrimSpace(text) == "-" {
+			text = ""
+		}
+		err = h.service.UpdateTransactionMerchant(chatID, state.TransactionID, strings.TrimPrefix(strings.TrimSpace(text), "@"))
+		if errors.Is(err, services.ErrMerchantTooLong) {
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Название продавца слишком длинное, сократите его до 100 символов."))
+			return
+		}
+	}
+
+	h.finishTransactionEdit(chatID, state.TransactionID, err)
+}
+
+// Завершение изменения операции: сброс состояния и новая карточка
+func (h *BotHandler) finishTransactionEdit(chatID, transactionID int64, err error) {
+	h.resetState(chatID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при изменении операции.", err)
+		return
+	}
+	h.showTransactionCard(chatID, 0, transactionID)
+}
+
+// Карточка операции с кнопками изменения и удаления
+func (h *BotHandler) showTransactionCard(chatID int64, messageID int, transactionID int64) {
+	t, err := h.service.GetTransaction(chatID, transactionID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении операции.", err)
+		return
+	}
+
+	h.sendOrEdit(chatID, messageID, transactionDetails(t), tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("💰 Сумма", fmt.Sprintf("tx:amount:%d", t.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("🗂 Категория", fmt.Sprintf("tx:cat:%d", t.ID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📅 Дата", fmt.Sprintf("tx:date:%d", t.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("📝 Заметка", fmt.Sprintf("tx:note:%d", t.ID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🏷 Теги", fmt.Sprintf("tx:tags:%d", t.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("🏪 Продавец", fmt.Sprintf("tx:merchant:%d", t.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("💳 Счет", fmt.Sprintf("tx:account:%d", t.ID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🎯 Цель", fmt.Sprintf("tx:goal:%d", t.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("🤝 Долг", fmt.Sprintf("tx:debt:%d", t.ID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🗑 Удалить", fmt.Sprintf("tx:del:%d", t.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ К истории", "tx:page:0"),
+		),
+	))
+}
+
+// Краткая строка операции для списка
+func transactionLine(t *models.Transaction) string {
+	sign := "−"
+	if t.Type == "income" {
+		sign = "+"
+	}
+	line := fmt.Sprintf("%s %s%s %s", t.Date.Format("02.01"), sign, amountWithCurrency(t.Amount, t.Currency), categoryName(t))
+	if t.Merchant != "" {
+		line += " · " + t.Merchant
+	}
+	if t.Note != "" {
+		line += " · " + t.Note
+	}
+	if len(t.Tags) > 0 {
+		line += " " + tagsText(t.Tags)
+	}
+	if t.RecurringID != 0 {
+		line += " 🔁"
+	}
+	if t.DebtID != 0 {
+		line += " 🤝"
+	}
+	return line
+}
+
+// Подробное описание операции
+func transactionDetails(t *models.Transaction) string {
+	kind := "Расход"
+	if t.Type == "income" {
+		kind = "Доход"
+	}
+	text := fmt.Sprintf("%s: %s\nКатегория: %s\nДата: %s", kind, amountWithCurrency(t.Amount, t.Currency), categoryName(t), t.Date.Format(displayDateLayout))
+	if t.Account != "" {
+		text += "\nСчет: " + t.Account
+	}
+	if t.Merchant != "" {
+		text += "\nПродавец: " + t.Merchant
+	}
+	if len(t.Tags) > 0 {
+		text += "\nТеги: " + tagsText(t.Tags)
+	}
+	if t.Note != "" {
+		text += "\nЗаметка: " + t.Note
+	}
+	if t.Debt != "" {
+		text += "\nПогашение долга: " + t.Debt
+	}
+	if t.Member != "" {
+		text += "\nЗаписал(а): " + t.Member
+	}
+	return text
+}
+
+// Теги для показа: "#отпуск #море"
+func tagsText(tags []string) string {
+	return "#" + strings.Join(tags, " #")
+}
+
+// Сумма с кодом валюты: "350.00 RUB"; без кода, если валюта не указана
+func amountWithCurrency(amount money.Amount, code string) string {
+	if code == "" {
+		return amount.String()
+	}
+	return amount.String() + " " + code
+}
+
+func categoryName(t *models.Transaction) string {
+	if t.Category == "" {
+		return "без категории"
+	}
+	return t.Category
+}
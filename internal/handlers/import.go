@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"finuchet-bot/internal/bankimport"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/services"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"path"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	maxImportFileSize = 5 << 20 // Максимальный размер выписки
+	importPreviewRows = 10      // Строк выписки в предпросмотре
+)
+
+var errImportFileTooLarge = errors.New("statement file is too large")
+
+// Загрузка файлов выписок из Telegram
+var importClient = &http.Client{Timeout: 30 * time.Second}
+
+// Подсказка по импорту выписки
+func (h *BotHandler) sendImportHelp(chatID int64) {
+	h.bot.Send(tgbotapi.NewMessage(chatID, "Отправьте выписку банка файлом CSV (до 5 МБ). "+
+		"После выбора формата выписки бот покажет найденные операции и загрузит их после подтверждения."))
+}
+
+// Получен файл: предлагаем выбрать формат выписки
+func (h *BotHandler) handleDocument(chatID int64, doc *tgbotapi.Document) {
+	ext := strings.ToLower(path.Ext(doc.FileName))
+	if ext != ".csv" && ext != ".txt" {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Поддерживаются только выписки в формате CSV."))
+		return
+	}
+	if doc.FileSize > maxImportFileSize {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Файл слишком большой, максимум 5 МБ."))
+		return
+	}
+
+	h.setState(chatID, &models.ChatState{State: StateImportLayout, FileID: doc.FileID})
+	h.sendOrEdit(chatID, 0, "Выберите формат выписки:", h.layoutKeyboard())
+}
+
+// Кнопки шаблонов выписок
+func (h *BotHandler) layoutKeyboard() tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, layout := range h.layouts {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(layout.Title, "imp:layout:"+layout.Name),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("↩️ Отменить", "imp:cancel"),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// Кнопки импорта: "imp:layout:<шаблон>" - предпросмотр, "imp:layouts" - другой шаблон,
+// "imp:ok" - загрузка, "imp:cancel" - отмена
+func (h *BotHandler) handleImportAction(chatID int64, messageID int, args string) {
+	action, param, _ := strings.Cut(args, ":")
+	state := h.getState(chatID)
+
+	switch action {
+	case "layouts":
+		if state.State != StateImportConfirm {
+			h.bot.Send(tgbotapi.NewEditMessageText(chatID, messageID, "Выписка уже обработана."))
+			return
+		}
+		state.State = StateImportLayout
+		h.setState(chatID, state)
+		h.sendOrEdit(chatID, messageID, "Выберите формат выписки:", h.layoutKeyboard())
+
+	case "layout":
+		if state.State != StateImportLayout && state.State != StateImportConfirm {
+			h.bot.Send(tgbotapi.NewEditMessageText(chatID, messageID, "Выписка уже обработана."))
+			return
+		}
+		h.previewImport(chatID, messageID, state, param)
+
+	case "ok":
+		if state.State != StateImportConfirm {
+			h.bot.Send(tgbotapi.NewEditMessageText(chatID, messageID, "Выписка уже обработана."))
+			return
+		}
+		h.confirmImport(chatID, messageID, state)
+
+	case "cancel":
+		if state.State == StateImportLayout || state.State == StateImportConfirm {
+			h.resetState(chatID)
+		}
+		h.bot.Send(tgbotapi.NewEditMessageText(chatID, messageID, "Импорт отменен."))
+	}
+}
+
+// Разбор выписки по выбранному шаблону и предпросмотр операций
+func (h *BotHandler) previewImport(chatID int64, messageID int, state *models.ChatState, layoutName string) {
+	layout, ok := h.layout(layoutName)
+	if !ok {
+		return
+	}
+
+	data, err := h.downloadFile(state.FileID)
+	if err != nil {
+		h.sendError(chatID, "Не удалось загрузить файл выписки.", err)
+		return
+	}
+	result, err := h.service.PrepareImport(chatID, data, layout)
+	if err != nil {
+		h.sendImportError(chatID, err)
+		return
+	}
+
+	state.State = StateImportConfirm
+	state.Layout = layout.Name
+	h.setState(chatID, state)
+
+	newRows := len(result.Rows) - result.Duplicates
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if newRows > 0 {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("✅ Загрузить (%d)", newRows), "imp:ok"),
+		))
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔄 Другой формат", "imp:layouts"),
+			tgbotapi.NewInlineKeyboardButtonData("↩️ Отменить", "imp:cancel"),
+		),
+	)
+	h.sendOrEdit(chatID, messageID, importPreview(layout, result), tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// Загрузка новых операций из выписки
+func (h *BotHandler) confirmImport(chatID int64, messageID int, state *models.ChatState) {
+	layout, ok := h.layout(state.Layout)
+	if !ok {
+		return
+	}
+
+	data, err := h.downloadFile(state.FileID)
+	if err != nil {
+		h.sendError(chatID, "Не удалось загрузить файл выписки.", err)
+		return
+	}
+	result, err := h.service.ImportTransactions(chatID, data, layout)
+	if err != nil {
+		h.sendImportError(chatID, err)
+		return
+	}
+	h.resetState(chatID)
+
+	text := fmt.Sprintf("✅ Загружено операций: %d", result.Imported)
+	if result.Duplicates > 0 {
+		text += fmt.Sprintf("\nПропущено дублей: %d", result.Duplicates)
+	}
+	if result.Skipped > 0 {
+		text += fmt.Sprintf("\nНе распознано строк: %d", result.Skipped)
+	}
+	h.bot.Send(tgbotapi.NewEditMessageText(chatID, messageID, text))
+}
+
+// Текст предпросмотра: итоги и первые строки выписки
+func importPreview(layout bankimport.Layout, result *services.ImportResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Выписка (%s): операций %d, новых %d, дублей %d", layout.Title,
+		len(result.Rows), len(result.Rows)-result.Duplicates, result.Duplicates)
+	if result.Skipped > 0 {
+		fmt.Fprintf(&b, ", не распознано строк %d", result.Skipped)
+	}
+	b.WriteString("\n\n")
+
+	for i, row := range result.Rows {
+		if i == importPreviewRows {
+			fmt.Fprintf(&b, "… и еще %d\n", len(result.Rows)-importPreviewRows)
+			break
+		}
+		sign := "-"
+		if row.Type == "income" {
+			sign = "+"
+		}
+		category := "без категории"
+		if row.Category != nil {
+			category = row.Category.Label()
+		}
+		fmt.Fprintf(&b, "%s %s%s %s", row.Date.Format(displayDateLayout), sign, amountWithCurrency(row.Amount, row.Currency), category)
+		if row.Description != "" {
+			b.WriteString(" · " + row.Description)
+		}
+		if row.Duplicate {
+			b.WriteString(" (уже есть)")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func (h *BotHandler) sendImportError(chatID int64, err error) {
+	switch {
+	case errors.Is(err, services.ErrEmptyStatement):
+		h.bot.Send(tgbotapi.NewMessage(chatID, "В файле не найдено операций. Проверьте, что выбран правильный формат выписки."))
+	case errors.Is(err, bankimport.ErrTooManyRows):
+		h.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Слишком много строк в выписке, максимум %d.", bankimport.MaxRows)))
+	default:
+		h.sendError(chatID, "Ошибка при разборе выписки.", err)
+	}
+}
+
+// Шаблон выписки по имени
+func (h *BotHandler) layout(name string) (bankimport.Layout, bool) {
+	for _, layout := range h.layouts {
+		if layout.Name == name {
+			return layout, true
+		}
+	}
+	return bankimport.Layout{}, false
+}
+
+// Загрузка файла, отправленного пользователем, с ограничением размера
+func (h *BotHandler) downloadFile(fileID string) (io.Reader, error) {
+	url, err := h.bot.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := importClient.Get(url)
+	if err != nil {
+		// Адрес файла содержит токен бота, в лог он попасть не должен
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("download file %s: %v", fileID, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download file: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImportFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImportFileSize {
+		return nil, errImportFileTooLarge
+	}
+	return bytes.NewReader(data), nil
+}
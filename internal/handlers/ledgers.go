@@ -0,0 +1,406 @@
+package handlers
+
+import (
+	"errors"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/quickentry"
+	"finuchet-bot/internal/services"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Права, нужные для действия
+type access int
+
+const (
+	accessRead  access = iota // Просмотр: доступен всем, даже не участникам
+	accessEdit                // Изменение данных: владелец и редакторы
+	accessOwner               // Управление учетом: только владелец
+)
+
+const maxMemberNameLength = 100 // Соответствует VARCHAR(100) в ledger_members
+
+// Участник учета, от имени которого пришло обновление; без отправителя - сам чат
+func memberOf(from *tgbotapi.User, chatID int64) *models.Member {
+	if from == nil {
+		return &models.Member{ID: chatID}
+	}
+	name := strings.TrimSpace(from.FirstName + " " + from.LastName)
+	if name == "" {
+		name = from.UserName
+	}
+	if runes := []rune(name); len(runes) > maxMemberNameLength {
+		name = string(runes[:maxMemberNameLength])
+	}
+	return &models.Member{ID: from.ID, Name: name}
+}
+
+// Проверка прав отправителя; при нехватке прав отправляет объяснение.
+// Незарегистрированный чат пропускается: его обработают сами команды.
+func (h *BotHandler) allowed(chatID int64, from *tgbotapi.User, need access) bool {
+	if need == accessRead {
+		return true
+	}
+	role, err := h.service.MemberRole(chatID, memberOf(from, chatID))
+	if errors.Is(err, services.ErrUserNotFound) {
+		return true
+	}
+	if err != nil {
+		h.sendError(chatID, "Ошибка при проверке прав.", err)
+		return false
+	}
+
+	var text string
+	switch {
+	case need == accessOwner && role == services.RoleOwner, need == accessEdit && services.CanEdit(role):
+		return true
+	case role == "":
+		text = "Вы не участник этого учета. Попросите владельца пригласить вас: /members"
+	case need == accessOwner:
+		text = "Это действие доступно только владельцу учета."
+	default:
+		text = "У вас доступ только для просмотра отчетов и истории."
+	}
+	h.bot.Send(tgbotapi.NewMessage(chatID, text))
+	return false
+}
+
+// Права для текстового сообщения. Переписка в группе, не похожая на операцию, прав не требует.
+func (h *BotHandler) inputAccess(chatID int64, command, args, text string) access {
+	if strings.HasPrefix(command, "/") {
+		return commandAccess(command, args)
+	}
+	if h.getState(chatID).State == StateNone && !quickentry.LooksLikeEntry(text) {
+		return accessRead
+	}
+	return accessEdit
+}
+
+// Команды без аргументов показывают данные, с аргументами - изменяют их
+func commandAccess(command, args string) access {
+	switch command {
+	case "/import":
+		return accessEdit
+	case "/budget", "/currency", "/rate", "/recurring", "/digest", "/settings", "/accounts", "/transfer", "/debt", "/goal":
+		if strings.TrimSpace(args) != "" {
+			return accessEdit
+		}
+	}
+	return accessRead
+}
+
+// Права для кнопки: списки и карточки открываются всем, изменения - по роли
+func callbackAccess(action, args string) access {
+	switch action {
+	case "clear":
+		return accessOwner
+	case "report", "rep", "chart", "edit", "export", "exp", "categories", "catpage",
+		"budgets", "recurring", "digests", "settings", "accounts", "members", "mem", "ledgers", "ldg", "debts", "goals", "forecast":
+		return accessRead
+	case "tx", "cm", "bud", "rec", "dig", "acc", "trf", "debt", "goal":
+		switch sub, _, _ := strings.Cut(args, ":"); sub {
+		case "list", "all", "open", "page":
+			return accessRead
+		}
+	}
+	return accessEdit
+}
+
+// Команда /start: регистрация чата; "/start <приглашение>" из ссылки - вступление в учет
+func (h *BotHandler) handleStartCommand(msg *tgbotapi.Message, token string) {
+	chatID := msg.Chat.ID
+	member := memberOf(msg.From, chatID)
+	created, err := h.service.RegisterUser(chatID, member, msg.Chat.Title)
+	if err != nil {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при регистрации, попробуйте позже."))
+		log.Printf("Ошибка регистрации пользователя: %v", err)
+		return
+	}
+
+	if token = strings.TrimSpace(token); token != "" {
+		joined, err := h.service.AcceptInvite(chatID, member, token)
+		switch {
+		case errors.Is(err, services.ErrInviteNotFound):
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Приглашение не найдено или истекло. Попросите владельца учета прислать новое."))
+		case errors.Is(err, services.ErrLedgerSelection):
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Откройте ссылку-приглашение в личном чате с ботом."))
+		case err != nil:
+			h.sendError(chatID, "Ошибка при вступлении в учет.", err)
+		default:
+			h.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Вы участник учета «%s», роль: %s. "+
+				"Операции и отчеты в этом чате теперь относятся к нему. Вернуться к личному учету: /ledger",
+				ledgerTitle(joined, member.ID), roleTitle(joined.Role))))
+			created = false // Часовой пояс задан в общем учете
+		}
+	}
+
+	h.sendMainMenu(chatID)
+	if created {
+		h.askTimezone(chatID)
+	}
+}
+
+// Участники учета чата; владельцу - кнопки управления и приглашения
+func (h *BotHandler) showMembers(chatID int64, messageID int, actorID int64) {
+	members, err := h.service.GetMembers(chatID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении участников.", err)
+		return
+	}
+
+	actorRole := ""
+	var b strings.Builder
+	b.WriteString("Участники учета:\n")
+	for _, m := range members {
+		b.WriteString(memberLine(m) + "\n")
+		if m.ID == actorID {
+			actorRole = m.Role
+		}
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	switch actorRole {
+	case services.RoleOwner:
+		b.WriteString("\nПригласите участника кнопкой ниже: бот пришлет одноразовую ссылку. " +
+			"Редактор вносит операции, наблюдатель только смотрит отчеты и историю.")
+		for _, m := range members {
+			if m.Role != services.RoleOwner {
+				rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData(memberName(m), fmt.Sprintf("mem:open:%d", m.ID)),
+				))
+			}
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("➕ Редактор", "mem:inv:"+services.RoleEditor),
+			tgbotapi.NewInlineKeyboardButtonData("➕ Наблюдатель", "mem:inv:"+services.RoleViewer),
+		))
+	case "":
+		b.WriteString("\nВы не участник этого учета: попросите владельца прислать приглашение.")
+	default:
+		b.WriteString("\nПриглашать участников может владелец учета.")
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🚪 Покинуть учет", "mem:leave"),
+		))
+	}
+	b.WriteString("\nВыбрать учет для личного чата: /ledger")
+	h.sendOrEdit(chatID, messageID, b.String(), tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// Кнопки участников: "mem:list", "mem:inv:<роль>", "mem:leave", "mem:open:<id>",
+// "mem:role:<id>:<роль>", "mem:del:<id>"; id - Telegram ID участника
+func (h *BotHandler) handleMemberAction(chatID int64, messageID int, from *tgbotapi.User, args string) {
+	actorID := memberOf(from, chatID).ID
+	action, param, _ := strings.Cut(args, ":")
+
+	switch action {
+	case "list":
+		h.showMembers(chatID, messageID, actorID)
+		return
+	case "inv":
+		h.sendInvite(chatID, actorID, param)
+		return
+	case "leave":
+		if err := h.service.RemoveMember(chatID, actorID, actorID); err != nil {
+			h.memberError(chatID, err)
+			return
+		}
+		h.bot.Send(tgbotapi.NewEditMessageText(chatID, messageID, "Вы покинули учет."))
+		return
+	}
+
+	idParam, role, _ := strings.Cut(param, ":")
+	memberID, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		return
+	}
+	switch action {
+	case "open":
+	case "role":
+		err = h.service.SetMemberRole(chatID, actorID, memberID, role)
+	case "del":
+		if err := h.service.RemoveMember(chatID, actorID, memberID); err != nil {
+			h.memberError(chatID, err)
+			return
+		}
+		h.showMembers(chatID, messageID, actorID)
+		return
+	default:
+		return
+	}
+	if err != nil {
+		h.memberError(chatID, err)
+		return
+	}
+	h.showMemberCard(chatID, messageID, actorID, memberID)
+}
+
+// Карточка участника с выбором роли
+func (h *BotHandler) showMemberCard(chatID int64, messageID int, actorID, memberID int64) {
+	members, err := h.service.GetMembers(chatID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении участников.", err)
+		return
+	}
+	var member *models.Member
+	for _, m := range members {
+		if m.ID == memberID {
+			member = m
+		}
+	}
+	if member == nil || member.Role == services.RoleOwner {
+		h.showMembers(chatID, messageID, actorID)
+		return
+	}
+
+	var roles []tgbotapi.InlineKeyboardButton
+	for _, role := range []string{services.RoleEditor, services.RoleViewer} {
+		if role != member.Role {
+			roles = append(roles, tgbotapi.NewInlineKeyboardButtonData("Сделать: "+roleTitle(role), fmt.Sprintf("mem:role:%d:%s", member.ID, role)))
+		}
+	}
+	h.sendOrEdit(chatID, messageID, memberLine(member), tgbotapi.NewInlineKeyboardMarkup(
+		roles,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🗑 Исключить", fmt.Sprintf("mem:del:%d", member.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ К участникам", "mem:list"),
+		),
+	))
+}
+
+// Ссылка-приглашение в учет чата
+func (h *BotHandler) sendInvite(chatID, actorID int64, role string) {
+	invite, err := h.service.CreateInvite(chatID, actorID, role)
+	if err != nil {
+		h.memberError(chatID, err)
+		return
+	}
+	h.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Приглашение в учет, роль: %s. По ссылке можно вступить один раз до %s:\nhttps://t.me/%s?start=%s",
+		roleTitle(invite.Role), invite.ExpiresAt.In(h.now(chatID).Location()).Format(displayDateLayout), h.bot.Self.UserName, invite.Token)))
+}
+
+// Команда /ledger: учеты, в которых участвует пользователь, с выбором учета для личного чата
+func (h *BotHandler) showLedgers(chatID int64, messageID int, memberID int64) {
+	if chatID < 0 {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "В группе ведется ее общий учет. Чтобы вести его из личного чата с ботом, "+
+			"вступите по приглашению владельца (/members) и выберите учет командой /ledger в личном чате."))
+		return
+	}
+	ledgers, activeID, err := h.service.GetLedgers(chatID, memberID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении учетов.", err)
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("Ваши учеты. Операции и отчеты в этом чате относятся к отмеченному:\n")
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, l := range ledgers {
+		mark := "▫️"
+		if l.LedgerID == activeID {
+			mark = "✅"
+		}
+		title := ledgerTitle(l, memberID)
+		fmt.Fprintf(&b, "%s %s — %s\n", mark, title, roleTitle(l.Role))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(mark+" "+title, fmt.Sprintf("ldg:use:%d", l.LedgerID)),
+		))
+	}
+	h.sendOrEdit(chatID, messageID, b.String(), tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// Кнопки учетов: "ldg:list", "ldg:use:<id учета>"
+func (h *BotHandler) handleLedgerAction(chatID int64, messageID int, from *tgbotapi.User, args string) {
+	memberID := memberOf(from, chatID).ID
+	action, param, _ := strings.Cut(args, ":")
+	switch action {
+	case "list":
+	case "use":
+		ledgerID, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return
+		}
+		err = h.service.UseLedger(chatID, memberID, ledgerID)
+		switch {
+		case errors.Is(err, services.ErrNotMember):
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Вы больше не участник этого учета."))
+		case errors.Is(err, services.ErrLedgerSelection):
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Учет выбирается только в личном чате с ботом."))
+			return
+		case err != nil:
+			h.sendError(chatID, "Ошибка при выборе учета.", err)
+			return
+		}
+		h.resetState(chatID) // Незавершенный ввод относился к прежнему учету
+	default:
+		return
+	}
+	h.showLedgers(chatID, messageID, memberID)
+}
+
+func (h *BotHandler) memberError(chatID int64, err error) {
+	switch {
+	case errors.Is(err, services.ErrNotOwner):
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Управлять участниками может только владелец учета."))
+	case errors.Is(err, services.ErrOwnerRole):
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Владелец не может покинуть учет или сменить свою роль."))
+	case errors.Is(err, services.ErrMemberNotFound):
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Участник не найден."))
+	default:
+		h.sendError(chatID, "Ошибка при изменении участников.", err)
+	}
+}
+
+// Строка участника: "✏️ Аня — редактор"
+func memberLine(m *models.Member) string {
+	icon := "✏️"
+	switch m.Role {
+	case services.RoleOwner:
+		icon = "👑"
+	case services.RoleViewer:
+		icon = "👀"
+	}
+	return icon + " " + memberName(m) + " — " + roleTitle(m.Role)
+}
+
+func memberName(m *models.Member) string {
+	if m.Name == "" {
+		return "Участник " + strconv.FormatInt(m.ID, 10)
+	}
+	return m.Name
+}
+
+// Имя автора операций в разбивке отчета
+func memberTotalName(t *models.MemberTotal) string {
+	switch {
+	case t.MemberID == 0:
+		return "Импорт и повторяющиеся"
+	case t.Name == "":
+		return "Бывший участник"
+	}
+	return t.Name
+}
+
+// Название учета для участника memberID: личный, группа или учет другого человека
+func ledgerTitle(m *models.Member, memberID int64) string {
+	switch {
+	case m.LedgerChat == memberID:
+		return "👤 Личный учет"
+	case m.LedgerTitle != "":
+		return "👥 " + m.LedgerTitle
+	}
+	return "👥 Общий учет"
+}
+
+func roleTitle(role string) string {
+	switch role {
+	case services.RoleOwner:
+		return "владелец"
+	case services.RoleViewer:
+		return "наблюдатель"
+	}
+	return "редактор"
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/repository"
+	"finuchet-bot/internal/services"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// pipelineRepo - учет в памяти для прогона обновлений через настоящий обработчик.
+// Чат сам является учетом, все участники - владельцы.
+type pipelineRepo struct {
+	repository.Repository
+	mu           sync.Mutex
+	transactions map[int64][]*models.Transaction // По учету
+}
+
+var pipelineCategories = []*models.Category{
+	{ID: 1, Name: "Кафе", Type: "expense"},
+	{ID: 2, Name: "Зарплата", Type: "income", Aliases: []string{"зп"}},
+}
+
+func (r *pipelineRepo) GetLedgerByChatID(chatID int64) (*models.User, error) {
+	return &models.User{ID: chatID, ChatID: chatID, BaseCurrency: "RUB", Timezone: "Europe/Moscow"}, nil
+}
+
+func (r *pipelineRepo) GetMember(ledgerID, memberID int64) (*models.Member, error) {
+	return &models.Member{LedgerID: ledgerID, ID: memberID, Role: services.RoleOwner}, nil
+}
+
+func (r *pipelineRepo) GetCategories(int64, string, bool) ([]*models.Category, error) {
+	return pipelineCategories, nil
+}
+
+func (r *pipelineRepo) GetCategoryByID(_, categoryID int64) (*models.Category, error) {
+	for _, c := range pipelineCategories {
+		if c.ID == categoryID {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *pipelineRepo) AddTransaction(transaction *models.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	transaction.ID = int64(len(r.transactions[transaction.UserID]) + 1)
+	r.transactions[transaction.UserID] = append(r.transactions[transaction.UserID], transaction)
+	return nil
+}
+
+func (r *pipelineRepo) GetBudget(int64, int64) (*models.Budget, error) {
+	return nil, nil
+}
+
+// Bot API, который на любой запрос отвечает успехом
+func newTestBot(t *testing.T) *tgbotapi.BotAPI {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"ok": true, "result": {"id": 1, "is_bot": true, "first_name": "Финучет", "username": "finuchet_bot",
+			"message_id": 1, "date": 1767225600, "chat": {"id": 1, "type": "private"}}}`)
+	}))
+	t.Cleanup(ts.Close)
+
+	bot, err := tgbotapi.NewBotAPIWithClient("test-token", ts.URL+"/bot%s/%s", ts.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bot
+}
+
+func messageUpdate(updateID int, chat tgbotapi.Chat, from int64, messageID int, text string) tgbotapi.Update {
+	return tgbotapi.Update{UpdateID: updateID, Message: &tgbotapi.Message{
+		MessageID: messageID, From: &tgbotapi.User{ID: from}, Chat: &chat, Text: text,
+	}}
+}
+
+func callbackUpdate(updateID int, chat tgbotapi.Chat, from int64, data string) tgbotapi.Update {
+	return tgbotapi.Update{UpdateID: updateID, CallbackQuery: &tgbotapi.CallbackQuery{
+		ID: fmt.Sprint(updateID), From: &tgbotapi.User{ID: from}, Data: data,
+		Message: &tgbotapi.Message{MessageID: 1, Chat: &chat},
+	}}
+}
+
+// Обновления многих чатов одновременно проходят через диспетчер, настоящий обработчик
+// и хранилище состояний в памяти; запускается с -race (make test)
+func TestPipelineConcurrentChats(t *testing.T) {
+	const perMember = 20
+
+	repo := &pipelineRepo{transactions: make(map[int64][]*models.Transaction)}
+	h := &BotHandler{
+		bot:     newTestBot(t),
+		service: services.NewFinanceService(repo),
+		states:  repository.NewMemoryStateStore(time.Hour),
+	}
+
+	// Личные чаты и группа, где двое вводят операции вперемешку
+	type member struct {
+		chat tgbotapi.Chat
+		from int64
+	}
+	group := tgbotapi.Chat{ID: -100500, Type: "group"}
+	members := []member{{group, 1}, {group, 2}}
+	for id := int64(10); id < 18; id++ {
+		members = append(members, member{tgbotapi.Chat{ID: id, Type: "private"}, id})
+	}
+
+	d := newDispatcher(4, 8, h.handleUpdate)
+	updateID := 0
+	for i := 0; i < perMember; i++ {
+		// Сначала все участники вводят операцию, затем все подтверждают ее
+		for _, m := range members {
+			updateID++
+			entryID := 1000 + i
+			d.Dispatch(messageUpdate(updateID, m.chat, m.from, entryID, "350 кафе"))
+		}
+		for _, m := range members {
+			updateID++
+			d.Dispatch(callbackUpdate(updateID, m.chat, m.from, fmt.Sprintf("qe_ok:%d", 1000+i)))
+		}
+	}
+	if err := closeWithin(t, d, 30*time.Second); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+
+	for _, m := range members {
+		var count int
+		for _, tr := range repo.transactions[m.chat.ID] {
+			if tr.MemberID == m.from {
+				count++
+			}
+		}
+		if count != perMember {
+			t.Errorf("chat %d member %d: %d transactions saved, want %d", m.chat.ID, m.from, count, perMember)
+		}
+		u := *h
+		u.sender = m.from
+		if state := u.getState(m.chat.ID); state.State != StateNone {
+			t.Errorf("chat %d member %d: dialog %q left after confirmation", m.chat.ID, m.from, state.State)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"errors"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"finuchet-bot/internal/quickentry"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	dateLayout        = "2006-01-02" // Формат даты в состоянии диалога
+	displayDateLayout = "02.01.2006" // Формат даты в сообщениях
+)
+
+// Быстрый ввод операции одной строкой с подтверждением; memberID - участник, который ее вводит,
+// entryID - его сообщение с операцией
+func (h *BotHandler) handleQuickEntry(chatID, memberID int64, entryID int, text string) {
+	categories, err := h.service.GetCategories(chatID, "", false)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении категорий.", err)
+		return
+	}
+	entry, err := quickentry.Parse(text, categories, h.now(chatID))
+	if errors.Is(err, money.ErrPrecision) || errors.Is(err, money.ErrOverflow) {
+		h.bot.Send(tgbotapi.NewMessage(chatID, amountErrorText(err)))
+		return
+	}
+	if err != nil {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Не удалось распознать операцию. Пример: 350 кафе обед, +50000 зп"))
+		return
+	}
+
+	state := &models.ChatState{
+		State:    StateQuickConfirm,
+		Type:     entry.Type,
+		Amount:   entry.Amount,
+		Currency: entry.Currency,
+		Date:     entry.Date.Format(dateLayout),
+		Note:     entry.Note,
+		Tags:     entry.Tags,
+		Merchant: entry.Merchant,
+		MemberID: memberID,
+		EntryID:  entryID,
+	}
+
+	// Категория не распознана: предлагаем выбрать ее кнопкой, сумма, дата и заметка сохраняются
+	if entry.Category == nil {
+		h.askQuickEntryCategory(chatID, state)
+		return
+	}
+
+	state.CategoryID = entry.Category.ID
+	h.setState(chatID, state)
+
+	msg := tgbotapi.NewMessage(chatID, h.quickEntrySummary(chatID, state))
+	msg.ReplyMarkup = quickEntryKeyboard(entryID)
+	h.bot.Send(msg)
+}
+
+// Кнопки подтверждения: "qe_<действие>:<entryID>". Номер операции в кнопках не дает
+// кнопкам старого сообщения подтвердить или изменить операцию, введенную позже.
+func quickEntryKeyboard(entryID int) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Записать", fmt.Sprintf("qe_ok:%d", entryID)),
+			tgbotapi.NewInlineKeyboardButtonData("🗂 Категория", fmt.Sprintf("qe_category:%d", entryID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("💳 Счет", fmt.Sprintf("qe_account:%d", entryID)),
+			tgbotapi.NewInlineKeyboardButtonData("↩️ Отменить", fmt.Sprintf("qe_cancel:%d", entryID)),
+		),
+	)
+}
+
+// Ожидающая подтверждения операция, к которой относится кнопка с параметрами args
+// ("<entryID>" или "<entryID>:<параметр>"); nil, если кнопка от другой или уже обработанной операции
+func (h *BotHandler) quickEntryState(chatID int64, args string) *models.ChatState {
+	state := h.getState(chatID)
+	if !quickEntryMatches(state, args) {
+		return nil
+	}
+	return state
+}
+
+func quickEntryMatches(state *models.ChatState, args string) bool {
+	id, _, _ := strings.Cut(args, ":")
+	entryID, err := strconv.Atoi(id)
+	return err == nil && state.State == StateQuickConfirm && state.EntryID == entryID
+}
+
+// Подтверждение операции из быстрого ввода
+func (h *BotHandler) confirmQuickEntry(chatID int64, messageID int, args string) {
+	state := h.quickEntryState(chatID, args)
+	if state == nil {
+		// В группе кнопку мог нажать другой участник: его нажатие не меняет чужое сообщение
+		if h.dialogMember(chatID) == 0 {
+			h.bot.Send(tgbotapi.NewEditMessageText(chatID, messageID, "Операция уже обработана."))
+		}
+		return
+	}
+
+	transaction, err := h.saveTransaction(chatID, state, state.Type, state.CategoryID)
+	if err != nil {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при добавлении операции."))
+		log.Printf("Ошибка добавления операции из быстрого ввода: %v", err)
+		return
+	}
+	h.resetState(chatID)
+	h.bot.Send(tgbotapi.NewEditMessageText(chatID, messageID, "✅ Записано\n"+h.quickEntrySummary(chatID, state)))
+	h.notifyBudget(chatID, transaction)
+}
+
+// Выбор другой категории для операции из быстрого ввода
+func (h *BotHandler) changeQuickEntryCategory(chatID int64, args string) {
+	state := h.quickEntryState(chatID, args)
+	if state == nil {
+		return
+	}
+	h.askQuickEntryCategory(chatID, state)
+}
+
+// Выбор счета для операции из быстрого ввода: кнопки счетов вместо кнопок подтверждения
+func (h *BotHandler) askQuickEntryAccount(chatID int64, messageID int, args string) {
+	state := h.quickEntryState(chatID, args)
+	if state == nil {
+		return
+	}
+	accounts, err := h.service.GetAccounts(chatID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении счетов.", err)
+		return
+	}
+	h.bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, accountsKeyboard(accounts, 0, func(a *models.Account) string {
+		return fmt.Sprintf("qe_acc:%d:%d", state.EntryID, a.ID)
+	})))
+}
+
+// Выбранный счет: "qe_acc:<entryID>:<id счета>"
+func (h *BotHandler) setQuickEntryAccount(chatID int64, messageID int, args string) {
+	state := h.quickEntryState(chatID, args)
+	_, param, _ := strings.Cut(args, ":")
+	accountID, err := strconv.ParseInt(param, 10, 64)
+	if state == nil || err != nil {
+		return
+	}
+	state.AccountID = accountID
+	h.setState(chatID, state)
+	h.bot.Send(tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, h.quickEntrySummary(chatID, state), quickEntryKeyboard(state.EntryID)))
+}
+
+// Отмена операции из быстрого ввода; кнопка старого сообщения не сбрасывает более новую операцию
+func (h *BotHandler) cancelQuickEntry(chatID int64, messageID int, args string) {
+	if h.quickEntryState(chatID, args) != nil {
+		h.resetState(chatID)
+	} else if h.dialogMember(chatID) != 0 {
+		return // Чужая операция в группе
+	}
+	h.bot.Send(tgbotapi.NewEditMessageText(chatID, messageID, "↩️ Операция отменена."))
+}
+
+// Переход к выбору категории с сохранением суммы, даты и заметки
+func (h *BotHandler) askQuickEntryCategory(chatID int64, state *models.ChatState) {
+	state.CategoryID = 0
+	if state.Type == "income" {
+		state.State = StateIncomeCategory
+		h.setState(chatID, state)
+		h.sendIncomeCategories(chatID)
+	} else {
+		state.State = StateExpenseCategory
+		h.setState(chatID, state)
+		h.sendExpenseCategories(chatID)
+	}
+}
+
+// Описание операции для подтверждения
+func (h *BotHandler) quickEntrySummary(chatID int64, state *models.ChatState) string {
+	kind := "Расход"
+	if state.Type == "income" {
+		kind = "Доход"
+	}
+
+	category := "—"
+	if c, err := h.service.GetCategory(chatID, state.CategoryID); err == nil {
+		category = c.Label()
+	}
+
+	text := fmt.Sprintf("%s: %s\nКатегория: %s", kind, amountWithCurrency(state.Amount, state.Currency), category)
+	if date, err := time.Parse(dateLayout, state.Date); err == nil {
+		text += "\nДата: " + date.Format(displayDateLayout)
+	}
+	if state.AccountID != 0 {
+		if a, err := h.service.GetAccount(chatID, state.AccountID); err == nil {
+			text += "\nСчет: " + a.Name
+		}
+	}
+	if state.Merchant != "" {
+		text += "\nПродавец: " + state.Merchant
+	}
+	if len(state.Tags) > 0 {
+		text += "\nТеги: " + tagsText(state.Tags)
+	}
+	if state.Note != "" {
+		text += "\nЗаметка: " + state.Note
+	}
+	return text
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"finuchet-bot/internal/models"
+	"testing"
+)
+
+func TestQuickEntryMatches(t *testing.T) {
+	pending := &models.ChatState{State: StateQuickConfirm, EntryID: 17}
+
+	tests := []struct {
+		name  string
+		state *models.ChatState
+		args  string
+		want  bool
+	}{
+		{"same entry", pending, "17", true},
+		{"same entry with account", pending, "17:3", true},
+		{"button of an older entry", pending, "12", false},
+		{"button without entry", pending, "", false},
+		{"entry already confirmed", &models.ChatState{EntryID: 17}, "17", false},
+		{"other dialog in progress", &models.ChatState{State: StateExpenseCategory, EntryID: 17}, "17", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quickEntryMatches(tt.state, tt.args); got != tt.want {
+				t.Errorf("quickEntryMatches(%q) = %v, want %v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"errors"
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/quickentry"
+	"finuchet-bot/internal/recurring"
+	"finuchet-bot/internal/services"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const recurringUsage = "Формат: /recurring ежемесячно 30000 аренда [5-го] [с 05.11] [до 31.12.2026]\n" +
+	"Периодичность: ежедневно, еженедельно, ежемесячно, ежегодно. Сумма и категория — как при быстром вводе."
+
+// Суффиксы дня месяца: "5-го", "5го", "5числа"
+var dayOfMonthSuffixes = []string{"-го", "го", "-числа", "числа"}
+
+var weekdayNames = [...]string{"вс", "пн", "вт", "ср", "чт", "пт", "сб"}
+
+// Создание операций по наступившим правилам и уведомление владельцев.
+// Планировщик вызывает его сразу после запуска, поэтому пропущенные за время
+// простоя операции создаются без ожидания первого интервала.
+func (h *BotHandler) processRecurring(now time.Time) {
+	runs, err := h.service.ProcessRecurring(now)
+	if err != nil {
+		log.Printf("Ошибка создания повторяющихся операций: %v", err)
+	}
+	for _, run := range runs {
+		var b strings.Builder
+		b.WriteString("🔁 Добавлено по расписанию:\n")
+		for _, t := range run.Transactions {
+			sign := "-"
+			if t.Type == "income" {
+				sign = "+"
+			}
+			fmt.Fprintf(&b, "%s %s%s %s", t.Date.Format(displayDateLayout), sign, amountWithCurrency(t.Amount, t.Currency), recurringName(run.Rule))
+			if t.Note != "" {
+				b.WriteString(" · " + t.Note)
+			}
+			b.WriteString("\n")
+		}
+		h.bot.Send(tgbotapi.NewMessage(run.Rule.ChatID, b.String()))
+		for _, t := range run.Transactions {
+			h.notifyBudget(run.Rule.ChatID, t)
+		}
+	}
+}
+
+// Команда /recurring: без аргументов - список правил, иначе новое правило
+func (h *BotHandler) handleRecurringCommand(chatID int64, args string) {
+	if strings.TrimSpace(args) == "" {
+		h.showRecurringRules(chatID, 0)
+		return
+	}
+
+	rule, problem := h.parseRecurringRule(chatID, args)
+	if problem != "" {
+		h.bot.Send(tgbotapi.NewMessage(chatID, problem+"\n"+recurringUsage))
+		return
+	}
+
+	err := h.service.CreateRecurringRule(chatID, rule)
+	switch {
+	case errors.Is(err, services.ErrRecurringDates):
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Дата окончания раньше первой операции."))
+		return
+	case errors.Is(err, services.ErrNoteTooLong):
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Заметка слишком длинная, сократите ее до 255 символов."))
+		return
+	case err != nil:
+		h.sendError(chatID, "Ошибка при сохранении правила.", err)
+		return
+	}
+
+	// Операции с уже наступившими датами создаются сразу
+	h.processRecurring(h.clock.Now())
+	h.showRecurringCard(chatID, 0, rule.ID)
+}
+
+// Разбор правила: периодичность, затем строка быстрого ввода с необязательными
+// "N-го" (день месяца), "с <дата>" (начало) и "до <дата>" (окончание).
+// problem - сообщение пользователю, если правило разобрать не удалось.
+func (h *BotHandler) parseRecurringRule(chatID int64, args string) (rule *models.RecurringRule, problem string) {
+	fields := strings.Fields(args)
+	frequency, ok := recurring.ParseFrequency(fields[0])
+	if !ok {
+		return nil, "Не указана периодичность."
+	}
+
+	now := h.now(chatID)
+	rule = &models.RecurringRule{Frequency: frequency}
+	var rest []string
+	for i := 1; i < len(fields); i++ {
+		word := strings.ToLower(fields[i])
+		if (word == "с" || word == "до") && i+1 < len(fields) {
+			date, ok := upcomingDate(fields[i+1], now)
+			if !ok {
+				return nil, fmt.Sprintf("Не удалось распознать дату %q.", fields[i+1])
+			}
+			if word == "с" {
+				rule.StartDate = date
+			} else {
+				rule.EndDate = date
+			}
+			i++
+			continue
+		}
+		if day, ok := parseDayOfMonth(word); ok && (frequency == recurring.Monthly || frequency == recurring.Yearly) {
+			rule.DayOfMonth = day
+			continue
+		}
+		rest = append(rest, fields[i])
+	}
+
+	categories, err := h.service.GetCategories(chatID, "", false)
+	if err != nil {
+		log.Printf("Ошибка получения категорий: %v", err)
+		return nil, "Ошибка при получении категорий."
+	}
+	entry, err := quickentry.Parse(strings.Join(rest, " "), categories, now)
+	if err != nil {
+		return nil, amountErrorText(err)
+	}
+	if entry.Category == nil {
+		return nil, "Категория не распознана."
+	}
+
+	rule.Type = entry.Type
+	rule.Amount = entry.Amount
+	rule.Currency = entry.Currency
+	rule.CategoryID = entry.Category.ID
+	rule.Note = entry.Note
+	if rule.StartDate.IsZero() {
+		rule.StartDate = entry.Date // Дата из строки быстрого ввода или сегодня
+	}
+	return rule, ""
+}
+
+// Дата начала или окончания: без года означает ближайшую такую дату, а не прошедшую
+func upcomingDate(word string, now time.Time) (time.Time, bool) {
+	date, ok := dates.Parse(word, now)
+	if ok && strings.Count(word, ".") == 1 && date.Year() < now.Year() {
+		date = date.AddDate(1, 0, 0)
+	}
+	return date, ok
+}
+
+func parseDayOfMonth(word string) (int, bool) {
+	for _, suffix := range dayOfMonthSuffixes {
+		if number, ok := strings.CutSuffix(word, suffix); ok {
+			day, err := strconv.Atoi(number)
+			return day, err == nil && day >= 1 && day <= 31
+		}
+	}
+	return 0, false
+}
+
+// Кнопки правил: "rec:<действие>[:<id правила>]"
+func (h *BotHandler) handleRecurringAction(chatID int64, messageID int, args string) {
+	action, param, _ := strings.Cut(args, ":")
+	if action == "list" {
+		h.showRecurringRules(chatID, messageID)
+		return
+	}
+
+	ruleID, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return
+	}
+
+	switch action {
+	case "open":
+	case "pause", "resume":
+		err = h.service.SetRecurringPaused(chatID, ruleID, action == "pause")
+	case "del":
+		if err := h.service.DeleteRecurringRule(chatID, ruleID); err != nil {
+			h.sendError(chatID, "Ошибка при удалении правила.", err)
+			return
+		}
+		h.showRecurringRules(chatID, messageID)
+		return
+	default:
+		return
+	}
+	if err != nil {
+		h.sendError(chatID, "Ошибка при изменении правила.", err)
+		return
+	}
+	h.showRecurringCard(chatID, messageID, ruleID)
+}
+
+// Список правил повторяющихся операций
+func (h *BotHandler) showRecurringRules(chatID int64, messageID int) {
+	rules, err := h.service.GetRecurringRules(chatID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении повторяющихся операций.", err)
+		return
+	}
+
+	text := "Повторяющиеся операции:"
+	if len(rules) == 0 {
+		text = "Повторяющихся операций нет.\n" + recurringUsage
+	}
+	// Пустой, а не nil список кнопок убирает клавиатуру у редактируемого сообщения
+	rows := [][]tgbotapi.InlineKeyboardButton{}
+	for _, rule := range rules {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(recurringLine(rule), fmt.Sprintf("rec:open:%d", rule.ID)),
+		))
+	}
+	h.sendOrEdit(chatID, messageID, text, tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows})
+}
+
+// Карточка правила с кнопками управления
+func (h *BotHandler) showRecurringCard(chatID int64, messageID int, ruleID int64) {
+	rule, err := h.service.GetRecurringRule(chatID, ruleID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении правила.", err)
+		return
+	}
+
+	kind := "Расход"
+	if rule.Type == "income" {
+		kind = "Доход"
+	}
+	text := fmt.Sprintf("%s: %s\nКатегория: %s\nПовтор: %s\nНачало: %s\n", kind, amountWithCurrency(rule.Amount, rule.Currency),
+		recurringName(rule), frequencyTitle(rule), rule.StartDate.Format(displayDateLayout))
+	if !rule.EndDate.IsZero() {
+		text += "Окончание: " + rule.EndDate.Format(displayDateLayout) + "\n"
+	}
+	if rule.Note != "" {
+		text += "Заметка: " + rule.Note + "\n"
+	}
+	switch {
+	case rule.NextDate.IsZero():
+		text += "Расписание закончилось."
+	case rule.Paused:
+		text += "⏸ Приостановлено."
+	default:
+		text += "Следующая операция: " + rule.NextDate.Format(displayDateLayout)
+	}
+
+	toggle := tgbotapi.NewInlineKeyboardButtonData("⏸ Пауза", fmt.Sprintf("rec:pause:%d", ruleID))
+	if rule.Paused {
+		toggle = tgbotapi.NewInlineKeyboardButtonData("▶️ Возобновить", fmt.Sprintf("rec:resume:%d", ruleID))
+	}
+	markup := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			toggle,
+			tgbotapi.NewInlineKeyboardButtonData("🗑 Удалить", fmt.Sprintf("rec:del:%d", ruleID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ К списку", "rec:list"),
+		),
+	)
+	h.sendOrEdit(chatID, messageID, text, markup)
+}
+
+// Строка правила: "⏸ Аренда 🏠: 30000.00 RUB, каждый месяц, 5-го"
+func recurringLine(rule *models.RecurringRule) string {
+	line := fmt.Sprintf("%s: %s, %s", recurringName(rule), amountWithCurrency(rule.Amount, rule.Currency), frequencyTitle(rule))
+	switch {
+	case rule.Paused:
+		line = "⏸ " + line
+	case rule.NextDate.IsZero():
+		line = "✔️ " + line
+	}
+	return line
+}
+
+// Периодичность словами: "каждую неделю, пн", "каждый месяц, 5-го"
+func frequencyTitle(rule *models.RecurringRule) string {
+	day := rule.DayOfMonth
+	if day == 0 {
+		day = rule.StartDate.Day()
+	}
+	switch rule.Frequency {
+	case recurring.Daily:
+		return "каждый день"
+	case recurring.Weekly:
+		return "каждую неделю, " + weekdayNames[rule.StartDate.Weekday()]
+	case recurring.Monthly:
+		return fmt.Sprintf("каждый месяц, %d-го", day)
+	case recurring.Yearly:
+		return fmt.Sprintf("каждый год, %02d.%02d", day, rule.StartDate.Month())
+	}
+	return rule.Frequency
+}
+
+func recurringName(rule *models.RecurringRule) string {
+	if rule.Category == "" {
+		return "Без категории"
+	}
+	return models.Category{Name: rule.Category, Emoji: rule.Emoji}.Label()
+}
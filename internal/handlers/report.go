@@ -0,0 +1,400 @@
+package handlers
+
+import (
+	"errors"
+	"finuchet-bot/internal/charts"
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/quickentry"
+	"finuchet-bot/internal/services"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Периоды отчета в порядке отображения
+var reportPeriods = []struct{ name, label string }{
+	{dates.PeriodToday, "Сегодня"},
+	{dates.PeriodWeek, "Неделя"},
+	{dates.PeriodMonth, "Месяц"},
+	{dates.PeriodLastMonth, "Прошлый месяц"},
+	{dates.PeriodYear, "С начала года"},
+	{dates.PeriodAll, "Все время"},
+}
+
+// Сколько категорий каждого типа показывать в отчете отдельными строками
+const reportCategoriesLimit = 15
+
+const reportUsage = "Укажите период: /report, /report week или /report 2026-01-01 2026-03-31. " +
+	"Отбор по тегу или продавцу: /report #отпуск, /report @Магнит month"
+
+// Максимальная длина данных кнопки в Telegram
+const callbackDataLimit = 64
+
+// Команда /report: без аргументов - текущий месяц, иначе название периода или даты начала и конца включительно.
+// Теги "#отпуск" и продавец "@Магнит" отбирают операции; с отбором без периода отчет строится за все время.
+func (h *BotHandler) handleReportCommand(chatID int64, args string) {
+	filter, rest := parseReportFilter(strings.Fields(args))
+	if !filter.IsEmpty() && len(rest) == 0 {
+		rest = []string{dates.PeriodAll}
+	}
+	period, ok := parseReportPeriod(strings.Join(rest, " "), h.now(chatID))
+	if !ok {
+		h.bot.Send(tgbotapi.NewMessage(chatID, reportUsage))
+		return
+	}
+	h.showReport(chatID, 0, period, filter)
+}
+
+// Кнопки отчета: "rep:<период>" или "rep:<период>|#<тег>|@<продавец>"
+func (h *BotHandler) handleReportAction(chatID int64, messageID int, args string) {
+	periodData, filterData, _ := strings.Cut(args, "|")
+	period, ok := decodePeriod(periodData, h.now(chatID))
+	if !ok {
+		return
+	}
+	filter, _ := parseReportFilter(strings.Split(filterData, "|"))
+	h.showReport(chatID, messageID, period, filter)
+}
+
+// Кнопки диаграмм: "chart:<вид>:<период>"; диаграмма отправляется отдельным сообщением
+func (h *BotHandler) handleChartAction(chatID int64, args string) {
+	kind, periodArgs, _ := strings.Cut(args, ":")
+	period, ok := decodePeriod(periodArgs, h.now(chatID))
+	if !ok || !charts.Supported(kind) {
+		return
+	}
+
+	h.bot.Send(tgbotapi.NewChatAction(chatID, tgbotapi.ChatUploadPhoto))
+	image, err := h.service.Chart(chatID, kind, period)
+	if errors.Is(err, charts.ErrNoData) {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Нет операций для диаграммы за этот период."))
+		return
+	}
+	if err != nil {
+		h.sendError(chatID, "Ошибка при построении диаграммы.", err)
+		return
+	}
+
+	photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileBytes{Name: kind + ".png", Bytes: image})
+	if kind != charts.KindMonthly {
+		photo.Caption = "Период: " + periodTitle(period)
+	}
+	if _, err := h.bot.Send(photo); err != nil {
+		h.sendError(chatID, "Ошибка при отправке диаграммы.", err)
+	}
+}
+
+// Отчет за период с кнопками перехода к соседним периодам
+func (h *BotHandler) showReport(chatID int64, messageID int, period dates.Period, filter models.TransactionFilter) {
+	report, err := h.service.GetFilteredReport(chatID, period, filter)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении отчета.", err)
+		return
+	}
+	h.sendOrEditFormatted(chatID, messageID, reportText(report), reportKeyboard(period, h.now(chatID), filter))
+}
+
+// Текст отчета: итоги, разбивка по категориям и крупнейшие расходы
+func reportText(report *services.Report) string {
+	var b strings.Builder
+	title := "Отчет "
+	if report.Filter.Tag != "" {
+		title += "#" + report.Filter.Tag + " "
+	}
+	if report.Filter.Merchant != "" {
+		title += "@" + report.Filter.Merchant + " "
+	}
+	b.WriteString(bold(title+periodTitle(report.Period)) + "\n")
+	if report.Currencies != nil {
+		b.WriteString(italic("Суммы пересчитаны в "+report.Currency) + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(escape(fmt.Sprintf("Доходы: %s (%d)\n", formatMoney(report.Income), report.IncomeCount)))
+	b.WriteString(escape(fmt.Sprintf("Расходы: %s (%d)\n", formatMoney(report.Expense), report.ExpenseCount)))
+	b.WriteString(escape("Баланс: "+formatMoney(report.Balance())) + "\n")
+	if report.AverageDailyExpense > 0 {
+		b.WriteString(escape("Средний расход в день: "+formatMoney(report.AverageDailyExpense)) + "\n")
+	}
+
+	writeCategoryShares(&b, "Расходы по категориям", report.CategoriesOf("expense"), report.HasPrevious)
+	writeCategoryShares(&b, "Доходы по категориям", report.CategoriesOf("income"), report.HasPrevious)
+
+	if len(report.Members) > 0 {
+		b.WriteString("\n" + bold("По участникам") + "\n")
+		for _, m := range report.Members {
+			kind := "расходы"
+			if m.Type == "income" {
+				kind = "доходы"
+			}
+			b.WriteString(escape(fmt.Sprintf("👤 %s, %s: %s (%d)", memberTotalName(m), kind, formatMoney(m.Amount), m.Count)) + "\n")
+		}
+	}
+
+	if len(report.Budgets) > 0 {
+		b.WriteString("\n" + bold("Бюджеты") + "\n")
+		for _, budget := range report.Budgets {
+			line := budgetLine(budget)
+			if remaining := budget.Remaining(); remaining >= 0 {
+				line += " · осталось " + formatMoney(remaining)
+			} else {
+				line += " · перерасход " + formatMoney(-remaining)
+			}
+			b.WriteString(escape(line) + "\n")
+		}
+	}
+
+	if len(report.TopExpenses) > 0 {
+		b.WriteString("\n" + bold("Крупнейшие расходы") + "\n")
+		for i, t := range report.TopExpenses {
+			amount := formatMoney(t.Amount)
+			if t.Currency != report.Currency {
+				amount += " " + t.Currency
+			}
+			line := fmt.Sprintf("%d. %s — %s · %s", i+1, t.Date.Format("02.01"), amount, categoryName(t))
+			if t.Note != "" {
+				line += " · " + t.Note
+			}
+			b.WriteString(escape(line) + "\n")
+		}
+	}
+
+	if len(report.Accounts) > 0 {
+		title := "Остатки на счетах"
+		if !report.AccountsAt.IsZero() {
+			title += " на " + report.AccountsAt.AddDate(0, 0, -1).Format(displayDateLayout)
+		}
+		b.WriteString("\n" + bold(title) + "\n")
+		for _, a := range report.Accounts {
+			b.WriteString(escape(accountLine(a)) + "\n")
+		}
+	}
+
+	if len(report.Currencies) > 0 {
+		b.WriteString("\n" + bold("По валютам") + "\n")
+		for _, c := range report.Currencies {
+			kind := "расходы"
+			if c.Type == "income" {
+				kind = "доходы"
+			}
+			b.WriteString(escape(fmt.Sprintf("%s, %s: %s (%d)", c.Currency, kind, formatMoney(c.Amount), c.Count)) + "\n")
+		}
+	}
+	if len(report.MissingRates) > 0 {
+		b.WriteString("\n" + escape(fmt.Sprintf("⚠️ Нет курса для %s: эти операции не вошли в суммы. Задайте курс командой /rate %s 90.5",
+			strings.Join(report.MissingRates, ", "), report.MissingRates[0])) + "\n")
+	}
+	return b.String()
+}
+
+// Строки категорий: сумма, доля, количество операций и изменение к прошлому периоду.
+// Небольшие категории сверх reportCategoriesLimit объединяются, чтобы уложиться в размер сообщения.
+func writeCategoryShares(b *strings.Builder, title string, shares []*services.CategoryShare, withChange bool) {
+	if len(shares) == 0 {
+		return
+	}
+	b.WriteString("\n" + bold(title) + "\n")
+
+	var rest services.CategoryShare
+	for i, c := range shares {
+		if i >= reportCategoriesLimit {
+			rest.Amount += c.Amount
+			rest.Share += c.Share
+			rest.Count += c.Count
+			continue
+		}
+		name := c.Category
+		if name == "" {
+			name = "Без категории"
+		} else if c.Emoji != "" {
+			name += " " + c.Emoji
+		}
+		line := fmt.Sprintf("%s — %s · %.1f%% · %d оп.", name, formatMoney(c.Amount), c.Share, c.Count)
+		if withChange {
+			line += " · " + formatChange(c)
+		}
+		b.WriteString(escape(line) + "\n")
+	}
+	if rest.Count > 0 {
+		b.WriteString(escape(fmt.Sprintf("Прочие (%d) — %s · %.1f%% · %d оп.",
+			len(shares)-reportCategoriesLimit, formatMoney(rest.Amount), rest.Share, rest.Count)) + "\n")
+	}
+}
+
+// Изменение к прошлому периоду: "↑12%", "↓5%", "новая"
+func formatChange(c *services.CategoryShare) string {
+	change, ok := c.Change()
+	switch {
+	case !ok:
+		return "новая"
+	case change >= 0.5:
+		return fmt.Sprintf("↑%.0f%%", change)
+	case change <= -0.5:
+		return fmt.Sprintf("↓%.0f%%", -change)
+	}
+	return "без изменений"
+}
+
+// Кнопки отчета. Отбор сохраняется в данных кнопок; диаграммы строятся только без отбора,
+// а кнопки, данные которых с отбором не помещаются в лимит Telegram, не показываются.
+func reportKeyboard(period dates.Period, now time.Time, filter models.TransactionFilter) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	filterData := encodeReportFilter(filter)
+	appendButton := func(row []tgbotapi.InlineKeyboardButton, title, data string) []tgbotapi.InlineKeyboardButton {
+		if len(data+filterData) > callbackDataLimit {
+			return row
+		}
+		return append(row, tgbotapi.NewInlineKeyboardButtonData(title, data+filterData))
+	}
+
+	if !period.IsAll() {
+		var nav []tgbotapi.InlineKeyboardButton
+		prev := period.Shift(-1)
+		nav = appendButton(nav, "◀️ "+periodShortTitle(prev), reportPeriodData(prev))
+		// Будущие периоды не показываем
+		if next := period.Shift(1); !next.From.After(dates.Day(now)) {
+			nav = appendButton(nav, periodShortTitle(next)+" ▶️", reportPeriodData(next))
+		}
+		if len(nav) > 0 {
+			rows = append(rows, nav)
+		}
+	}
+
+	if filter.IsEmpty() {
+		periodData := encodePeriod(period)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🥧 Категории", "chart:"+charts.KindCategories+":"+periodData),
+			tgbotapi.NewInlineKeyboardButtonData("📊 Месяцы", "chart:"+charts.KindMonthly+":"+periodData),
+			tgbotapi.NewInlineKeyboardButtonData("📈 Баланс", "chart:"+charts.KindBalance+":"+periodData),
+		))
+	}
+
+	var row []tgbotapi.InlineKeyboardButton
+	for _, p := range reportPeriods {
+		row = appendButton(row, p.label, "rep:"+p.name)
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func reportPeriodData(p dates.Period) string {
+	return "rep:" + encodePeriod(p)
+}
+
+// Отбор в данных кнопки: "|#<тег>|@<продавец>"; пробелы в продавце заменяются на "_"
+func encodeReportFilter(filter models.TransactionFilter) string {
+	var data string
+	if filter.Tag != "" {
+		data += "|#" + filter.Tag
+	}
+	if filter.Merchant != "" {
+		data += "|@" + strings.ReplaceAll(filter.Merchant, " ", "_")
+	}
+	return data
+}
+
+// Отбор из слов "#тег" и "@продавец"; остальные слова возвращаются в rest
+func parseReportFilter(fields []string) (filter models.TransactionFilter, rest []string) {
+	for _, field := range fields {
+		if tag, ok := quickentry.Tag(field); ok {
+			filter.Tag = tag
+		} else if merchant, ok := quickentry.Merchant(field); ok {
+			filter.Merchant = merchant
+		} else if field != "" {
+			rest = append(rest, field)
+		}
+	}
+	return filter, rest
+}
+
+// Период в данных кнопки: "all" или "<с>:<по>", где <по> не входит в период
+func encodePeriod(p dates.Period) string {
+	if p.IsAll() {
+		return dates.PeriodAll
+	}
+	return p.From.Format(dateLayout) + ":" + p.To.Format(dateLayout)
+}
+
+// Период из данных кнопки: название периода или "<с>:<по>"
+func decodePeriod(data string, now time.Time) (dates.Period, bool) {
+	from, to, isRange := strings.Cut(data, ":")
+	if !isRange {
+		return dates.PeriodByName(from, now)
+	}
+
+	var period dates.Period
+	var err error
+	if period.From, err = time.ParseInLocation(dateLayout, from, now.Location()); err != nil {
+		return dates.Period{}, false
+	}
+	if period.To, err = time.ParseInLocation(dateLayout, to, now.Location()); err != nil {
+		return dates.Period{}, false
+	}
+	return period, true
+}
+
+// Период из аргументов команды /report
+func parseReportPeriod(args string, now time.Time) (dates.Period, bool) {
+	fields := strings.Fields(args)
+	switch len(fields) {
+	case 0:
+		return dates.PeriodByName(dates.PeriodMonth, now)
+	case 1:
+		if period, ok := dates.PeriodByName(strings.ToLower(fields[0]), now); ok {
+			return period, true
+		}
+		day, ok := dates.Parse(fields[0], now)
+		if !ok {
+			return dates.Period{}, false
+		}
+		return dates.Period{From: day, To: day.AddDate(0, 0, 1)}, true
+	case 2:
+		from, ok := dates.Parse(fields[0], now)
+		if !ok {
+			return dates.Period{}, false
+		}
+		to, ok := dates.Parse(fields[1], now)
+		if !ok || to.Before(from) {
+			return dates.Period{}, false
+		}
+		return dates.Period{From: from, To: to.AddDate(0, 0, 1)}, true
+	}
+	return dates.Period{}, false
+}
+
+// Заголовок периода: "за 05.03.2026", "с 01.03.2026 по 31.03.2026"
+func periodTitle(p dates.Period) string {
+	switch {
+	case p.IsAll():
+		return "за все время"
+	case p.From.IsZero():
+		return "по " + p.To.AddDate(0, 0, -1).Format(displayDateLayout)
+	case p.To.IsZero():
+		return "с " + p.From.Format(displayDateLayout)
+	case p.Days() == 1:
+		return "за " + p.From.Format(displayDateLayout)
+	}
+	return "с " + p.From.Format(displayDateLayout) + " по " + p.To.AddDate(0, 0, -1).Format(displayDateLayout)
+}
+
+// Краткая подпись периода для кнопок: "05.03", "03.2026", "05.03–11.03", "01.01.26–31.03.26"
+func periodShortTitle(p dates.Period) string {
+	last := p.To.AddDate(0, 0, -1)
+	switch {
+	case p.Days() == 1:
+		return p.From.Format("02.01")
+	case p.From.Equal(dates.MonthStart(p.From)) && p.To.Equal(p.From.AddDate(0, 1, 0)):
+		return p.From.Format("01.2006")
+	case p.From.Year() != last.Year() || p.Days() > 31:
+		return p.From.Format("02.01.06") + "–" + last.Format("02.01.06")
+	}
+	return p.From.Format("02.01") + "–" + last.Format("02.01")
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"finuchet-bot/internal/services"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const timezoneUsage = "Выберите часовой пояс кнопкой или отправьте /settings Asia/Vladivostok (имя из базы IANA) либо /settings UTC+10."
+
+// Часовые пояса России для выбора кнопками
+var timezoneChoices = []struct {
+	Title string
+	Name  string
+}{
+	{"Калининград +2", "Europe/Kaliningrad"},
+	{"Москва +3", "Europe/Moscow"},
+	{"Самара +4", "Europe/Samara"},
+	{"Екатеринбург +5", "Asia/Yekaterinburg"},
+	{"Омск +6", "Asia/Omsk"},
+	{"Новосибирск +7", "Asia/Novosibirsk"},
+	{"Иркутск +8", "Asia/Irkutsk"},
+	{"Якутск +9", "Asia/Yakutsk"},
+	{"Владивосток +10", "Asia/Vladivostok"},
+	{"Магадан +11", "Asia/Magadan"},
+	{"Камчатка +12", "Asia/Kamchatka"},
+}
+
+// Текущее время в часовом поясе пользователя. Если пояс получить не удалось,
+// используется время сервера, чтобы не прерывать обработку сообщения.
+func (h *BotHandler) now(chatID int64) time.Time {
+	now, err := h.service.Now(chatID)
+	if err != nil {
+		if !errors.Is(err, services.ErrUserNotFound) {
+			log.Printf("Ошибка получения часового пояса: %v", err)
+		}
+		return time.Now()
+	}
+	return now
+}
+
+// Команда /settings: без аргументов - текущие настройки, иначе смена часового пояса
+func (h *BotHandler) handleSettingsCommand(chatID int64, args string) {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		h.sendSettings(chatID, 0)
+		return
+	}
+	h.setTimezone(chatID, 0, parseTimezone(args))
+}
+
+// Кнопки настроек: "tz:<имя пояса>"
+func (h *BotHandler) handleTimezoneAction(chatID int64, messageID int, name string) {
+	h.setTimezone(chatID, messageID, name)
+}
+
+func (h *BotHandler) setTimezone(chatID int64, messageID int, name string) {
+	name, err := h.service.SetTimezone(chatID, name)
+	switch {
+	case errors.Is(err, services.ErrUnknownTimezone):
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Неизвестный часовой пояс. "+timezoneUsage))
+		return
+	case err != nil:
+		h.sendError(chatID, "Ошибка при смене часового пояса.", err)
+		return
+	}
+	text := fmt.Sprintf("Часовой пояс: %s, сейчас %s.", name, h.now(chatID).Format("02.01 15:04"))
+	// Пустой, а не nil список кнопок убирает клавиатуру выбора
+	h.sendOrEdit(chatID, messageID, text, tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}})
+}
+
+// Текущие настройки с кнопками выбора часового пояса
+func (h *BotHandler) sendSettings(chatID int64, messageID int) {
+	name, err := h.service.GetTimezone(chatID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении настроек.", err)
+		return
+	}
+	code, err := h.service.GetBaseCurrency(chatID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении настроек.", err)
+		return
+	}
+
+	text := fmt.Sprintf("Часовой пояс: %s, сейчас %s.\nБазовая валюта: %s (сменить: /currency).\n\n%s",
+		name, h.now(chatID).Format("02.01 15:04"), code, timezoneUsage)
+	h.sendOrEdit(chatID, messageID, text, timezoneKeyboard())
+}
+
+// Вопрос о часовом поясе новому пользователю
+func (h *BotHandler) askTimezone(chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, "В каком часовом поясе вы живете? От него зависит, какой день считается сегодняшним. "+
+		"Сейчас выбрана Москва, изменить можно позже в /settings.")
+	msg.ReplyMarkup = timezoneKeyboard()
+	h.bot.Send(msg)
+}
+
+func timezoneKeyboard() tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, choice := range timezoneChoices {
+		button := tgbotapi.NewInlineKeyboardButtonData(choice.Title, "tz:"+choice.Name)
+		if i%2 == 0 {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+		} else {
+			rows[len(rows)-1] = append(rows[len(rows)-1], button)
+		}
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// Имя часового пояса из ввода пользователя: имя IANA как есть, смещение
+// "UTC+10", "GMT-5", "+3" - как пояс Etc/GMT с обратным по соглашению IANA знаком
+func parseTimezone(s string) string {
+	offset := strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	offset = strings.TrimPrefix(strings.TrimPrefix(offset, "UTC"), "GMT")
+	if offset == "" {
+		return "UTC"
+	}
+	if offset[0] != '+' && offset[0] != '-' {
+		return s
+	}
+	hours, err := strconv.Atoi(offset[1:])
+	if err != nil || hours > 14 {
+		return s
+	}
+	if hours == 0 {
+		return "UTC"
+	}
+	sign := "-"
+	if offset[0] == '-' {
+		sign = "+"
+	}
+	return fmt.Sprintf("Etc/GMT%s%d", sign, hours)
+}
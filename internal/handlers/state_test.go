@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/repository"
+	"testing"
+	"time"
+)
+
+func TestGroupDialogsArePerMember(t *testing.T) {
+	const group, private = -100500, 42
+
+	h := &BotHandler{states: repository.NewMemoryStateStore(time.Hour)}
+	anna, boris := *h, *h
+	anna.sender, boris.sender = 1, 2
+
+	anna.setState(group, &models.ChatState{State: StateQuickConfirm, EntryID: 7, MemberID: 1})
+	if got := boris.getState(group); got.State != StateNone {
+		t.Fatalf("another member sees dialog %q in the group", got.State)
+	}
+
+	boris.setState(group, &models.ChatState{State: StateWaitingExpense})
+	boris.resetState(group)
+	if got := anna.getState(group); got.State != StateQuickConfirm || got.EntryID != 7 {
+		t.Fatalf("dialog of the first member changed to %+v", got)
+	}
+
+	// В личном чате диалог один на чат
+	anna.setState(private, &models.ChatState{State: StateWaitingIncome})
+	if got := h.getState(private); got.State != StateWaitingIncome {
+		t.Errorf("private chat dialog = %q, want %q", got.State, StateWaitingIncome)
+	}
+}
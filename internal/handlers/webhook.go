@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"finuchet-bot/config"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+	maxUpdateSize     = 1 << 20 // Обновления Telegram значительно меньше 1 МБ
+)
+
+// webhookHandler принимает обновления от Telegram по HTTP и передает их
+// в тот же конвейер обработки, что и long polling.
+// Для локальной проверки достаточно отправить POST с JSON сохраненного обновления:
+//
+//	curl -X POST -H "X-Telegram-Bot-Api-Secret-Token: $WEBHOOK_SECRET" \
+//	     -d @update.json http://localhost:8080/telegram/webhook
+type webhookHandler struct {
+	secretToken string
+	dispatch    func(tgbotapi.Update) bool
+}
+
+func (wh *webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Без секрета любой мог бы прислать обновление с чужим from.id; пустой секрет
+	// отсекается при проверке конфигурации, а здесь на всякий случай отклоняет все запросы
+	got := r.Header.Get(secretTokenHeader)
+	if wh.secretToken == "" || subtle.ConstantTimeCompare([]byte(got), []byte(wh.secretToken)) != 1 {
+		http.Error(w, "invalid secret token", http.StatusForbidden)
+		return
+	}
+
+	var update tgbotapi.Update
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxUpdateSize)).Decode(&update); err != nil {
+		http.Error(w, "invalid update: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Ответ отправляется после постановки в очередь: если очередь заполнена,
+	// Telegram подождет и не станет присылать следующие обновления
+	if !wh.dispatch(update) {
+		// Бот останавливается — Telegram повторит доставку позже
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// newWebhookServer создает HTTP-сервер для приема обновлений
+func newWebhookServer(cfg config.WebhookConfig, dispatch func(tgbotapi.Update) bool) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(cfg.Path, &webhookHandler{secretToken: cfg.SecretToken, dispatch: dispatch})
+
+	return &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// serveWebhook регистрирует webhook в Telegram (если задан публичный адрес) и запускает HTTP-сервер.
+// При отмене ctx сервер перестает принимать запросы и дожидается уже начатых.
+func (h *BotHandler) serveWebhook(ctx context.Context, d *dispatcher) error {
+	cfg := h.webhook
+	if cfg.URL != "" {
+		if err := h.setWebhook(cfg); err != nil {
+			return err
+		}
+	}
+
+	server := newWebhookServer(cfg, d.Dispatch)
+	log.Printf("Принимаем обновления через webhook на %s%s", cfg.Listen, cfg.Path)
+
+	errCh := make(chan error, 1)
+	go func() {
+		if cfg.CertFile != "" && cfg.KeyFile != "" {
+			errCh <- server.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
+		} else {
+			// TLS завершается на reverse proxy
+			errCh <- server.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), h.shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+// WebhookConfig из библиотеки не поддерживает secret_token, поэтому вызываем метод API напрямую
+func (h *BotHandler) setWebhook(cfg config.WebhookConfig) error {
+	params := tgbotapi.Params{"url": cfg.URL}
+	params.AddNonEmpty("secret_token", cfg.SecretToken)
+	_, err := h.bot.MakeRequest("setWebhook", params)
+	return err
+}
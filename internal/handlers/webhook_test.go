@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"finuchet-bot/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const testSecret = "s3cret"
+
+const validUpdate = `{"update_id": 42, "message": {"message_id": 7, "from": {"id": 1001, "first_name": "Аня"},
+	"chat": {"id": -500, "type": "group"}, "date": 1767225600, "text": "500 кафе"}}`
+
+func TestWebhookHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		secret     string // Секрет в конфигурации
+		method     string
+		header     string // Секрет в запросе
+		body       string
+		accept     bool // Что вернет dispatch
+		wantStatus int
+		wantUpdate bool
+	}{
+		{"valid update", testSecret, http.MethodPost, testSecret, validUpdate, true, http.StatusOK, true},
+		{"wrong secret", testSecret, http.MethodPost, "guess", validUpdate, true, http.StatusForbidden, false},
+		{"missing secret", testSecret, http.MethodPost, "", validUpdate, true, http.StatusForbidden, false},
+		{"no secret configured", "", http.MethodPost, "", validUpdate, true, http.StatusForbidden, false},
+		{"wrong method", testSecret, http.MethodGet, testSecret, "", true, http.StatusMethodNotAllowed, false},
+		{"bad json", testSecret, http.MethodPost, testSecret, `{"update_id":`, true, http.StatusBadRequest, false},
+		{"shutting down", testSecret, http.MethodPost, testSecret, validUpdate, false, http.StatusServiceUnavailable, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []tgbotapi.Update
+			wh := &webhookHandler{secretToken: tt.secret, dispatch: func(u tgbotapi.Update) bool {
+				got = append(got, u)
+				return tt.accept
+			}}
+
+			req := httptest.NewRequest(tt.method, "/telegram/webhook", strings.NewReader(tt.body))
+			if tt.header != "" {
+				req.Header.Set(secretTokenHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			wh.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if !tt.wantUpdate {
+				if len(got) != 0 {
+					t.Errorf("dispatched %d updates, want none", len(got))
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("dispatched %d updates, want 1", len(got))
+			}
+			u := got[0]
+			if u.UpdateID != 42 || u.Message == nil || u.Message.From.ID != 1001 || u.Message.Chat.ID != -500 {
+				t.Errorf("dispatched update %+v does not match the request", u)
+			}
+		})
+	}
+}
+
+func TestWebhookServerReachesDispatcher(t *testing.T) {
+	handled := make(chan tgbotapi.Update, 1)
+	d := newDispatcher(2, 1, func(u tgbotapi.Update) { handled <- u })
+	defer closeWithin(t, d, 5*time.Second)
+
+	server := newWebhookServer(config.WebhookConfig{Path: "/telegram/webhook", SecretToken: testSecret}, d.Dispatch)
+	ts := httptest.NewServer(server.Handler)
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/telegram/webhook", strings.NewReader(validUpdate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(secretTokenHeader, testSecret)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	select {
+	case u := <-handled:
+		if u.UpdateID != 42 {
+			t.Errorf("handled update %d, want 42", u.UpdateID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("update did not reach the dispatcher")
+	}
+
+	// Другой путь сервер не обслуживает
+	resp, err = http.Post(ts.URL+"/other", "application/json", strings.NewReader(validUpdate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status for unknown path = %d, want 404", resp.StatusCode)
+	}
+}
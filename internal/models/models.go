@@ -1,19 +1,311 @@
 package models
 
 import (
+	"finuchet-bot/internal/money"
 	"time"
 )
 
+// Учет личного или группового чата
 type User struct {
-	ID     int64
-	ChatID int64
+	ID           int64
+	ChatID       int64
+	BaseCurrency string // Валюта, в которую пересчитываются отчеты
+	Timezone     string // Часовой пояс IANA, например "Europe/Moscow"
+	Title        string // Название группы; пусто для личного учета
+	LedgerID     int64  // Общий учет, который ведется из этого чата; 0 - собственный
+}
+
+// Участник учета: человек в Telegram и его роль
+type Member struct {
+	LedgerID    int64
+	ID          int64 // Telegram ID участника
+	Name        string
+	Role        string // "owner", "editor" или "viewer"
+	LedgerTitle string // Название учета, заполняется при выборе учетов участника
+	LedgerChat  int64  // Чат учета, заполняется при выборе учетов участника
+}
+
+// Приглашение в учет по ссылке
+type Invite struct {
+	Token     string
+	LedgerID  int64
+	Role      string // "editor" или "viewer"
+	CreatedBy int64  // Telegram ID пригласившего
+	ExpiresAt time.Time
 }
 
 type Transaction struct {
+	ID          int64
+	UserID      int64
+	Amount      money.Amount
+	Currency    string    // Код ISO 4217; если не задан, используется базовая валюта пользователя
+	CategoryID  int64     // 0, если категория удалена
+	Category    string    // Название категории, заполняется при чтении
+	AccountID   int64     // Счет операции; 0 при записи - основной счет пользователя
+	Account     string    // Название счета, заполняется при чтении
+	Type        string    // "income" или "expense"
+	Date        time.Time // Дата операции; если не задана, используется текущая
+	Note        string
+	Tags        []string // Теги без "#" в нижнем регистре
+	Merchant    string   // Продавец; пусто, если не указан
+	ImportHash  string   // Отпечаток строки банковской выписки; пусто для операций, введенных вручную
+	RecurringID int64    // Правило, по которому создана операция; 0 - введена вручную
+	MemberID    int64    // Telegram ID участника, записавшего операцию; 0 - не указан
+	Member      string   // Имя участника, заполняется при чтении
+	DebtID      int64    // Долг, в погашение которого засчитана операция; 0 - не погашение
+	Debt        string   // Контрагент долга, заполняется при чтении
+	CreatedAt   time.Time
+}
+
+// Счет пользователя: наличные, карта, вклад
+type Account struct {
+	ID             int64
+	UserID         int64
+	Name           string
+	Currency       string
+	OpeningBalance money.Amount // Остаток на момент начала учета
+	IsDefault      bool         // Основной счет: на него записываются операции без выбранного счета
+	Archived       bool         // Архивные счета не предлагаются при вводе и не показываются в остатках
+	Balance        money.Amount // Текущий остаток в валюте счета, заполняется при расчете остатков
+}
+
+// Перевод между счетами пользователя; не считается ни доходом, ни расходом
+type Transfer struct {
+	ID            int64
+	UserID        int64
+	FromAccountID int64
+	FromAccount   string // Название счета-источника, заполняется при чтении
+	ToAccountID   int64
+	ToAccount     string       // Название счета-получателя, заполняется при чтении
+	Amount        money.Amount // Списано в валюте счета-источника
+	ToAmount      money.Amount // Зачислено в валюте счета-получателя
+	FromCurrency  string       // Валюта счета-источника, заполняется при чтении
+	ToCurrency    string       // Валюта счета-получателя, заполняется при чтении
+	Date          time.Time
+	Note          string
+}
+
+// Долг: деньги, данные в долг или занятые у кого-то
+type Debt struct {
+	ID           int64
+	UserID       int64
+	ChatID       int64  // Чат учета, заполняется при выборе напоминаний
+	Direction    string // "lent" - должны пользователю, "borrowed" - должен пользователь
+	Counterparty string // Кто должен или кому должны
+	Amount       money.Amount
+	Currency     string
+	Date         time.Time
+	DueDate      time.Time // Срок возврата; нулевой - без срока
+	Note         string
+	Closed       bool         // Закрыт вручную, например долг прощен
+	Repaid       money.Amount // Сумма погашений, заполняется при чтении
+}
+
+// Remaining возвращает непогашенную часть долга
+func (d *Debt) Remaining() money.Amount {
+	return d.Amount - d.Repaid
+}
+
+// IsOpen сообщает, что долг не погашен и не закрыт
+func (d *Debt) IsOpen() bool {
+	return !d.Closed && d.Remaining() > 0
+}
+
+// Overdue сообщает, что срок открытого долга прошел до дня today; даты сравниваются
+// по календарю, в часовом поясе today
+func (d *Debt) Overdue(today time.Time) bool {
+	if !d.IsOpen() || d.DueDate.IsZero() {
+		return false
+	}
+	due := time.Date(d.DueDate.Year(), d.DueDate.Month(), d.DueDate.Day(), 0, 0, 0, 0, today.Location())
+	return due.Before(today)
+}
+
+// Цель накоплений: "Отпуск 150000 к июлю"
+type Goal struct {
+	ID          int64
+	UserID      int64
+	Name        string
+	Target      money.Amount
+	Currency    string
+	Deadline    time.Time    // К какому дню накопить; нулевой - без срока
+	Saved       money.Amount // Сумма взносов, заполняется при чтении
+	CompletedAt time.Time    // Когда цель достигнута; нулевой - еще не достигнута
+}
+
+// Remaining возвращает, сколько еще нужно отложить
+func (g *Goal) Remaining() money.Amount {
+	if g.Saved >= g.Target {
+		return 0
+	}
+	return g.Target - g.Saved
+}
+
+// Взнос в цель; TransactionID - операция, из которой отложены деньги, 0 - взнос вручную
+type GoalContribution struct {
+	ID            int64
+	GoalID        int64
+	Amount        money.Amount
+	Date          time.Time
+	TransactionID int64
+}
+
+// Отбор операций в отчете; пустые поля не ограничивают выборку
+type TransactionFilter struct {
+	Tag      string // Тег без "#"
+	Merchant string // Продавец, без учета регистра
+}
+
+// IsEmpty сообщает, что фильтр не задан
+func (f TransactionFilter) IsEmpty() bool {
+	return f.Tag == "" && f.Merchant == ""
+}
+
+// Сумма и количество операций одного типа за день или месяц
+type DateTotal struct {
+	Date   time.Time // День или первый день месяца
+	Type   string    // "income" или "expense"
+	Amount money.Amount
+	Count  int
+}
+
+// Сумма операций одного типа в исходной валюте
+type CurrencyTotal struct {
+	Currency string
+	Type     string // "income" или "expense"
+	Amount   money.Amount
+	Count    int
+}
+
+// Сумма и количество операций одного типа, записанных участником
+type MemberTotal struct {
+	MemberID int64  // 0 - операции без автора: импорт и повторяющиеся
+	Name     string // Имя участника; пусто, если он покинул учет
+	Type     string // "income" или "expense"
+	Amount   money.Amount
+	Count    int
+}
+
+// Курс валюты: стоимость одной единицы Currency в валюте Quote
+type ExchangeRate struct {
+	UserID   int64 // 0 - курс из общего источника
+	Currency string
+	Quote    string
+	Date     time.Time
+	Rate     float64
+}
+
+// Сумма и количество операций по категории
+type CategoryTotal struct {
+	CategoryID int64  // 0 - операции без категории
+	Category   string // Название категории
+	Emoji      string
+	Type       string // "income" или "expense"
+	Amount     money.Amount
+	Count      int
+}
+
+// Суммы категории за месяц: отдельно операции повторяющихся правил и все остальные
+type CategoryMonthTotal struct {
+	Month      time.Time // Первый день месяца
+	CategoryID int64     // 0 - операции без категории
+	Category   string
+	Emoji      string
+	Type       string       // "income" или "expense"
+	Amount     money.Amount // Операции, введенные вручную или импортированные
+	Recurring  money.Amount // Операции повторяющихся правил
+}
+
+// Категория доходов или расходов пользователя
+type Category struct {
+	ID       int64
+	UserID   int64
+	Name     string
+	Emoji    string
+	Type     string   // "income" или "expense"
+	Aliases  []string // Дополнительные слова для быстрого ввода, в нижнем регистре
+	Position int      // Порядок в клавиатуре
+	Archived bool     // Архивные категории не предлагаются при вводе, но остаются в истории
+}
+
+// Текст кнопки категории
+func (c Category) Label() string {
+	if c.Emoji == "" {
+		return c.Name
+	}
+	return c.Name + " " + c.Emoji
+}
+
+// Месячный бюджет категории расходов
+type Budget struct {
+	ID         int64
+	UserID     int64
+	CategoryID int64
+	Category   string // Название категории, заполняется при чтении
+	Emoji      string
+	Amount     money.Amount // Лимит на месяц
+	Rollover   bool         // Переносить неизрасходованный остаток на следующий месяц
+	StartMonth time.Time    // Первый месяц, с которого считается перенос
+}
+
+// Лимит бюджета, действующий с месяца Month до следующего изменения
+type BudgetLimit struct {
+	Month  time.Time
+	Amount money.Amount
+}
+
+// Правило повторяющейся операции
+type RecurringRule struct {
+	ID         int64
+	UserID     int64
+	ChatID     int64  // Чат владельца, заполняется при выборе правил к исполнению
+	Timezone   string // Часовой пояс владельца, заполняется при чтении
+	CategoryID int64  // 0, если категория удалена
+	Category   string
+	Emoji      string
+	Type       string // "income" или "expense"
+	Amount     money.Amount
+	Currency   string
+	Note       string
+	Frequency  string    // "daily", "weekly", "monthly" или "yearly"
+	DayOfMonth int       // День месяца для monthly и yearly; 0 - день StartDate
+	StartDate  time.Time //
+	EndDate    time.Time // Нулевая - без окончания
+	NextDate   time.Time // Дата следующей операции; нулевая - расписание закончилось
+	Paused     bool
+}
+
+// Подписка на регулярную сводку
+type Digest struct {
 	ID        int64
 	UserID    int64
-	Amount    float64
-	Category  string
-	Type      string // "income" или "expense"
-	CreatedAt time.Time
+	ChatID    int64  // Заполняется при выборе сводок к отправке
+	Timezone  string // Часовой пояс пользователя, заполняется при чтении
+	Frequency string // "daily", "weekly" или "monthly"
+	Minute    int    // Время отправки: минуты от полуночи по местному времени
+	NextRun   time.Time
+	LastRun   time.Time // Нулевое, если сводка еще не отправлялась
+}
+
+// Состояние диалога с пользователем
+type ChatState struct {
+	State      string       `json:"state"`
+	Amount     money.Amount `json:"amount,omitempty"`      // Введенная сумма, ожидающая выбора категории
+	Type       string       `json:"type,omitempty"`        // Тип операции при быстром вводе
+	Currency   string       `json:"currency,omitempty"`    // Валюта суммы, если указана при вводе
+	CategoryID int64        `json:"category_id,omitempty"` // Выбранная или редактируемая категория
+	Date       string       `json:"date,omitempty"`        // Дата операции в формате 2006-01-02
+	Note       string       `json:"note,omitempty"`
+	Tags       []string     `json:"tags,omitempty"`       // Теги операции из быстрого ввода
+	Merchant   string       `json:"merchant,omitempty"`   // Продавец операции из быстрого ввода
+	AccountID  int64        `json:"account_id,omitempty"` // Выбранный счет; 0 - основной
+	MemberID   int64        `json:"member_id,omitempty"`  // Участник, который начал ввод операции
+	DebtID     int64        `json:"debt_id,omitempty"`    // Долг, который погашает вводимая операция
+	GoalID     int64        `json:"goal_id,omitempty"`    // Цель, в которую вносятся деньги
+	EntryID    int          `json:"entry_id,omitempty"`   // Сообщение, из которого начат быстрый ввод; связывает с ним кнопки подтверждения
+
+	TransactionID int64 `json:"transaction_id,omitempty"` // Редактируемая операция
+
+	FileID string `json:"file_id,omitempty"` // Загруженная выписка, ожидающая импорта
+	Layout string `json:"layout,omitempty"`  // Выбранный шаблон выписки
 }
@@ -0,0 +1,226 @@
+// Package money хранит денежные суммы в копейках (сотых долях валюты), чтобы сложение
+// и сравнение сумм были точными, а не зависели от округления чисел с плавающей точкой.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Amount - сумма в сотых долях валюты: 12.30 хранится как 1230
+type Amount int64
+
+const (
+	// Scale - число сотых в единице валюты
+	Scale = 100
+	// Max - наибольшая сумма одной операции, которую вмещает колонка NUMERIC(10,2)
+	Max Amount = 99_999_999_99
+)
+
+var (
+	ErrInvalid   = errors.New("invalid amount")
+	ErrPrecision = errors.New("amount has more than two decimal places")
+	ErrOverflow  = errors.New("amount exceeds 99999999.99")
+)
+
+// New возвращает сумму из целой части и сотых: New(12, 30) = 12.30
+func New(units, cents int64) Amount {
+	return Amount(units*Scale + cents)
+}
+
+// Parse разбирает сумму вида "12", "-12.3", "12,30". Больше двух знаков после
+// разделителя - ошибка ErrPrecision, сумма больше Max по модулю - ErrOverflow.
+func Parse(s string) (Amount, error) {
+	a, err := parse(s)
+	if err != nil {
+		return 0, err
+	}
+	if err := a.Check(); err != nil {
+		return 0, err
+	}
+	return a, nil
+}
+
+// Разбор без ограничения Max: итоги из базы могут быть больше суммы одной операции
+func parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, fraction, hasFraction := strings.Cut(strings.Replace(s, ",", ".", 1), ".")
+	if whole == "" && fraction == "" || hasFraction && fraction == "" || !digits(whole) || !digits(fraction) {
+		return 0, ErrInvalid
+	}
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > 2 {
+		return 0, ErrPrecision
+	}
+
+	units, err := strconv.ParseInt("0"+whole, 10, 64)
+	if err != nil || units > math.MaxInt64/Scale-1 {
+		return 0, ErrOverflow
+	}
+	cents, _ := strconv.ParseInt((fraction + "00")[:2], 10, 64)
+
+	a := New(units, cents)
+	if negative {
+		a = -a
+	}
+	return a, nil
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// FromFloat округляет число до копеек; используется для значений, которые
+// приходят извне как числа с плавающей точкой
+func FromFloat(f float64) (Amount, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) || math.Abs(f) > float64(Max)/Scale {
+		return 0, ErrOverflow
+	}
+	return Amount(math.Round(f * Scale)), nil
+}
+
+// Check проверяет, что сумма помещается в колонку NUMERIC(10,2)
+func (a Amount) Check() error {
+	if a > Max || a < -Max {
+		return ErrOverflow
+	}
+	return nil
+}
+
+// Float64 - приближенное значение для диаграмм и процентов; не используйте его для расчетов
+func (a Amount) Float64() float64 {
+	return float64(a) / Scale
+}
+
+// Abs возвращает модуль суммы
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// Mul умножает сумму на коэффициент (например, курс валюты) с округлением до копеек
+func (a Amount) Mul(k float64) Amount {
+	return Amount(math.Round(float64(a) * k))
+}
+
+// Div делит сумму на n частей с округлением до копеек
+func (a Amount) Div(n int64) Amount {
+	if n == 0 {
+		return 0
+	}
+	q, r := int64(a)/n, int64(a)%n
+	if r < 0 {
+		r = -r
+	}
+	if 2*r >= abs(n) {
+		if (a < 0) != (n < 0) {
+			q--
+		} else {
+			q++
+		}
+	}
+	return Amount(q)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Percent возвращает долю a от total в процентах
+func (a Amount) Percent(total Amount) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(a) / float64(total) * 100
+}
+
+// String - сумма с двумя знаками после точки: "12.30", "-0.05"
+func (a Amount) String() string {
+	sign := ""
+	if a < 0 {
+		sign = "-"
+	}
+	v := uint64(a)
+	if a < 0 {
+		v = uint64(-a)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/Scale, v%Scale)
+}
+
+// Scan читает NUMERIC из базы без промежуточного float64
+func (a *Amount) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	case int64:
+		*a = Amount(v * Scale)
+		return nil
+	case float64:
+		f, err := FromFloat(v)
+		*a = f
+		return err
+	}
+	return fmt.Errorf("money: cannot scan %T", src)
+}
+
+func (a *Amount) scanString(s string) error {
+	v, err := parse(s)
+	if err != nil {
+		return fmt.Errorf("money: scan %q: %w", s, err)
+	}
+	*a = v
+	return nil
+}
+
+// Value передает сумму в базу строкой, чтобы NUMERIC получил точное значение
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// MarshalJSON записывает сумму числом с двумя знаками: 12.30
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON принимает число или строку; число с большим количеством знаков
+// (из старых записей с float64) округляется до копеек
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" {
+		return nil
+	}
+	v, err := Parse(s)
+	if errors.Is(err, ErrPrecision) {
+		var f float64
+		if f, err = strconv.ParseFloat(s, 64); err == nil {
+			v, err = FromFloat(f)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
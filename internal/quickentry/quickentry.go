@@ -0,0 +1,241 @@
+// Package quickentry разбирает операции, введенные одной строкой:
+// "350 кафе обед", "+50000 зп", "-1200.50 такси вчера", "4200 продукты #отпуск @Магнит".
+package quickentry
+
+import (
+	"errors"
+	"finuchet-bot/internal/currency"
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrNoAmount      = errors.New("amount not found")
+	ErrInvalidAmount = errors.New("invalid amount")
+)
+
+// Знак, сумма (пробелы как разделители тысяч) и дробная часть после запятой или точки.
+// Больше двух знаков после запятой - ошибка money.ErrPrecision, а не другая сумма.
+var amountRe = regexp.MustCompile(`^([+-])?\s*(\d{1,3}(?:[ \x{00A0}\x{202F}]\d{3})+|\d+)(?:[.,](\d+))?(?:\s+|$)`)
+
+// Entry - разобранная операция
+type Entry struct {
+	Type     string           // "income" или "expense"
+	Amount   money.Amount     // Всегда положительная
+	Currency string           // Код валюты, если она указана сразу после суммы: "20 usd", "15 €"
+	Category *models.Category // nil, если категорию распознать не удалось
+	Date     time.Time        // Начало дня операции
+	Note     string           // Слова, не распознанные как категория, дата, тег или продавец
+	Tags     []string         // Теги "#отпуск" без "#" в нижнем регистре
+	Merchant string           // Продавец "@Магнит"
+}
+
+// LooksLikeEntry сообщает, начинается ли текст с суммы
+func LooksLikeEntry(text string) bool {
+	return amountRe.MatchString(strings.TrimSpace(text))
+}
+
+// Parse разбирает строку быстрого ввода.
+// Без знака операция считается расходом, "+" означает доход, "-" - явный расход.
+// Если знака нет, а слово совпало только с категорией дохода, операция считается доходом.
+func Parse(text string, categories []*models.Category, now time.Time) (*Entry, error) {
+	text = strings.TrimSpace(text)
+	m := amountRe.FindStringSubmatch(text)
+	if m == nil {
+		return nil, ErrNoAmount
+	}
+
+	amount, err := parseAmountParts(m[2], m[3])
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &Entry{Type: "expense", Amount: amount, Date: dates.Day(now)}
+	explicitType := m[1] != ""
+	if m[1] == "+" {
+		entry.Type = "income"
+	}
+
+	var noteWords []string
+	for i, word := range strings.Fields(text[len(m[0]):]) {
+		if tag, ok := Tag(word); ok {
+			entry.Tags = appendTag(entry.Tags, tag)
+			continue
+		}
+		if merchant, ok := Merchant(word); ok {
+			entry.Merchant = merchant
+			continue
+		}
+		clean := normalize(word)
+		if i == 0 {
+			if code, ok := currency.Parse(clean); ok {
+				entry.Currency = code
+				continue
+			}
+		}
+		if d, ok := dates.Parse(clean, now); ok {
+			entry.Date = d
+			continue
+		}
+		if entry.Category == nil {
+			if c, ok := MatchCategory(clean, ofType(categories, entry.Type)); ok {
+				entry.Category = c
+				continue
+			}
+			if !explicitType {
+				if c, ok := MatchCategory(clean, ofType(categories, "income")); ok {
+					entry.Type = "income"
+					entry.Category = c
+					continue
+				}
+			}
+		}
+		noteWords = append(noteWords, word)
+	}
+	entry.Note = strings.Join(noteWords, " ")
+
+	return entry, nil
+}
+
+// Tag распознает тег "#отпуск" и возвращает его без "#" в нижнем регистре
+func Tag(word string) (string, bool) {
+	rest, ok := strings.CutPrefix(word, "#")
+	if !ok {
+		return "", false
+	}
+	tag := normalize(rest)
+	return tag, tag != ""
+}
+
+// Tags возвращает теги из текста "#отпуск #море" или "отпуск, море" без повторов
+func Tags(text string) []string {
+	var tags []string
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return unicode.IsSpace(r) || r == ',' }) {
+		if tag := normalize(strings.TrimPrefix(word, "#")); tag != "" {
+			tags = appendTag(tags, tag)
+		}
+	}
+	return tags
+}
+
+func appendTag(tags []string, tag string) []string {
+	for _, t := range tags {
+		if t == tag {
+			return tags
+		}
+	}
+	return append(tags, tag)
+}
+
+// Merchant распознает продавца "@Магнит"; "_" заменяет пробел: "@Вкусно_и_точка"
+func Merchant(word string) (string, bool) {
+	rest, ok := strings.CutPrefix(word, "@")
+	if !ok {
+		return "", false
+	}
+	merchant := strings.TrimFunc(strings.ReplaceAll(rest, "_", " "), func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r)
+	})
+	return merchant, merchant != ""
+}
+
+// ParseAmount разбирает сумму: "1200", "1 200,50", "1200.5".
+// Кроме ErrInvalidAmount возвращает money.ErrPrecision и money.ErrOverflow.
+func ParseAmount(s string) (money.Amount, error) {
+	m := amountRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil || m[1] == "-" || len(m[0]) != len(strings.TrimSpace(s)) {
+		return 0, ErrInvalidAmount
+	}
+	return parseAmountParts(m[2], m[3])
+}
+
+func parseAmountParts(whole, fraction string) (money.Amount, error) {
+	whole = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, whole)
+	if fraction != "" {
+		whole += "." + fraction
+	}
+	amount, err := money.Parse(whole)
+	if err != nil {
+		return 0, err
+	}
+	if amount <= 0 {
+		return 0, ErrInvalidAmount
+	}
+	return amount, nil
+}
+
+// MatchCategory ищет категорию по слову: сначала точное совпадение с названием
+// или синонимом, затем однозначное совпадение по началу слова (не короче 3 букв)
+func MatchCategory(word string, categories []*models.Category) (*models.Category, bool) {
+	word = normalize(word)
+	if word == "" {
+		return nil, false
+	}
+
+	for _, c := range categories {
+		for _, name := range names(c) {
+			if name == word {
+				return c, true
+			}
+		}
+	}
+
+	if len([]rune(word)) < 3 {
+		return nil, false
+	}
+	var found []*models.Category
+	for _, c := range categories {
+		for _, name := range names(c) {
+			if strings.HasPrefix(name, word) {
+				found = append(found, c)
+				break
+			}
+		}
+	}
+	if len(found) == 1 {
+		return found[0], true
+	}
+	return nil, false
+}
+
+// Названия категории для сравнения: полное название, его первое слово и синонимы
+func names(c *models.Category) []string {
+	name := normalize(c.Name)
+	result := []string{name}
+	if first, _, ok := strings.Cut(name, " "); ok {
+		result = append(result, first)
+	}
+	for _, alias := range c.Aliases {
+		result = append(result, normalize(alias))
+	}
+	return result
+}
+
+func ofType(categories []*models.Category, transactionType string) []*models.Category {
+	var result []*models.Category
+	for _, c := range categories {
+		if c.Type == transactionType {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// Нижний регистр, без знаков препинания по краям (кроме "/" в "З/п") и "ё" как "е"
+func normalize(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimFunc(s, func(r rune) bool {
+		return unicode.IsPunct(r) && r != '/'
+	})
+	return strings.ReplaceAll(s, "ё", "е")
+}
@@ -0,0 +1,139 @@
+package quickentry
+
+import (
+	"errors"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"slices"
+	"testing"
+	"time"
+)
+
+var testCategories = []*models.Category{
+	{ID: 1, Name: "Кафе", Type: "expense", Aliases: []string{"ресторан"}},
+	{ID: 2, Name: "Такси", Type: "expense"},
+	{ID: 3, Name: "Продукты", Type: "expense"},
+	{ID: 4, Name: "Зарплата", Type: "income", Aliases: []string{"зп"}},
+}
+
+func TestParse(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skip(err)
+	}
+	// Среда, 11 марта 2026
+	now := time.Date(2026, 3, 11, 23, 30, 0, 0, moscow)
+	today := time.Date(2026, 3, 11, 0, 0, 0, 0, moscow)
+
+	tests := []struct {
+		text     string
+		typ      string
+		amount   money.Amount
+		currency string
+		category int64 // 0 - категория не распознана
+		date     time.Time
+		note     string
+		tags     []string
+		merchant string
+	}{
+		{text: "350 кафе обед", typ: "expense", amount: 350_00, category: 1, date: today, note: "обед"},
+		{text: "+50000 зп", typ: "income", amount: 50000_00, category: 4, date: today},
+		{text: "50000 зп", typ: "income", amount: 50000_00, category: 4, date: today},
+		{text: "-1200.50 такси вчера", typ: "expense", amount: 1200_50, category: 2, date: today.AddDate(0, 0, -1)},
+		{text: "1 200,50 продукты", typ: "expense", amount: 1200_50, category: 3, date: today},
+		{text: "1 200 ресторан", typ: "expense", amount: 1200_00, category: 1, date: today},
+		{text: "20 usd кафе", typ: "expense", amount: 20_00, currency: "USD", category: 1, date: today},
+		{text: "15 € такси пт", typ: "expense", amount: 15_00, currency: "EUR", category: 2, date: today.AddDate(0, 0, -5)},
+		{text: "300 кафе 05.03", typ: "expense", amount: 300_00, category: 1, date: today.AddDate(0, 0, -6)},
+		{
+			text: "4200 прод #Отпуск #отпуск @Вкусно_и_точка", typ: "expense", amount: 4200_00, category: 3, date: today,
+			tags: []string{"отпуск"}, merchant: "Вкусно и точка",
+		},
+		// Явный расход не ищет категорию среди доходов
+		{text: "-50 зп", typ: "expense", amount: 50_00, date: today, note: "зп"},
+		// Валюта распознается только сразу после суммы
+		{text: "100 кафе usd", typ: "expense", amount: 100_00, category: 1, date: today, note: "usd"},
+		{text: "99 непонятно что", typ: "expense", amount: 99_00, date: today, note: "непонятно что"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			entry, err := Parse(tt.text, testCategories, now)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if entry.Type != tt.typ || entry.Amount != tt.amount || entry.Currency != tt.currency {
+				t.Errorf("got %s %s %q, want %s %s %q", entry.Type, entry.Amount, entry.Currency, tt.typ, tt.amount, tt.currency)
+			}
+			var category int64
+			if entry.Category != nil {
+				category = entry.Category.ID
+			}
+			if category != tt.category {
+				t.Errorf("category = %d, want %d", category, tt.category)
+			}
+			if !entry.Date.Equal(tt.date) {
+				t.Errorf("date = %s, want %s", entry.Date, tt.date)
+			}
+			if entry.Note != tt.note || entry.Merchant != tt.merchant || !slices.Equal(entry.Tags, tt.tags) {
+				t.Errorf("note %q, tags %q, merchant %q; want %q, %q, %q",
+					entry.Note, entry.Tags, entry.Merchant, tt.note, tt.tags, tt.merchant)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	now := time.Date(2026, 3, 11, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		text string
+		want error
+	}{
+		{"1200.505 такси", money.ErrPrecision},
+		{"1,999 кафе", money.ErrPrecision},
+		{"100000000 кафе", money.ErrOverflow},
+		{"0 кафе", ErrInvalidAmount},
+		{"кафе 350", ErrNoAmount},
+		{"", ErrNoAmount},
+		{"привет", ErrNoAmount},
+		{"350кафе", ErrNoAmount},
+		{"1.2.3 кафе", ErrNoAmount},
+		{"12 34", nil}, // Не разделитель тысяч: сумма 12 и заметка "34"
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			_, err := Parse(tt.text, testCategories, now)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Parse(%q) error = %v, want %v", tt.text, err, tt.want)
+			}
+			if got := LooksLikeEntry(tt.text); got != (tt.want != ErrNoAmount) {
+				t.Errorf("LooksLikeEntry(%q) = %v", tt.text, got)
+			}
+		})
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want money.Amount
+		err  error
+	}{
+		{"1200", 1200_00, nil},
+		{"1 200,50", 1200_50, nil},
+		{"1200.5", 1200_50, nil},
+		{"+15", 15_00, nil},
+		{"0,01", 1, nil},
+		{"12.345", 0, money.ErrPrecision},
+		{"-15", 0, ErrInvalidAmount},
+		{"0", 0, ErrInvalidAmount},
+		{"12 кафе", 0, ErrInvalidAmount},
+		{"1 20", 0, ErrInvalidAmount},
+		{"", 0, ErrInvalidAmount},
+	}
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.in)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("ParseAmount(%q) = %s, %v; want %s, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Package recurring вычисляет даты повторяющихся операций: аренды, зарплаты, подписок.
+// Даты считаются от начала расписания, а не от предыдущей операции, поэтому
+// платеж 31-го числа в феврале приходится на 28-е, а в марте снова на 31-е.
+package recurring
+
+import (
+	"strings"
+	"time"
+)
+
+// Периодичность правила
+const (
+	Daily   = "daily"
+	Weekly  = "weekly"
+	Monthly = "monthly"
+	Yearly  = "yearly"
+)
+
+// Названия периодичности в командах бота
+var frequencyWords = map[string]string{
+	"daily": Daily, "ежедневно": Daily, "каждый_день": Daily,
+	"weekly": Weekly, "еженедельно": Weekly, "каждую_неделю": Weekly,
+	"monthly": Monthly, "ежемесячно": Monthly, "каждый_месяц": Monthly,
+	"yearly": Yearly, "ежегодно": Yearly, "каждый_год": Yearly,
+}
+
+// ParseFrequency распознает периодичность: "ежемесячно", "monthly"
+func ParseFrequency(word string) (string, bool) {
+	frequency, ok := frequencyWords[strings.ToLower(strings.TrimSpace(word))]
+	return frequency, ok
+}
+
+// Valid сообщает, известна ли периодичность
+func Valid(frequency string) bool {
+	switch frequency {
+	case Daily, Weekly, Monthly, Yearly:
+		return true
+	}
+	return false
+}
+
+// Schedule - расписание повторяющейся операции
+type Schedule struct {
+	Frequency  string
+	DayOfMonth int       // День месяца для Monthly и Yearly; 0 - день Start. В коротких месяцах - последний день
+	Start      time.Time // Первая возможная дата
+	End        time.Time // Последняя возможная дата включительно; нулевая - без окончания
+}
+
+// At возвращает n-ю дату расписания, считая с нуля. Для Monthly и Yearly с DayOfMonth
+// нулевая дата может оказаться раньше Start; Next такие даты пропускает.
+func (s Schedule) At(n int) time.Time {
+	start := day(s.Start)
+	switch s.Frequency {
+	case Daily:
+		return start.AddDate(0, 0, n)
+	case Weekly:
+		return start.AddDate(0, 0, 7*n)
+	case Monthly:
+		return s.monthDay(start.Year(), start.Month()+time.Month(n))
+	case Yearly:
+		return s.monthDay(start.Year()+n, start.Month())
+	}
+	return time.Time{}
+}
+
+// Next возвращает первую дату расписания не раньше from и не раньше Start;
+// ok = false, если расписание к этому времени закончилось
+func (s Schedule) Next(from time.Time) (next time.Time, ok bool) {
+	from = day(from)
+	if start := day(s.Start); from.Before(start) {
+		from = start
+	}
+
+	// Оценка номера даты снизу, затем шаг вперед до первой подходящей
+	n := 0
+	start := day(s.Start)
+	switch s.Frequency {
+	case Daily:
+		n = int(from.Sub(start).Hours() / 24)
+	case Weekly:
+		n = int(from.Sub(start).Hours()/24) / 7
+	case Monthly:
+		n = (from.Year()-start.Year())*12 + int(from.Month()-start.Month()) - 1
+	case Yearly:
+		n = from.Year() - start.Year() - 1
+	default:
+		return time.Time{}, false
+	}
+	if n < 0 {
+		n = 0
+	}
+	for next = s.At(n); next.Before(from); next = s.At(n) {
+		n++
+	}
+
+	if !s.End.IsZero() && next.After(day(s.End)) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// Between возвращает даты расписания в интервале [from, to] включительно, не больше limit
+func (s Schedule) Between(from, to time.Time, limit int) []time.Time {
+	var result []time.Time
+	to = day(to)
+	for next, ok := s.Next(from); ok && !next.After(to) && len(result) < limit; next, ok = s.Next(next.AddDate(0, 0, 1)) {
+		result = append(result, next)
+	}
+	return result
+}
+
+// Дата в месяце с переносом на последний день, если в месяце меньше дней
+func (s Schedule) monthDay(year int, month time.Month) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	d := s.DayOfMonth
+	if d == 0 {
+		d = s.Start.Day()
+	}
+	if last := first.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+	return first.AddDate(0, 0, d-1)
+}
+
+// Календарная дата в UTC: даты из базы приходят в UTC, а введенные - в поясе пользователя
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,134 @@
+package recurring
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestScheduleAt(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule Schedule
+		n        int
+		want     time.Time
+	}{
+		{"daily", Schedule{Frequency: Daily, Start: date(2026, 1, 30)}, 3, date(2026, 2, 2)},
+		{"weekly", Schedule{Frequency: Weekly, Start: date(2026, 1, 7)}, 2, date(2026, 1, 21)},
+		{"31st in January", Schedule{Frequency: Monthly, Start: date(2026, 1, 31)}, 0, date(2026, 1, 31)},
+		{"31st carried into February", Schedule{Frequency: Monthly, Start: date(2026, 1, 31)}, 1, date(2026, 2, 28)},
+		{"31st back in March", Schedule{Frequency: Monthly, Start: date(2026, 1, 31)}, 2, date(2026, 3, 31)},
+		{"31st in April", Schedule{Frequency: Monthly, Start: date(2026, 1, 31)}, 3, date(2026, 4, 30)},
+		{"31st in leap February", Schedule{Frequency: Monthly, Start: date(2024, 1, 31)}, 1, date(2024, 2, 29)},
+		{"30th across year end", Schedule{Frequency: Monthly, Start: date(2026, 11, 30)}, 3, date(2027, 2, 28)},
+		{"day of month before start", Schedule{Frequency: Monthly, DayOfMonth: 5, Start: date(2026, 1, 20)}, 0, date(2026, 1, 5)},
+		{"day of month 31 from the 10th", Schedule{Frequency: Monthly, DayOfMonth: 31, Start: date(2026, 2, 10)}, 0, date(2026, 2, 28)},
+		{"leap day in a common year", Schedule{Frequency: Yearly, Start: date(2024, 2, 29)}, 1, date(2025, 2, 28)},
+		{"leap day in the next leap year", Schedule{Frequency: Yearly, Start: date(2024, 2, 29)}, 4, date(2028, 2, 29)},
+		{"unknown frequency", Schedule{Frequency: "hourly", Start: date(2026, 1, 1)}, 1, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.At(tt.n); !got.Equal(tt.want) {
+				t.Errorf("At(%d) = %s, want %s", tt.n, got.Format(time.DateOnly), tt.want.Format(time.DateOnly))
+			}
+		})
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		from     time.Time
+		want     time.Time
+		wantOK   bool
+	}{
+		{"from before start", Schedule{Frequency: Daily, Start: date(2026, 3, 1)}, date(2026, 1, 1), date(2026, 3, 1), true},
+		{"from is a schedule date", Schedule{Frequency: Weekly, Start: date(2026, 1, 7)}, date(2026, 1, 14), date(2026, 1, 14), true},
+		{"from between dates", Schedule{Frequency: Weekly, Start: date(2026, 1, 7)}, date(2026, 1, 8), date(2026, 1, 14), true},
+		{"skips day of month before start", Schedule{Frequency: Monthly, DayOfMonth: 5, Start: date(2026, 1, 20)}, date(2026, 1, 20), date(2026, 2, 5), true},
+		{"31st after February", Schedule{Frequency: Monthly, Start: date(2026, 1, 31)}, date(2026, 3, 1), date(2026, 3, 31), true},
+		{"28th of February is the carried 31st", Schedule{Frequency: Monthly, Start: date(2026, 1, 31)}, date(2026, 2, 28), date(2026, 2, 28), true},
+		{"leap day yearly in a common year", Schedule{Frequency: Yearly, Start: date(2024, 2, 29)}, date(2025, 1, 1), date(2025, 2, 28), true},
+		{"leap day yearly in a leap year", Schedule{Frequency: Yearly, Start: date(2024, 2, 29)}, date(2027, 3, 1), date(2028, 2, 29), true},
+		{"end is inclusive", Schedule{Frequency: Daily, Start: date(2026, 1, 1), End: date(2026, 1, 3)}, date(2026, 1, 3), date(2026, 1, 3), true},
+		{"after end", Schedule{Frequency: Daily, Start: date(2026, 1, 1), End: date(2026, 1, 3)}, date(2026, 1, 4), time.Time{}, false},
+		{"next date after end", Schedule{Frequency: Monthly, Start: date(2026, 1, 31), End: date(2026, 4, 29)}, date(2026, 4, 1), time.Time{}, false},
+		{"calendar day of a local time", Schedule{Frequency: Daily, Start: date(2026, 1, 1)}, time.Date(2026, 1, 31, 23, 30, 0, 0, moscow), date(2026, 1, 31), true},
+		{"unknown frequency", Schedule{Frequency: "hourly", Start: date(2026, 1, 1)}, date(2026, 1, 1), time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.schedule.Next(tt.from)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, %v, want %s, %v", tt.from.Format(time.DateOnly),
+					got.Format(time.DateOnly), ok, tt.want.Format(time.DateOnly), tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestScheduleBetween(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule Schedule
+		from, to time.Time
+		limit    int
+		want     []time.Time
+	}{
+		{
+			name:     "31st through short months",
+			schedule: Schedule{Frequency: Monthly, Start: date(2026, 1, 31)},
+			from:     date(2026, 1, 1), to: date(2026, 4, 30), limit: 10,
+			want: []time.Time{date(2026, 1, 31), date(2026, 2, 28), date(2026, 3, 31), date(2026, 4, 30)},
+		},
+		{
+			name:     "to is inclusive",
+			schedule: Schedule{Frequency: Weekly, Start: date(2026, 1, 7)},
+			from:     date(2026, 1, 7), to: date(2026, 1, 21), limit: 10,
+			want: []time.Time{date(2026, 1, 7), date(2026, 1, 14), date(2026, 1, 21)},
+		},
+		{
+			name:     "daily across leap day",
+			schedule: Schedule{Frequency: Daily, Start: date(2024, 1, 1)},
+			from:     date(2024, 2, 27), to: date(2024, 3, 1), limit: 10,
+			want: []time.Time{date(2024, 2, 27), date(2024, 2, 28), date(2024, 2, 29), date(2024, 3, 1)},
+		},
+		{
+			name:     "stops at end",
+			schedule: Schedule{Frequency: Monthly, Start: date(2026, 1, 31), End: date(2026, 3, 31)},
+			from:     date(2026, 1, 1), to: date(2026, 12, 31), limit: 10,
+			want: []time.Time{date(2026, 1, 31), date(2026, 2, 28), date(2026, 3, 31)},
+		},
+		{
+			name:     "limit",
+			schedule: Schedule{Frequency: Daily, Start: date(2026, 1, 1)},
+			from:     date(2026, 1, 1), to: date(2026, 12, 31), limit: 2,
+			want: []time.Time{date(2026, 1, 1), date(2026, 1, 2)},
+		},
+		{
+			name:     "nothing due",
+			schedule: Schedule{Frequency: Monthly, Start: date(2026, 1, 31)},
+			from:     date(2026, 2, 1), to: date(2026, 2, 27), limit: 10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.schedule.Between(tt.from, tt.to, tt.limit)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Between() returned %d dates %v, want %d", len(got), got, len(tt.want))
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("date #%d = %s, want %s", i, got[i].Format(time.DateOnly), tt.want[i].Format(time.DateOnly))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"database/sql"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"time"
+)
+
+const accountColumns = "acc.id, acc.user_id, acc.name, acc.currency, acc.opening_balance, acc.is_default, acc.archived"
+
+func scanAccount(row interface{ Scan(...any) error }, extra ...any) (*models.Account, error) {
+	account := &models.Account{}
+	err := row.Scan(append([]any{&account.ID, &account.UserID, &account.Name, &account.Currency,
+		&account.OpeningBalance, &account.IsDefault, &account.Archived}, extra...)...)
+	return account, err
+}
+
+// GetAccounts возвращает счета пользователя: основной первым, остальные в порядке создания
+func (r *PostgresRepository) GetAccounts(userID int64, withArchived bool) ([]*models.Account, error) {
+	rows, err := r.db.Query(`SELECT `+accountColumns+` FROM accounts AS acc
+		WHERE acc.user_id = $1 AND ($2 OR NOT acc.archived)
+		ORDER BY acc.is_default DESC, acc.id`, userID, withArchived)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []*models.Account
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+// GetAccountByID возвращает счет, только если он принадлежит пользователю
+func (r *PostgresRepository) GetAccountByID(userID, accountID int64) (*models.Account, error) {
+	account, err := scanAccount(r.db.QueryRow(`SELECT `+accountColumns+` FROM accounts AS acc
+		WHERE acc.id = $1 AND acc.user_id = $2`, accountID, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return account, err
+}
+
+// GetDefaultAccount возвращает основной счет пользователя или nil, если его нет
+func (r *PostgresRepository) GetDefaultAccount(userID int64) (*models.Account, error) {
+	account, err := scanAccount(r.db.QueryRow(`SELECT `+accountColumns+` FROM accounts AS acc
+		WHERE acc.user_id = $1 AND acc.is_default`, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return account, err
+}
+
+// CreateAccount добавляет счет; счет с тем же названием вернет ErrDuplicate
+func (r *PostgresRepository) CreateAccount(account *models.Account) error {
+	err := r.db.QueryRow(`INSERT INTO accounts (user_id, name, currency, opening_balance, is_default)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`,
+		account.UserID, account.Name, account.Currency, account.OpeningBalance, account.IsDefault,
+	).Scan(&account.ID)
+	return duplicateError(err)
+}
+
+// UpdateAccount изменяет название, начальный остаток и архивность счета
+func (r *PostgresRepository) UpdateAccount(account *models.Account) error {
+	_, err := r.db.Exec(`UPDATE accounts SET name = $3, opening_balance = $4, archived = $5
+		WHERE id = $1 AND user_id = $2`,
+		account.ID, account.UserID, account.Name, account.OpeningBalance, account.Archived)
+	return duplicateError(err)
+}
+
+// SetDefaultAccount делает счет основным вместо прежнего
+func (r *PostgresRepository) SetDefaultAccount(userID, accountID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE accounts SET is_default = FALSE WHERE user_id = $1 AND is_default", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE accounts SET is_default = TRUE WHERE id = $1 AND user_id = $2", accountID, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SeedAccount создает основной счет, если у пользователя еще нет ни одного счета
+func (r *PostgresRepository) SeedAccount(account *models.Account) error {
+	_, err := r.db.Exec(`INSERT INTO accounts (user_id, name, currency, is_default)
+		SELECT $1, $2, $3, TRUE
+		WHERE NOT EXISTS (SELECT 1 FROM accounts WHERE user_id = $1)
+		ON CONFLICT DO NOTHING`,
+		account.UserID, account.Name, account.Currency)
+	return err
+}
+
+// GetAccountBalances возвращает неархивные счета с остатками в валюте счета на начало дня to;
+// нулевой to - текущие остатки. Операции в другой валюте пересчитываются по курсу на их дату,
+// операции без курса в остаток не попадают.
+func (r *PostgresRepository) GetAccountBalances(userID int64, to time.Time) ([]*models.Account, error) {
+	rows, err := r.db.Query(`SELECT `+accountColumns+`, acc.opening_balance
+			+ COALESCE((SELECT SUM(CASE WHEN t.type = 'income' THEN 1 ELSE -1 END * `+amountIn("acc.currency")+`)
+				FROM transactions AS t
+				WHERE t.account_id = acc.id AND ($2::date IS NULL OR t.create_dat < $2::date)), 0)
+			+ COALESCE((SELECT SUM(tr.to_amount) FROM transfers AS tr
+				WHERE tr.to_account_id = acc.id AND ($2::date IS NULL OR tr.transfer_date < $2::date)), 0)
+			- COALESCE((SELECT SUM(tr.amount) FROM transfers AS tr
+				WHERE tr.from_account_id = acc.id AND ($2::date IS NULL OR tr.transfer_date < $2::date)), 0)
+		FROM accounts AS acc
+		WHERE acc.user_id = $1 AND NOT acc.archived
+		ORDER BY acc.is_default DESC, acc.id`, userID, nullDate(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []*models.Account
+	for rows.Next() {
+		var balance money.Amount
+		account, err := scanAccount(rows, &balance)
+		if err != nil {
+			return nil, err
+		}
+		account.Balance = balance
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+// Переводы вместе с названиями и валютами счетов
+const selectTransfers = `SELECT tr.id, tr.user_id, tr.from_account_id, f.name, f.currency, tr.to_account_id, d.name, d.currency,
+		tr.amount, tr.to_amount, tr.transfer_date, tr.note
+	FROM transfers AS tr
+	JOIN accounts AS f ON f.id = tr.from_account_id
+	JOIN accounts AS d ON d.id = tr.to_account_id`
+
+// AddTransfer сохраняет перевод: списание и зачисление записываются одной строкой
+func (r *PostgresRepository) AddTransfer(transfer *models.Transfer) error {
+	return r.db.QueryRow(`INSERT INTO transfers (user_id, from_account_id, to_account_id, amount, to_amount, transfer_date, note)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`,
+		transfer.UserID, transfer.FromAccountID, transfer.ToAccountID, transfer.Amount, transfer.ToAmount, transfer.Date, transfer.Note,
+	).Scan(&transfer.ID)
+}
+
+// GetRecentTransfers возвращает последние переводы пользователя, начиная с самых новых
+func (r *PostgresRepository) GetRecentTransfers(userID int64, limit int) ([]*models.Transfer, error) {
+	rows, err := r.db.Query(selectTransfers+`
+		WHERE tr.user_id = $1
+		ORDER BY tr.transfer_date DESC, tr.id DESC
+		LIMIT $2`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []*models.Transfer
+	for rows.Next() {
+		t := &models.Transfer{}
+		if err := rows.Scan(&t.ID, &t.UserID, &t.FromAccountID, &t.FromAccount, &t.FromCurrency, &t.ToAccountID, &t.ToAccount, &t.ToCurrency,
+			&t.Amount, &t.ToAmount, &t.Date, &t.Note); err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, rows.Err()
+}
+
+// DeleteTransfer удаляет перевод; чужие переводы не затрагиваются
+func (r *PostgresRepository) DeleteTransfer(userID, transferID int64) error {
+	_, err := r.db.Exec("DELETE FROM transfers WHERE id = $1 AND user_id = $2", transferID, userID)
+	return err
+}
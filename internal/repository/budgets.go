@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"database/sql"
+	"finuchet-bot/internal/models"
+)
+
+// Бюджеты вместе с названием категории
+const selectBudgets = `SELECT b.id, b.user_id, b.category_id, c.category, COALESCE(c.emoji, ''), b.amount, b.rollover, b.start_month
+	FROM budgets AS b
+	JOIN user_categories AS c ON c.id = b.category_id`
+
+func scanBudget(row interface{ Scan(...any) error }) (*models.Budget, error) {
+	budget := &models.Budget{}
+	err := row.Scan(&budget.ID, &budget.UserID, &budget.CategoryID, &budget.Category, &budget.Emoji,
+		&budget.Amount, &budget.Rollover, &budget.StartMonth)
+	return budget, err
+}
+
+// GetBudgets возвращает бюджеты пользователя в порядке категорий
+func (r *PostgresRepository) GetBudgets(userID int64) ([]*models.Budget, error) {
+	rows, err := r.db.Query(selectBudgets+" WHERE b.user_id = $1 ORDER BY c.position, c.id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var budgets []*models.Budget
+	for rows.Next() {
+		budget, err := scanBudget(rows)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, budget)
+	}
+	return budgets, rows.Err()
+}
+
+// GetBudget возвращает бюджет категории или nil, если он не задан
+func (r *PostgresRepository) GetBudget(userID, categoryID int64) (*models.Budget, error) {
+	budget, err := scanBudget(r.db.QueryRow(selectBudgets+" WHERE b.user_id = $1 AND b.category_id = $2", userID, categoryID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return budget, err
+}
+
+// SetBudget создает или изменяет бюджет категории. Лимит budget.Amount действует с месяца
+// budget.StartMonth, у прошлых месяцев остаются их лимиты. При включении или выключении
+// переноса остатка отсчет начинается заново с budget.StartMonth.
+func (r *PostgresRepository) SetBudget(budget *models.Budget) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	month := budget.StartMonth
+	err = tx.QueryRow(`INSERT INTO budgets (user_id, category_id, amount, rollover, start_month)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, category_id) DO UPDATE SET
+			amount = EXCLUDED.amount,
+			rollover = EXCLUDED.rollover,
+			start_month = CASE WHEN budgets.rollover <> EXCLUDED.rollover THEN EXCLUDED.start_month ELSE budgets.start_month END
+		RETURNING id, start_month`,
+		budget.UserID, budget.CategoryID, budget.Amount, budget.Rollover, month,
+	).Scan(&budget.ID, &budget.StartMonth)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO budget_limits (budget_id, month, amount) VALUES ($1, $2, $3)
+		ON CONFLICT (budget_id, month) DO UPDATE SET amount = EXCLUDED.amount`,
+		budget.ID, month, budget.Amount); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetBudgetLimits возвращает историю лимитов бюджета по возрастанию месяца
+func (r *PostgresRepository) GetBudgetLimits(budgetID int64) ([]*models.BudgetLimit, error) {
+	rows, err := r.db.Query("SELECT month, amount FROM budget_limits WHERE budget_id = $1 ORDER BY month", budgetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var limits []*models.BudgetLimit
+	for rows.Next() {
+		limit := &models.BudgetLimit{}
+		if err := rows.Scan(&limit.Month, &limit.Amount); err != nil {
+			return nil, err
+		}
+		limits = append(limits, limit)
+	}
+	return limits, rows.Err()
+}
+
+// DeleteBudget удаляет бюджет категории
+func (r *PostgresRepository) DeleteBudget(userID, categoryID int64) error {
+	_, err := r.db.Exec("DELETE FROM budgets WHERE user_id = $1 AND category_id = $2", userID, categoryID)
+	return err
+}
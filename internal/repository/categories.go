@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"finuchet-bot/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrDuplicate - запись нарушает ограничение уникальности
+	ErrDuplicate = errors.New("duplicate record")
+	// ErrNotFound - изменяемой записи нет или она принадлежит другому пользователю
+	ErrNotFound = errors.New("record not found")
+)
+
+const categoryColumns = "id, user_id, category, emoji, type, aliases, position, archived"
+
+func scanCategory(row interface{ Scan(...any) error }) (*models.Category, error) {
+	category := &models.Category{}
+	err := row.Scan(&category.ID, &category.UserID, &category.Name, &category.Emoji, &category.Type,
+		pq.Array(&category.Aliases), &category.Position, &category.Archived)
+	return category, err
+}
+
+// GetCategories возвращает категории пользователя в порядке отображения.
+// Пустой transactionType означает категории обоих типов.
+func (r *PostgresRepository) GetCategories(userID int64, transactionType string, withArchived bool) ([]*models.Category, error) {
+	rows, err := r.db.Query(`SELECT `+categoryColumns+` FROM user_categories
+		WHERE user_id = $1 AND ($2 = '' OR type = $2) AND ($3 OR NOT archived)
+		ORDER BY type, position, id`, userID, transactionType, withArchived)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*models.Category
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+// GetCategoryByID возвращает категорию, только если она принадлежит пользователю
+func (r *PostgresRepository) GetCategoryByID(userID, categoryID int64) (*models.Category, error) {
+	category, err := scanCategory(r.db.QueryRow(`SELECT `+categoryColumns+` FROM user_categories
+		WHERE id = $1 AND user_id = $2`, categoryID, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return category, err
+}
+
+// CreateCategory добавляет категорию в конец списка
+func (r *PostgresRepository) CreateCategory(category *models.Category) error {
+	err := r.db.QueryRow(`INSERT INTO user_categories (user_id, category, emoji, type, aliases, position)
+		VALUES ($1, $2, $3, $4, $5, (SELECT COALESCE(MAX(position), 0) + 1 FROM user_categories WHERE user_id = $1 AND type = $4))
+		RETURNING id, position`,
+		category.UserID, category.Name, category.Emoji, category.Type, stringArray(category.Aliases),
+	).Scan(&category.ID, &category.Position)
+	return duplicateError(err)
+}
+
+func (r *PostgresRepository) UpdateCategory(category *models.Category) error {
+	_, err := r.db.Exec(`UPDATE user_categories SET category = $3, emoji = $4, aliases = $5, archived = $6
+		WHERE id = $1 AND user_id = $2`,
+		category.ID, category.UserID, category.Name, category.Emoji, stringArray(category.Aliases), category.Archived)
+	return duplicateError(err)
+}
+
+// SetCategoryPositions проставляет порядок категорий согласно categoryIDs
+func (r *PostgresRepository) SetCategoryPositions(userID int64, categoryIDs []int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, id := range categoryIDs {
+		if _, err := tx.Exec("UPDATE user_categories SET position = $3 WHERE id = $1 AND user_id = $2", id, userID, i+1); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SeedCategories добавляет категории по умолчанию; уже существующие не изменяются
+func (r *PostgresRepository) SeedCategories(userID int64, categories []models.Category) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, c := range categories {
+		_, err := tx.Exec(`INSERT INTO user_categories (user_id, category, emoji, type, aliases, position)
+			VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (user_id, category, type) DO NOTHING`,
+			userID, c.Name, c.Emoji, c.Type, stringArray(c.Aliases), i+1)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Пустой список сохраняется как '{}', а не NULL
+func stringArray(values []string) interface{} {
+	if values == nil {
+		values = []string{}
+	}
+	return pq.Array(values)
+}
+
+// Нарушение ограничения уникальности превращается в ErrDuplicate
+func duplicateError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrDuplicate
+	}
+	return err
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"database/sql"
+	"finuchet-bot/internal/models"
+	"time"
+)
+
+// Сумма операций, засчитанных в погашение долга d
+const debtRepaid = `COALESCE((SELECT SUM(t.amount) FROM debt_payments AS dp
+		JOIN transactions AS t ON t.id = dp.transaction_id
+		WHERE dp.debt_id = d.id), 0)`
+
+// Долги вместе с суммой погашений и чатом учета
+const selectDebts = `SELECT d.id, d.user_id, u.chat_id, d.direction, d.counterparty, d.amount, d.currency,
+		d.debt_date, d.due_date, d.note, d.closed, ` + debtRepaid + `
+	FROM debts AS d
+	JOIN users AS u ON u.id = d.user_id`
+
+func scanDebt(row interface{ Scan(...any) error }) (*models.Debt, error) {
+	debt := &models.Debt{}
+	var due sql.NullTime
+	err := row.Scan(&debt.ID, &debt.UserID, &debt.ChatID, &debt.Direction, &debt.Counterparty, &debt.Amount, &debt.Currency,
+		&debt.Date, &due, &debt.Note, &debt.Closed, &debt.Repaid)
+	debt.DueDate = due.Time
+	return debt, err
+}
+
+func (r *PostgresRepository) queryDebts(query string, args ...any) ([]*models.Debt, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var debts []*models.Debt
+	for rows.Next() {
+		debt, err := scanDebt(rows)
+		if err != nil {
+			return nil, err
+		}
+		debts = append(debts, debt)
+	}
+	return debts, rows.Err()
+}
+
+// GetDebts возвращает долги пользователя: сначала с ближайшим сроком, долги без срока в конце.
+// Без withClosed - только открытые: не закрытые вручную и не погашенные полностью.
+func (r *PostgresRepository) GetDebts(userID int64, withClosed bool) ([]*models.Debt, error) {
+	return r.queryDebts(selectDebts+`
+		WHERE d.user_id = $1 AND ($2 OR (NOT d.closed AND d.amount > `+debtRepaid+`))
+		ORDER BY d.due_date NULLS LAST, d.debt_date, d.id`, userID, withClosed)
+}
+
+// GetDebt возвращает долг, только если он принадлежит пользователю
+func (r *PostgresRepository) GetDebt(userID, debtID int64) (*models.Debt, error) {
+	debt, err := scanDebt(r.db.QueryRow(selectDebts+" WHERE d.id = $1 AND d.user_id = $2", debtID, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return debt, err
+}
+
+// CreateDebt сохраняет новый долг
+func (r *PostgresRepository) CreateDebt(debt *models.Debt) error {
+	return r.db.QueryRow(`INSERT INTO debts (user_id, direction, counterparty, amount, currency, debt_date, due_date, note)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id`,
+		debt.UserID, debt.Direction, debt.Counterparty, debt.Amount, debt.Currency, debt.Date, nullDate(debt.DueDate), debt.Note,
+	).Scan(&debt.ID)
+}
+
+// UpdateDebt изменяет срок, заметку и закрытие долга. Новый срок снова включает напоминание о просрочке.
+func (r *PostgresRepository) UpdateDebt(debt *models.Debt) error {
+	_, err := r.db.Exec(`UPDATE debts SET
+			reminded_on = CASE WHEN due_date IS DISTINCT FROM $3 THEN NULL ELSE reminded_on END,
+			due_date = $3, note = $4, closed = $5
+		WHERE id = $1 AND user_id = $2`,
+		debt.ID, debt.UserID, nullDate(debt.DueDate), debt.Note, debt.Closed)
+	return err
+}
+
+// DeleteDebt удаляет долг; операции погашений остаются в истории
+func (r *PostgresRepository) DeleteDebt(userID, debtID int64) error {
+	_, err := r.db.Exec("DELETE FROM debts WHERE id = $1 AND user_id = $2", debtID, userID)
+	return err
+}
+
+// SetTransactionDebt засчитывает операцию в погашение долга; нулевой debtID отменяет погашение
+func (r *PostgresRepository) SetTransactionDebt(transactionID, debtID int64) error {
+	if debtID == 0 {
+		_, err := r.db.Exec("DELETE FROM debt_payments WHERE transaction_id = $1", transactionID)
+		return err
+	}
+	_, err := r.db.Exec(`INSERT INTO debt_payments (transaction_id, debt_id) VALUES ($1, $2)
+		ON CONFLICT (transaction_id) DO UPDATE SET debt_id = EXCLUDED.debt_id`, transactionID, debtID)
+	return err
+}
+
+// ClaimOverdueDebts отмечает напоминание и возвращает открытые долги, срок которых прошел
+// по местной дате пользователя на момент now. О долге напоминается раз в every дней, пока он открыт;
+// долг, забранный одним запуском планировщика, другой запуск в тот же день не получит.
+func (r *PostgresRepository) ClaimOverdueDebts(now time.Time, every int) ([]*models.Debt, error) {
+	return r.queryDebts(`WITH today AS (
+			SELECT u.id AS user_id, ($1::timestamptz AT TIME ZONE u.timezone)::date AS day FROM users AS u
+		), claimed AS (
+			UPDATE debts AS d SET reminded_on = today.day
+			FROM today
+			WHERE today.user_id = d.user_id
+			  AND NOT d.closed AND d.due_date < today.day
+			  AND (d.reminded_on IS NULL OR d.reminded_on <= today.day - $2::int)
+			  AND d.amount > `+debtRepaid+`
+			RETURNING d.id
+		)
+		`+selectDebts+`
+		WHERE d.id IN (SELECT id FROM claimed)
+		ORDER BY d.user_id, d.due_date, d.id`, now, every)
+}
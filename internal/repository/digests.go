@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"database/sql"
+	"finuchet-bot/internal/models"
+	"time"
+)
+
+// Подписки вместе с чатом и часовым поясом пользователя
+const selectDigests = `SELECT d.id, d.user_id, u.chat_id, u.timezone, d.frequency, d.send_minute, d.next_run, d.last_run
+	FROM digests AS d
+	JOIN users AS u ON u.id = d.user_id`
+
+func (r *PostgresRepository) queryDigests(query string, args ...any) ([]*models.Digest, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var digests []*models.Digest
+	for rows.Next() {
+		digest := &models.Digest{}
+		var lastRun sql.NullTime
+		if err := rows.Scan(&digest.ID, &digest.UserID, &digest.ChatID, &digest.Timezone,
+			&digest.Frequency, &digest.Minute, &digest.NextRun, &lastRun); err != nil {
+			return nil, err
+		}
+		digest.LastRun = lastRun.Time
+		digests = append(digests, digest)
+	}
+	return digests, rows.Err()
+}
+
+// GetDigests возвращает подписки пользователя на сводки
+func (r *PostgresRepository) GetDigests(userID int64) ([]*models.Digest, error) {
+	return r.queryDigests(selectDigests+`
+		WHERE d.user_id = $1
+		ORDER BY CASE d.frequency WHEN 'daily' THEN 1 WHEN 'weekly' THEN 2 ELSE 3 END`, userID)
+}
+
+// GetDueDigests возвращает сводки всех пользователей, время отправки которых наступило
+func (r *PostgresRepository) GetDueDigests(now time.Time) ([]*models.Digest, error) {
+	return r.queryDigests(selectDigests+`
+		WHERE d.next_run <= $1
+		ORDER BY d.next_run, d.id`, now)
+}
+
+// SetDigest создает или изменяет подписку пользователя на сводку той же периодичности
+func (r *PostgresRepository) SetDigest(digest *models.Digest) error {
+	return r.db.QueryRow(`INSERT INTO digests (user_id, frequency, send_minute, next_run)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, frequency) DO UPDATE SET
+			send_minute = EXCLUDED.send_minute,
+			next_run = EXCLUDED.next_run
+		RETURNING id`,
+		digest.UserID, digest.Frequency, digest.Minute, digest.NextRun,
+	).Scan(&digest.ID)
+}
+
+// DeleteDigest отменяет подписку на сводку
+func (r *PostgresRepository) DeleteDigest(userID int64, frequency string) error {
+	_, err := r.db.Exec("DELETE FROM digests WHERE user_id = $1 AND frequency = $2", userID, frequency)
+	return err
+}
+
+// ClaimDigest переносит отправку сводки на next и отмечает время now как последнюю отправку.
+// Возвращает false, если сводку уже забрал другой запуск планировщика или подписку изменили.
+func (r *PostgresRepository) ClaimDigest(digest *models.Digest, next, now time.Time) (bool, error) {
+	result, err := r.db.Exec("UPDATE digests SET next_run = $3, last_run = $4 WHERE id = $1 AND next_run = $2",
+		digest.ID, digest.NextRun, next, now)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"database/sql"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"time"
+)
+
+// Сумма взносов в цель g
+const goalSaved = `COALESCE((SELECT SUM(c.amount) FROM goal_contributions AS c WHERE c.goal_id = g.id), 0)`
+
+// Цели вместе с накопленной суммой
+const selectGoals = `SELECT g.id, g.user_id, g.name, g.target, g.currency, g.deadline, g.completed_at, ` + goalSaved + `
+	FROM goals AS g`
+
+func scanGoal(row interface{ Scan(...any) error }) (*models.Goal, error) {
+	goal := &models.Goal{}
+	var deadline, completed sql.NullTime
+	err := row.Scan(&goal.ID, &goal.UserID, &goal.Name, &goal.Target, &goal.Currency, &deadline, &completed, &goal.Saved)
+	goal.Deadline, goal.CompletedAt = deadline.Time, completed.Time
+	return goal, err
+}
+
+// GetGoals возвращает цели пользователя: сначала недостигнутые с ближайшим сроком, цели без срока
+// и достигнутые в конце
+func (r *PostgresRepository) GetGoals(userID int64) ([]*models.Goal, error) {
+	rows, err := r.db.Query(selectGoals+`
+		WHERE g.user_id = $1
+		ORDER BY g.completed_at IS NOT NULL, g.deadline NULLS LAST, g.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var goals []*models.Goal
+	for rows.Next() {
+		goal, err := scanGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, goal)
+	}
+	return goals, rows.Err()
+}
+
+// GetGoal возвращает цель, только если она принадлежит пользователю
+func (r *PostgresRepository) GetGoal(userID, goalID int64) (*models.Goal, error) {
+	goal, err := scanGoal(r.db.QueryRow(selectGoals+" WHERE g.id = $1 AND g.user_id = $2", goalID, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return goal, err
+}
+
+// CreateGoal сохраняет новую цель; цель с тем же названием вернет ErrDuplicate
+func (r *PostgresRepository) CreateGoal(goal *models.Goal) error {
+	err := r.db.QueryRow(`INSERT INTO goals (user_id, name, target, currency, deadline)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`,
+		goal.UserID, goal.Name, goal.Target, goal.Currency, nullDate(goal.Deadline),
+	).Scan(&goal.ID)
+	return duplicateError(err)
+}
+
+// UpdateGoal изменяет сумму и срок цели
+func (r *PostgresRepository) UpdateGoal(goal *models.Goal) error {
+	_, err := r.db.Exec("UPDATE goals SET target = $3, deadline = $4 WHERE id = $1 AND user_id = $2",
+		goal.ID, goal.UserID, goal.Target, nullDate(goal.Deadline))
+	return err
+}
+
+// DeleteGoal удаляет цель вместе со взносами; операции, из которых делались взносы, остаются
+func (r *PostgresRepository) DeleteGoal(userID, goalID int64) error {
+	_, err := r.db.Exec("DELETE FROM goals WHERE id = $1 AND user_id = $2", goalID, userID)
+	return err
+}
+
+// AddGoalContribution сохраняет взнос в цель
+func (r *PostgresRepository) AddGoalContribution(contribution *models.GoalContribution) error {
+	return r.db.QueryRow(`INSERT INTO goal_contributions (goal_id, amount, contribution_date, transaction_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`,
+		contribution.GoalID, contribution.Amount, contribution.Date, nullID(contribution.TransactionID),
+	).Scan(&contribution.ID)
+}
+
+// GetGoalContributions возвращает последние limit взносов в цель, новые первыми
+func (r *PostgresRepository) GetGoalContributions(goalID int64, limit int) ([]*models.GoalContribution, error) {
+	rows, err := r.db.Query(`SELECT id, goal_id, amount, contribution_date, COALESCE(transaction_id, 0)
+		FROM goal_contributions
+		WHERE goal_id = $1
+		ORDER BY contribution_date DESC, id DESC
+		LIMIT $2`, goalID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contributions []*models.GoalContribution
+	for rows.Next() {
+		c := &models.GoalContribution{}
+		if err := rows.Scan(&c.ID, &c.GoalID, &c.Amount, &c.Date, &c.TransactionID); err != nil {
+			return nil, err
+		}
+		contributions = append(contributions, c)
+	}
+	return contributions, rows.Err()
+}
+
+// DeleteGoalContribution удаляет взнос из цели
+func (r *PostgresRepository) DeleteGoalContribution(goalID, contributionID int64) error {
+	_, err := r.db.Exec("DELETE FROM goal_contributions WHERE id = $1 AND goal_id = $2", contributionID, goalID)
+	return err
+}
+
+// GetAllocatedAmount возвращает, сколько из операции уже внесено в цели
+func (r *PostgresRepository) GetAllocatedAmount(transactionID int64) (money.Amount, error) {
+	var amount money.Amount
+	err := r.db.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM goal_contributions WHERE transaction_id = $1",
+		transactionID).Scan(&amount)
+	return amount, err
+}
+
+// UpdateGoalCompletion отмечает цель достигнутой на момент now, если накоплено не меньше цели,
+// или снимает отметку, если накоплений снова не хватает. Сообщает, что цель достигнута только что.
+func (r *PostgresRepository) UpdateGoalCompletion(goalID int64, now time.Time) (bool, error) {
+	var completed bool
+	err := r.db.QueryRow(`UPDATE goals AS g
+		SET completed_at = CASE WHEN `+goalSaved+` >= g.target THEN COALESCE(g.completed_at, $2) END
+		FROM goals AS old
+		WHERE g.id = $1 AND old.id = g.id
+		RETURNING old.completed_at IS NULL AND g.completed_at IS NOT NULL`, goalID, now,
+	).Scan(&completed)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return completed, err
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"database/sql"
+	"finuchet-bot/internal/models"
+	"time"
+)
+
+// GetLedgerByChatID возвращает учет, который ведется из чата: общий, если он выбран
+// в личном чате, иначе собственный учет чата
+func (r *PostgresRepository) GetLedgerByChatID(chatID int64) (*models.User, error) {
+	return scanUser(r.db.QueryRow(`SELECT `+userColumns+` FROM users AS c
+		JOIN users AS u ON u.id = COALESCE(c.ledger_id, c.id)
+		WHERE c.chat_id = $1`, chatID))
+}
+
+// SetUserTitle сохраняет название группы для списка учетов
+func (r *PostgresRepository) SetUserTitle(userID int64, title string) error {
+	_, err := r.db.Exec("UPDATE users SET title = $2 WHERE id = $1", userID, title)
+	return err
+}
+
+// SetUserLedger выбирает общий учет для чата; нулевой ledgerID возвращает собственный
+func (r *PostgresRepository) SetUserLedger(userID, ledgerID int64) error {
+	_, err := r.db.Exec("UPDATE users SET ledger_id = $2 WHERE id = $1", userID, nullID(ledgerID))
+	return err
+}
+
+const memberColumns = "m.ledger_id, m.member_id, m.name, m.role"
+
+func scanMember(row interface{ Scan(...any) error }, extra ...any) (*models.Member, error) {
+	member := &models.Member{}
+	err := row.Scan(append([]any{&member.LedgerID, &member.ID, &member.Name, &member.Role}, extra...)...)
+	return member, err
+}
+
+func (r *PostgresRepository) queryMembers(query string, args ...any) ([]*models.Member, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*models.Member
+	for rows.Next() {
+		var title string
+		var chatID int64
+		member, err := scanMember(rows, &title, &chatID)
+		if err != nil {
+			return nil, err
+		}
+		member.LedgerTitle, member.LedgerChat = title, chatID
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// GetMember возвращает участника учета или nil, если он не участвует в учете
+func (r *PostgresRepository) GetMember(ledgerID, memberID int64) (*models.Member, error) {
+	member, err := scanMember(r.db.QueryRow(`SELECT `+memberColumns+` FROM ledger_members AS m
+		WHERE m.ledger_id = $1 AND m.member_id = $2`, ledgerID, memberID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return member, err
+}
+
+// GetMembers возвращает участников учета: владельца первым, остальных в порядке вступления
+func (r *PostgresRepository) GetMembers(ledgerID int64) ([]*models.Member, error) {
+	return r.queryMembers(`SELECT `+memberColumns+`, u.title, u.chat_id FROM ledger_members AS m
+		JOIN users AS u ON u.id = m.ledger_id
+		WHERE m.ledger_id = $1
+		ORDER BY m.role = 'owner' DESC, m.created_at, m.member_id`, ledgerID)
+}
+
+// GetMemberships возвращает учеты, в которых участвует человек, вместе с их названиями
+func (r *PostgresRepository) GetMemberships(memberID int64) ([]*models.Member, error) {
+	return r.queryMembers(`SELECT `+memberColumns+`, u.title, u.chat_id FROM ledger_members AS m
+		JOIN users AS u ON u.id = m.ledger_id
+		WHERE m.member_id = $1
+		ORDER BY u.chat_id = $1 DESC, m.created_at`, memberID)
+}
+
+// AddMember добавляет участника или меняет роль и имя уже вступившего; роль владельца не меняется
+func (r *PostgresRepository) AddMember(member *models.Member) error {
+	_, err := r.db.Exec(`INSERT INTO ledger_members (ledger_id, member_id, name, role)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (ledger_id, member_id) DO UPDATE SET name = EXCLUDED.name, role = EXCLUDED.role
+		WHERE ledger_members.role <> 'owner'`,
+		member.LedgerID, member.ID, member.Name, member.Role)
+	return err
+}
+
+// ClaimLedger делает участника владельцем учета, если у учета еще нет участников.
+// Сообщает, стал ли участник владельцем.
+func (r *PostgresRepository) ClaimLedger(member *models.Member) (bool, error) {
+	result, err := r.db.Exec(`INSERT INTO ledger_members (ledger_id, member_id, name, role)
+		SELECT $1, $2, $3, 'owner'
+		WHERE NOT EXISTS (SELECT 1 FROM ledger_members WHERE ledger_id = $1)
+		ON CONFLICT DO NOTHING`,
+		member.LedgerID, member.ID, member.Name)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// SetMemberName обновляет имя участника
+func (r *PostgresRepository) SetMemberName(ledgerID, memberID int64, name string) error {
+	_, err := r.db.Exec("UPDATE ledger_members SET name = $3 WHERE ledger_id = $1 AND member_id = $2",
+		ledgerID, memberID, name)
+	return err
+}
+
+// SetMemberRole изменяет роль участника
+func (r *PostgresRepository) SetMemberRole(ledgerID, memberID int64, role string) error {
+	_, err := r.db.Exec("UPDATE ledger_members SET role = $3 WHERE ledger_id = $1 AND member_id = $2",
+		ledgerID, memberID, role)
+	return err
+}
+
+// DeleteMember исключает участника из учета; его личный чат возвращается к собственному учету
+func (r *PostgresRepository) DeleteMember(ledgerID, memberID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM ledger_members WHERE ledger_id = $1 AND member_id = $2", ledgerID, memberID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE users SET ledger_id = NULL WHERE chat_id = $2 AND ledger_id = $1", ledgerID, memberID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateInvite сохраняет приглашение
+func (r *PostgresRepository) CreateInvite(invite *models.Invite) error {
+	_, err := r.db.Exec(`INSERT INTO ledger_invites (token, ledger_id, role, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		invite.Token, invite.LedgerID, invite.Role, invite.CreatedBy, invite.ExpiresAt)
+	return err
+}
+
+// TakeInvite забирает действующее на момент now приглашение: по ссылке можно вступить один раз.
+// Возвращает nil, если приглашения нет или оно истекло; истекшие приглашения удаляются.
+func (r *PostgresRepository) TakeInvite(token string, now time.Time) (*models.Invite, error) {
+	if _, err := r.db.Exec("DELETE FROM ledger_invites WHERE expires_at <= $1", now); err != nil {
+		return nil, err
+	}
+	invite := &models.Invite{}
+	err := r.db.QueryRow(`DELETE FROM ledger_invites WHERE token = $1
+		RETURNING token, ledger_id, role, created_by, expires_at`, token,
+	).Scan(&invite.Token, &invite.LedgerID, &invite.Role, &invite.CreatedBy, &invite.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return invite, err
+}
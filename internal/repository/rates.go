@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"database/sql"
+	"finuchet-bot/internal/models"
+	"time"
+)
+
+// Курс сохраняется вместе с обратным, чтобы пересчет работал в обе стороны
+const upsertRate = `INSERT INTO exchange_rates (user_id, currency, quote, rate_date, rate)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (COALESCE(user_id, 0), currency, quote, rate_date) DO UPDATE SET rate = EXCLUDED.rate`
+
+// SetRate сохраняет курс на дату и обратный к нему
+func (r *PostgresRepository) SetRate(rate *models.ExchangeRate) error {
+	return r.SaveRates([]*models.ExchangeRate{rate})
+}
+
+// SaveRates сохраняет курсы и обратные к ним в одной транзакции
+func (r *PostgresRepository) SaveRates(rates []*models.ExchangeRate) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(upsertRate)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, rate := range rates {
+		if _, err := stmt.Exec(nullID(rate.UserID), rate.Currency, rate.Quote, rate.Date, rate.Rate); err != nil {
+			return err
+		}
+		if _, err := stmt.Exec(nullID(rate.UserID), rate.Quote, rate.Currency, rate.Date, 1/rate.Rate); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetRate возвращает курс code к quote, ближайший к дате (как при пересчете отчетов)
+func (r *PostgresRepository) GetRate(userID int64, code, quote string, date time.Time) (float64, bool, error) {
+	var rate float64
+	err := r.db.QueryRow(`SELECT rate FROM exchange_rates
+		WHERE currency = $2 AND quote = $3 AND (user_id IS NULL OR user_id = $1)
+		ORDER BY rate_date <= $4::date DESC, ABS(rate_date - $4::date), user_id IS NULL
+		LIMIT 1`, userID, code, quote, date).Scan(&rate)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return rate, err == nil, err
+}
+
+// GetLatestRates возвращает последний известный курс каждой валюты к quote
+func (r *PostgresRepository) GetLatestRates(userID int64, quote string) ([]*models.ExchangeRate, error) {
+	rows, err := r.db.Query(`SELECT DISTINCT ON (currency) COALESCE(user_id, 0), currency, quote, rate_date, rate
+		FROM exchange_rates
+		WHERE quote = $2 AND (user_id IS NULL OR user_id = $1)
+		ORDER BY currency, rate_date DESC, user_id IS NULL`, userID, quote)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []*models.ExchangeRate
+	for rows.Next() {
+		rate := &models.ExchangeRate{}
+		if err := rows.Scan(&rate.UserID, &rate.Currency, &rate.Quote, &rate.Date, &rate.Rate); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, rows.Err()
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"database/sql"
+	"finuchet-bot/internal/models"
+	"time"
+)
+
+// Правила вместе с названием категории, чатом и часовым поясом владельца
+const selectRecurringRules = `SELECT r.id, r.user_id, u.chat_id, u.timezone, COALESCE(r.category_id, 0), COALESCE(c.category, ''), COALESCE(c.emoji, ''),
+		r.type, r.amount, r.currency, r.note, r.frequency, COALESCE(r.day_of_month, 0), r.start_date, r.end_date, r.next_date, r.paused
+	FROM recurring_rules AS r
+	JOIN users AS u ON u.id = r.user_id
+	LEFT JOIN user_categories AS c ON c.id = r.category_id`
+
+func scanRecurringRule(row interface{ Scan(...any) error }) (*models.RecurringRule, error) {
+	rule := &models.RecurringRule{}
+	var endDate, nextDate sql.NullTime
+	err := row.Scan(&rule.ID, &rule.UserID, &rule.ChatID, &rule.Timezone, &rule.CategoryID, &rule.Category, &rule.Emoji,
+		&rule.Type, &rule.Amount, &rule.Currency, &rule.Note, &rule.Frequency, &rule.DayOfMonth,
+		&rule.StartDate, &endDate, &nextDate, &rule.Paused)
+	rule.EndDate = endDate.Time
+	rule.NextDate = nextDate.Time
+	return rule, err
+}
+
+func (r *PostgresRepository) queryRecurringRules(query string, args ...any) ([]*models.RecurringRule, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*models.RecurringRule
+	for rows.Next() {
+		rule, err := scanRecurringRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// GetRecurringRules возвращает правила пользователя: сначала действующие, по дате следующей операции
+func (r *PostgresRepository) GetRecurringRules(userID int64) ([]*models.RecurringRule, error) {
+	return r.queryRecurringRules(selectRecurringRules+`
+		WHERE r.user_id = $1
+		ORDER BY r.paused, r.next_date IS NULL, r.next_date, r.id`, userID)
+}
+
+// GetRecurringRule возвращает правило, только если оно принадлежит пользователю
+func (r *PostgresRepository) GetRecurringRule(userID, ruleID int64) (*models.RecurringRule, error) {
+	rule, err := scanRecurringRule(r.db.QueryRow(selectRecurringRules+" WHERE r.id = $1 AND r.user_id = $2", ruleID, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return rule, err
+}
+
+// GetDueRecurringRules возвращает действующие правила всех пользователей с операциями
+// не позже сегодняшнего дня по часовому поясу владельца на момент now
+func (r *PostgresRepository) GetDueRecurringRules(now time.Time) ([]*models.RecurringRule, error) {
+	return r.queryRecurringRules(selectRecurringRules+`
+		WHERE NOT r.paused AND r.next_date <= ($1::timestamptz AT TIME ZONE u.timezone)::date
+		ORDER BY r.next_date, r.id`, now)
+}
+
+// CreateRecurringRule сохраняет новое правило
+func (r *PostgresRepository) CreateRecurringRule(rule *models.RecurringRule) error {
+	return r.db.QueryRow(`INSERT INTO recurring_rules
+			(user_id, category_id, type, amount, currency, note, frequency, day_of_month, start_date, end_date, next_date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id`,
+		rule.UserID, nullID(rule.CategoryID), rule.Type, rule.Amount, rule.Currency, rule.Note, rule.Frequency,
+		sql.NullInt64{Int64: int64(rule.DayOfMonth), Valid: rule.DayOfMonth != 0},
+		rule.StartDate, nullDate(rule.EndDate), nullDate(rule.NextDate),
+	).Scan(&rule.ID)
+}
+
+// SetRecurringRuleState приостанавливает или возобновляет правило и задает дату следующей операции
+func (r *PostgresRepository) SetRecurringRuleState(userID, ruleID int64, paused bool, next time.Time) error {
+	_, err := r.db.Exec("UPDATE recurring_rules SET paused = $3, next_date = $4 WHERE id = $1 AND user_id = $2",
+		ruleID, userID, paused, nullDate(next))
+	return err
+}
+
+// DeleteRecurringRule удаляет правило; созданные по нему операции остаются
+func (r *PostgresRepository) DeleteRecurringRule(userID, ruleID int64) error {
+	_, err := r.db.Exec("DELETE FROM recurring_rules WHERE id = $1 AND user_id = $2", ruleID, userID)
+	return err
+}
+
+// ApplyRecurringRule в одной транзакции создает операции по правилу и переносит его
+// следующую дату на next. Если правило уже обработано другим запуском (next_date изменилась),
+// ничего не делает. Операция на дату, которая уже есть у правила, не создается повторно.
+// Возвращает созданные операции.
+func (r *PostgresRepository) ApplyRecurringRule(rule *models.RecurringRule, transactions []*models.Transaction, next time.Time) ([]*models.Transaction, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var current sql.NullTime
+	err = tx.QueryRow("SELECT next_date FROM recurring_rules WHERE id = $1 AND NOT paused FOR UPDATE", rule.ID).Scan(&current)
+	if err == sql.ErrNoRows || err == nil && (!current.Valid || !current.Time.Equal(rule.NextDate)) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO transactions (user_id, amount, currency, category_id, type, create_dat, note, recurring_id, account_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, ` + accountOrDefault(9) + `)
+		ON CONFLICT (recurring_id, create_dat) WHERE recurring_id IS NOT NULL DO NOTHING
+		RETURNING id`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var created []*models.Transaction
+	for _, t := range transactions {
+		err := stmt.QueryRow(t.UserID, t.Amount, t.Currency, nullID(t.CategoryID), t.Type, t.Date, t.Note, rule.ID, nullID(t.AccountID)).Scan(&t.ID)
+		if err == sql.ErrNoRows {
+			continue // Операция на эту дату уже создана
+		}
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, t)
+	}
+
+	if _, err := tx.Exec("UPDATE recurring_rules SET next_date = $2 WHERE id = $1", rule.ID, nullDate(next)); err != nil {
+		return nil, err
+	}
+	return created, tx.Commit()
+}
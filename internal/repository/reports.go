@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"finuchet-bot/internal/models"
+	"fmt"
+	"time"
+)
+
+// Сумма операции t в базовой валюте пользователя u (см. amountIn)
+var convertedAmount = amountIn("u.base_currency")
+
+// Сумма операции t в валюте quote по курсу на ближайшую дату: сначала
+// не позже даты операции, при равенстве дат курс пользователя важнее общего.
+// Пересчитанная сумма округляется до копеек до суммирования, чтобы итоги были точными.
+// NULL, если курса нет; такие операции не попадают в суммы (см. GetMissingRates).
+func amountIn(quote string) string {
+	return `(CASE WHEN t.currency = ` + quote + ` THEN t.amount ELSE ROUND(t.amount * (
+		SELECT r.rate FROM exchange_rates AS r
+		WHERE r.currency = t.currency AND r.quote = ` + quote + `
+		  AND (r.user_id IS NULL OR r.user_id = t.user_id)
+		ORDER BY r.rate_date <= t.create_dat DESC, ABS(r.rate_date - t.create_dat), r.user_id IS NULL
+		LIMIT 1), 2) END)`
+}
+
+// Условие отбора операций t по тегу и продавцу из фильтра; n - номер первого из двух параметров,
+// которые добавляет filterArgs
+func filterCondition(n int) string {
+	return fmt.Sprintf(`
+		  AND ($%d = '' OR EXISTS (SELECT 1 FROM transaction_tags AS tt WHERE tt.transaction_id = t.id AND tt.tag = $%d))
+		  AND ($%d = '' OR lower(t.merchant) = lower($%d))`, n, n, n+1, n+1)
+}
+
+func filterArgs(filter models.TransactionFilter) []any {
+	return []any{filter.Tag, filter.Merchant}
+}
+
+// GetDailyTotals возвращает суммы отобранных операций в базовой валюте по дням и типам за период [from, to).
+// Нулевые from и to снимают ограничение с соответствующей стороны.
+func (r *PostgresRepository) GetDailyTotals(userID int64, from, to time.Time, filter models.TransactionFilter) ([]*models.DateTotal, error) {
+	return r.queryDateTotals("t.create_dat", filterCondition(4), userID, from, to, filterArgs(filter)...)
+}
+
+// GetMonthlyTotals возвращает суммы операций в базовой валюте по месяцам и типам за период [from, to)
+func (r *PostgresRepository) GetMonthlyTotals(userID int64, from, to time.Time) ([]*models.DateTotal, error) {
+	return r.queryDateTotals("date_trunc('month', t.create_dat)::date", "", userID, from, to)
+}
+
+// GetCategoryMonthlyTotals возвращает суммы операций категории в базовой валюте по месяцам за период [from, to)
+func (r *PostgresRepository) GetCategoryMonthlyTotals(userID, categoryID int64, from, to time.Time) ([]*models.DateTotal, error) {
+	return r.queryDateTotals("date_trunc('month', t.create_dat)::date", " AND t.category_id = $4", userID, from, to, categoryID)
+}
+
+// Суммы операций, сгруппированные по выражению над датой; condition дополняет условие WHERE
+// и может ссылаться на args начиная с $4
+func (r *PostgresRepository) queryDateTotals(dateExpr, condition string, userID int64, from, to time.Time, args ...any) ([]*models.DateTotal, error) {
+	rows, err := r.db.Query(`SELECT `+dateExpr+` AS day, t.type, COALESCE(SUM(`+convertedAmount+`), 0), COUNT(*)
+		FROM transactions AS t
+		JOIN users AS u ON u.id = t.user_id
+		WHERE t.user_id = $1
+		  AND ($2::date IS NULL OR t.create_dat >= $2::date)
+		  AND ($3::date IS NULL OR t.create_dat < $3::date)`+condition+`
+		GROUP BY day, t.type
+		ORDER BY day, t.type`, append([]any{userID, nullDate(from), nullDate(to)}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []*models.DateTotal
+	for rows.Next() {
+		total := &models.DateTotal{}
+		if err := rows.Scan(&total.Date, &total.Type, &total.Amount, &total.Count); err != nil {
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
+
+// GetCategoryTotals возвращает суммы отобранных операций в базовой валюте по категориям за период [from, to),
+// по убыванию суммы внутри каждого типа
+func (r *PostgresRepository) GetCategoryTotals(userID int64, from, to time.Time, filter models.TransactionFilter) ([]*models.CategoryTotal, error) {
+	rows, err := r.db.Query(`SELECT COALESCE(t.category_id, 0), COALESCE(c.category, ''), COALESCE(c.emoji, ''), t.type,
+			COALESCE(SUM(`+convertedAmount+`), 0) AS total, COUNT(*)
+		FROM transactions AS t
+		JOIN users AS u ON u.id = t.user_id
+		LEFT JOIN user_categories AS c ON c.id = t.category_id
+		WHERE t.user_id = $1
+		  AND ($2::date IS NULL OR t.create_dat >= $2::date)
+		  AND ($3::date IS NULL OR t.create_dat < $3::date)`+filterCondition(4)+`
+		GROUP BY t.category_id, c.category, c.emoji, t.type
+		ORDER BY t.type, total DESC, c.category`, append([]any{userID, nullDate(from), nullDate(to)}, filterArgs(filter)...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []*models.CategoryTotal
+	for rows.Next() {
+		total := &models.CategoryTotal{}
+		if err := rows.Scan(&total.CategoryID, &total.Category, &total.Emoji, &total.Type, &total.Amount, &total.Count); err != nil {
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
+
+// GetLargestTransactions возвращает limit самых крупных в пересчете на базовую валюту
+// отобранных операций типа за период [from, to)
+func (r *PostgresRepository) GetLargestTransactions(userID int64, transactionType string, from, to time.Time, filter models.TransactionFilter, limit int) ([]*models.Transaction, error) {
+	return r.queryTransactions(selectTransactions+`
+		JOIN users AS u ON u.id = t.user_id
+		WHERE t.user_id = $1 AND t.type = $2
+		  AND ($3::date IS NULL OR t.create_dat >= $3::date)
+		  AND ($4::date IS NULL OR t.create_dat < $4::date)`+filterCondition(6)+`
+		ORDER BY `+convertedAmount+` DESC NULLS LAST, t.create_dat DESC, t.id DESC
+		LIMIT $5`, append([]any{userID, transactionType, nullDate(from), nullDate(to), limit}, filterArgs(filter)...)...)
+}
+
+// GetCurrencyTotals возвращает суммы отобранных операций в исходных валютах за период [from, to)
+func (r *PostgresRepository) GetCurrencyTotals(userID int64, from, to time.Time, filter models.TransactionFilter) ([]*models.CurrencyTotal, error) {
+	rows, err := r.db.Query(`SELECT t.currency, t.type, SUM(t.amount), COUNT(*)
+		FROM transactions AS t
+		WHERE t.user_id = $1
+		  AND ($2::date IS NULL OR t.create_dat >= $2::date)
+		  AND ($3::date IS NULL OR t.create_dat < $3::date)`+filterCondition(4)+`
+		GROUP BY t.currency, t.type
+		ORDER BY t.currency, t.type`, append([]any{userID, nullDate(from), nullDate(to)}, filterArgs(filter)...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []*models.CurrencyTotal
+	for rows.Next() {
+		total := &models.CurrencyTotal{}
+		if err := rows.Scan(&total.Currency, &total.Type, &total.Amount, &total.Count); err != nil {
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
+
+// GetMemberTotals возвращает суммы отобранных операций в базовой валюте по участникам, записавшим их,
+// за период [from, to), по убыванию суммы внутри каждого типа
+func (r *PostgresRepository) GetMemberTotals(userID int64, from, to time.Time, filter models.TransactionFilter) ([]*models.MemberTotal, error) {
+	rows, err := r.db.Query(`SELECT COALESCE(t.member_id, 0), COALESCE(m.name, ''), t.type,
+			COALESCE(SUM(`+convertedAmount+`), 0) AS total, COUNT(*)
+		FROM transactions AS t
+		JOIN users AS u ON u.id = t.user_id
+		LEFT JOIN ledger_members AS m ON m.ledger_id = t.user_id AND m.member_id = t.member_id
+		WHERE t.user_id = $1
+		  AND ($2::date IS NULL OR t.create_dat >= $2::date)
+		  AND ($3::date IS NULL OR t.create_dat < $3::date)`+filterCondition(4)+`
+		GROUP BY t.member_id, m.name, t.type
+		ORDER BY t.type, total DESC, m.name`, append([]any{userID, nullDate(from), nullDate(to)}, filterArgs(filter)...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []*models.MemberTotal
+	for rows.Next() {
+		total := &models.MemberTotal{}
+		if err := rows.Scan(&total.MemberID, &total.Name, &total.Type, &total.Amount, &total.Count); err != nil {
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
+
+// GetMissingRates возвращает валюты отобранных операций за период [from, to), для которых нет курса к базовой валюте
+func (r *PostgresRepository) GetMissingRates(userID int64, from, to time.Time, filter models.TransactionFilter) ([]string, error) {
+	return r.queryStrings(`SELECT DISTINCT t.currency
+		FROM transactions AS t
+		JOIN users AS u ON u.id = t.user_id
+		WHERE t.user_id = $1
+		  AND ($2::date IS NULL OR t.create_dat >= $2::date)
+		  AND ($3::date IS NULL OR t.create_dat < $3::date)`+filterCondition(4)+`
+		  AND `+convertedAmount+` IS NULL
+		ORDER BY t.currency`, append([]any{userID, nullDate(from), nullDate(to)}, filterArgs(filter)...)...)
+}
+
+// Значения первой колонки запроса
+func (r *PostgresRepository) queryStrings(query string, args ...any) ([]string, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// GetCategoryMonthTotals возвращает суммы операций в базовой валюте по месяцам, категориям и типам
+// за период [from, to), отдельно для операций повторяющихся правил
+func (r *PostgresRepository) GetCategoryMonthTotals(userID int64, from, to time.Time) ([]*models.CategoryMonthTotal, error) {
+	rows, err := r.db.Query(`SELECT date_trunc('month', t.create_dat)::date AS month,
+			COALESCE(t.category_id, 0), COALESCE(c.category, ''), COALESCE(c.emoji, ''), t.type,
+			COALESCE(SUM(`+convertedAmount+`) FILTER (WHERE t.recurring_id IS NULL), 0),
+			COALESCE(SUM(`+convertedAmount+`) FILTER (WHERE t.recurring_id IS NOT NULL), 0)
+		FROM transactions AS t
+		JOIN users AS u ON u.id = t.user_id
+		LEFT JOIN user_categories AS c ON c.id = t.category_id
+		WHERE t.user_id = $1
+		  AND ($2::date IS NULL OR t.create_dat >= $2::date)
+		  AND ($3::date IS NULL OR t.create_dat < $3::date)
+		GROUP BY month, t.category_id, c.category, c.emoji, t.type
+		ORDER BY month, t.type, c.category`, userID, nullDate(from), nullDate(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []*models.CategoryMonthTotal
+	for rows.Next() {
+		total := &models.CategoryMonthTotal{}
+		if err := rows.Scan(&total.Month, &total.CategoryID, &total.Category, &total.Emoji, &total.Type, &total.Amount, &total.Recurring); err != nil {
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
@@ -3,14 +3,112 @@ package repository
 import (
 	"database/sql"
 	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"time"
 )
 
 type Repository interface {
 	GetUserByChatID(chatID int64) (*models.User, error)
+	GetLedgerByChatID(chatID int64) (*models.User, error)
 	CreateUser(user *models.User) error
+	SetUserTitle(userID int64, title string) error
+	SetUserLedger(userID, ledgerID int64) error
+	SetBaseCurrency(userID int64, code string) error
+	SetTimezone(userID int64, name string) error
+	GetBaseCurrencies() ([]string, error)
 	AddTransaction(transaction *models.Transaction) error
 	DelData(chatID int64) error
 	GetTransactions(userID int64) ([]*models.Transaction, error)
+	GetRecentTransactions(userID int64, limit, offset int) ([]*models.Transaction, error)
+	SearchTransactions(userID int64, query string, limit int) ([]*models.Transaction, error)
+	GetTransactionByID(userID, transactionID int64) (*models.Transaction, error)
+	UpdateTransaction(transaction *models.Transaction) error
+	DeleteTransaction(userID, transactionID int64) error
+	IterateTransactions(userID int64, from, to time.Time, fn func(*models.Transaction) error) error
+	BulkInsertTransactions(transactions []*models.Transaction) (int, error)
+	GetDailyTotals(userID int64, from, to time.Time, filter models.TransactionFilter) ([]*models.DateTotal, error)
+	GetMonthlyTotals(userID int64, from, to time.Time) ([]*models.DateTotal, error)
+	GetCategoryTotals(userID int64, from, to time.Time, filter models.TransactionFilter) ([]*models.CategoryTotal, error)
+	GetLargestTransactions(userID int64, transactionType string, from, to time.Time, filter models.TransactionFilter, limit int) ([]*models.Transaction, error)
+	GetCategoryMonthlyTotals(userID, categoryID int64, from, to time.Time) ([]*models.DateTotal, error)
+	GetCurrencyTotals(userID int64, from, to time.Time, filter models.TransactionFilter) ([]*models.CurrencyTotal, error)
+	GetMissingRates(userID int64, from, to time.Time, filter models.TransactionFilter) ([]string, error)
+	GetMemberTotals(userID int64, from, to time.Time, filter models.TransactionFilter) ([]*models.MemberTotal, error)
+	GetCategoryMonthTotals(userID int64, from, to time.Time) ([]*models.CategoryMonthTotal, error)
+
+	GetMember(ledgerID, memberID int64) (*models.Member, error)
+	GetMembers(ledgerID int64) ([]*models.Member, error)
+	GetMemberships(memberID int64) ([]*models.Member, error)
+	AddMember(member *models.Member) error
+	ClaimLedger(member *models.Member) (bool, error)
+	SetMemberName(ledgerID, memberID int64, name string) error
+	SetMemberRole(ledgerID, memberID int64, role string) error
+	DeleteMember(ledgerID, memberID int64) error
+	CreateInvite(invite *models.Invite) error
+	TakeInvite(token string, now time.Time) (*models.Invite, error)
+
+	GetAccounts(userID int64, withArchived bool) ([]*models.Account, error)
+	GetAccountByID(userID, accountID int64) (*models.Account, error)
+	GetDefaultAccount(userID int64) (*models.Account, error)
+	CreateAccount(account *models.Account) error
+	UpdateAccount(account *models.Account) error
+	SetDefaultAccount(userID, accountID int64) error
+	SeedAccount(account *models.Account) error
+	GetAccountBalances(userID int64, to time.Time) ([]*models.Account, error)
+	AddTransfer(transfer *models.Transfer) error
+	GetRecentTransfers(userID int64, limit int) ([]*models.Transfer, error)
+	DeleteTransfer(userID, transferID int64) error
+
+	GetDebts(userID int64, withClosed bool) ([]*models.Debt, error)
+	GetDebt(userID, debtID int64) (*models.Debt, error)
+	CreateDebt(debt *models.Debt) error
+	UpdateDebt(debt *models.Debt) error
+	DeleteDebt(userID, debtID int64) error
+	SetTransactionDebt(transactionID, debtID int64) error
+	ClaimOverdueDebts(now time.Time, every int) ([]*models.Debt, error)
+
+	GetGoals(userID int64) ([]*models.Goal, error)
+	GetGoal(userID, goalID int64) (*models.Goal, error)
+	CreateGoal(goal *models.Goal) error
+	UpdateGoal(goal *models.Goal) error
+	DeleteGoal(userID, goalID int64) error
+	AddGoalContribution(contribution *models.GoalContribution) error
+	GetGoalContributions(goalID int64, limit int) ([]*models.GoalContribution, error)
+	DeleteGoalContribution(goalID, contributionID int64) error
+	GetAllocatedAmount(transactionID int64) (money.Amount, error)
+	UpdateGoalCompletion(goalID int64, now time.Time) (bool, error)
+
+	SetRate(rate *models.ExchangeRate) error
+	SaveRates(rates []*models.ExchangeRate) error
+	GetRate(userID int64, code, quote string, date time.Time) (float64, bool, error)
+	GetLatestRates(userID int64, quote string) ([]*models.ExchangeRate, error)
+
+	GetBudgets(userID int64) ([]*models.Budget, error)
+	GetBudget(userID, categoryID int64) (*models.Budget, error)
+	SetBudget(budget *models.Budget) error
+	GetBudgetLimits(budgetID int64) ([]*models.BudgetLimit, error)
+	DeleteBudget(userID, categoryID int64) error
+
+	GetRecurringRules(userID int64) ([]*models.RecurringRule, error)
+	GetRecurringRule(userID, ruleID int64) (*models.RecurringRule, error)
+	GetDueRecurringRules(now time.Time) ([]*models.RecurringRule, error)
+	CreateRecurringRule(rule *models.RecurringRule) error
+	SetRecurringRuleState(userID, ruleID int64, paused bool, next time.Time) error
+	DeleteRecurringRule(userID, ruleID int64) error
+	ApplyRecurringRule(rule *models.RecurringRule, transactions []*models.Transaction, next time.Time) ([]*models.Transaction, error)
+
+	GetDigests(userID int64) ([]*models.Digest, error)
+	GetDueDigests(now time.Time) ([]*models.Digest, error)
+	SetDigest(digest *models.Digest) error
+	DeleteDigest(userID int64, frequency string) error
+	ClaimDigest(digest *models.Digest, next, now time.Time) (bool, error)
+
+	GetCategories(userID int64, transactionType string, withArchived bool) ([]*models.Category, error)
+	GetCategoryByID(userID, categoryID int64) (*models.Category, error)
+	CreateCategory(category *models.Category) error
+	UpdateCategory(category *models.Category) error
+	SetCategoryPositions(userID int64, categoryIDs []int64) error
+	SeedCategories(userID int64, categories []models.Category) error
 }
 
 type PostgresRepository struct {
@@ -21,55 +119,97 @@ func NewPostgresRepository(db *sql.DB) Repository {
 	return &PostgresRepository{db: db}
 }
 
-func (r *PostgresRepository) GetUserByChatID(chatID int64) (*models.User, error) {
+const userColumns = "u.id, u.chat_id, u.base_currency, u.timezone, u.title, COALESCE(u.ledger_id, 0)"
+
+func scanUser(row interface{ Scan(...any) error }) (*models.User, error) {
 	user := &models.User{}
-	err := r.db.QueryRow("SELECT id, chat_id FROM users WHERE chat_id = $1", chatID).Scan(&user.ID, &user.ChatID)
+	err := row.Scan(&user.ID, &user.ChatID, &user.BaseCurrency, &user.Timezone, &user.Title, &user.LedgerID)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	return user, err
 }
 
+// GetUserByChatID возвращает собственный учет чата
+func (r *PostgresRepository) GetUserByChatID(chatID int64) (*models.User, error) {
+	return scanUser(r.db.QueryRow("SELECT "+userColumns+" FROM users AS u WHERE u.chat_id = $1", chatID))
+}
+
 func (r *PostgresRepository) CreateUser(user *models.User) error {
 	_, err := r.db.Exec("INSERT INTO users (chat_id) VALUES ($1) ON CONFLICT DO NOTHING", user.ChatID)
 	return err
 }
 
+// AddTransaction сохраняет операцию вместе с тегами и погашением долга
 func (r *PostgresRepository) AddTransaction(transaction *models.Transaction) error {
-	_, err := r.db.Exec("WITH ins0 AS (
-							INSERT INTO user_categories (user_id, category, type)
-							VALUES ($1, 'book', 'expense')
-							RETURNING id, type
-							)
-							INSERT INTO transactions (category_id, type, user_id, amount)
-							SELECT id, type, '1', 543
-							FROM   ins0 ON CONFLICT DO NOTHING
-	INSERT INTO transactions (user_id, amount, category, type) VALUES ($1, $2, $3, $4)",
-		transaction.UserID, transaction.Amount, transaction.Category, transaction.Type)
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("INSERT INTO transactions (user_id, amount, currency, category_id, type, create_dat, note, merchant, account_id, member_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, "+accountOrDefault(9)+", $10) RETURNING id",
+		transaction.UserID, transaction.Amount, transaction.Currency, nullID(transaction.CategoryID), transaction.Type, transaction.Date, transaction.Note, transaction.Merchant,
+		nullID(transaction.AccountID), nullID(transaction.MemberID),
+	).Scan(&transaction.ID)
+	if err != nil {
+		return err
+	}
+	if err := insertTags(tx, transaction); err != nil {
+		return err
+	}
+	if transaction.DebtID != 0 {
+		if _, err := tx.Exec("INSERT INTO debt_payments (transaction_id, debt_id) VALUES ($1, $2)", transaction.ID, transaction.DebtID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SetTimezone изменяет часовой пояс пользователя
+func (r *PostgresRepository) SetTimezone(userID int64, name string) error {
+	_, err := r.db.Exec("UPDATE users SET timezone = $2 WHERE id = $1", userID, name)
 	return err
 }
 
-func (r *PostgresRepository) DelData(userID int64) error {
-	_, err := r.db.Exec("DELETE FROM transactions WHERE user_id = $1", userID)
+// SetBaseCurrency изменяет валюту отчетов пользователя
+func (r *PostgresRepository) SetBaseCurrency(userID int64, code string) error {
+	_, err := r.db.Exec("UPDATE users SET base_currency = $2 WHERE id = $1", userID, code)
 	return err
 }
 
-func (r *PostgresRepository) GetTransactions(userID int64) ([]*models.Transaction, error) {
-	rows, err := r.db.Query("SELECT id, user_id, amount, category, type, created_at FROM transactions WHERE user_id = $1", userID)
-	// rows, err := r.db.Query("SELECT tr.id, tr.user_id, tr.amount, uc.category, uc.type, tr.create_at FROM transactions as tr JOIN user_categories as uc ON uc.user_id = tr.user_id WHERE user_id = $1", userID)
+// GetBaseCurrencies возвращает базовые валюты всех пользователей
+func (r *PostgresRepository) GetBaseCurrencies() ([]string, error) {
+	return r.queryStrings("SELECT DISTINCT base_currency FROM users ORDER BY base_currency")
+}
+
+// DelData удаляет операции и переводы пользователя; счета и их начальные остатки сохраняются
+func (r *PostgresRepository) DelData(userID int64) error {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
-
-	var transactions []*models.Transaction
-	for rows.Next() {
-		transaction := &models.Transaction{}
-		err = rows.Scan(&transaction.ID, &transaction.UserID, &transaction.Amount, &transaction.Category, &transaction.Type, &transaction.CreatedAt)
-		if err != nil {
-			return nil, err
-		}
-		transactions = append(transactions, transaction)
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM transfers WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM transactions WHERE user_id = $1", userID); err != nil {
+		return err
 	}
-	return transactions, nil
+	return tx.Commit()
+}
+
+func (r *PostgresRepository) GetTransactions(userID int64) ([]*models.Transaction, error) {
+	return r.queryTransactions(selectTransactions+" WHERE t.user_id = $1", userID)
+}
+
+// Пустая дата передается как NULL
+func nullDate(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// Нулевой идентификатор передается как NULL
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
 }
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"finuchet-bot/internal/models"
+	"time"
+)
+
+// StateStore хранит состояния диалогов, чтобы они переживали перезапуск бота
+// и были общими для нескольких его экземпляров.
+// Диалог определяется чатом и участником: в группе у каждого участника свой диалог,
+// memberID = 0 - диалог всего чата (личный чат).
+// Брошенные диалоги удаляются по истечении TTL.
+type StateStore interface {
+	GetState(chatID, memberID int64) (*models.ChatState, error) // nil, если состояния нет или оно истекло
+	SetState(chatID, memberID int64, state *models.ChatState) error
+	DeleteState(chatID, memberID int64) error
+	Close() error // Освобождает ресурсы хранилища при остановке бота
+}
+
+type PostgresStateStore struct {
+	db  *sql.DB
+	ttl time.Duration
+}
+
+func NewPostgresStateStore(db *sql.DB, ttl time.Duration) *PostgresStateStore {
+	return &PostgresStateStore{db: db, ttl: ttl}
+}
+
+func (s *PostgresStateStore) GetState(chatID, memberID int64) (*models.ChatState, error) {
+	var data []byte
+	err := s.db.QueryRow("SELECT data FROM chat_states WHERE chat_id = $1 AND member_id = $2 AND expires_at > NOW()",
+		chatID, memberID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &models.ChatState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (s *PostgresStateStore) SetState(chatID, memberID int64, state *models.ChatState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO chat_states (chat_id, member_id, data, expires_at)
+		VALUES ($1, $2, $3, NOW() + $4 * INTERVAL '1 second')
+		ON CONFLICT (chat_id, member_id) DO UPDATE
+		SET data = EXCLUDED.data, expires_at = EXCLUDED.expires_at, updated_at = CURRENT_TIMESTAMP`,
+		chatID, memberID, data, s.ttl.Seconds())
+	return err
+}
+
+func (s *PostgresStateStore) DeleteState(chatID, memberID int64) error {
+	_, err := s.db.Exec("DELETE FROM chat_states WHERE chat_id = $1 AND member_id = $2", chatID, memberID)
+	return err
+}
+
+// Соединение с базой данных закрывается отдельно
+func (s *PostgresStateStore) Close() error {
+	return nil
+}
+
+// PurgeExpired удаляет брошенные диалоги с истекшим TTL
+func (s *PostgresStateStore) PurgeExpired() (int64, error) {
+	res, err := s.db.Exec("DELETE FROM chat_states WHERE expires_at <= NOW()")
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
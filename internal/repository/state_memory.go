@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"finuchet-bot/internal/models"
+	"sync"
+	"time"
+)
+
+// MemoryStateStore хранит состояния в памяти процесса.
+// Подходит для локального запуска и тестов: состояния не переживают перезапуск.
+type MemoryStateStore struct {
+	mu     sync.Mutex
+	ttl    time.Duration
+	states map[memoryStateKey]memoryState
+}
+
+type memoryStateKey struct {
+	chatID, memberID int64
+}
+
+type memoryState struct {
+	state     models.ChatState
+	expiresAt time.Time
+}
+
+func NewMemoryStateStore(ttl time.Duration) *MemoryStateStore {
+	return &MemoryStateStore{ttl: ttl, states: make(map[memoryStateKey]memoryState)}
+}
+
+func (s *MemoryStateStore) GetState(chatID, memberID int64) (*models.ChatState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := memoryStateKey{chatID, memberID}
+	entry, ok := s.states[key]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(s.states, key)
+		return nil, nil
+	}
+	state := entry.state // Возвращаем копию, чтобы вызывающий код не менял хранилище в обход блокировки
+	return &state, nil
+}
+
+func (s *MemoryStateStore) SetState(chatID, memberID int64, state *models.ChatState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[memoryStateKey{chatID, memberID}] = memoryState{state: *state, expiresAt: time.Now().Add(s.ttl)}
+	return nil
+}
+
+func (s *MemoryStateStore) DeleteState(chatID, memberID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.states, memoryStateKey{chatID, memberID})
+	return nil
+}
+
+func (s *MemoryStateStore) Close() error {
+	return nil
+}
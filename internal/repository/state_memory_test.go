@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"finuchet-bot/internal/models"
+	"testing"
+	"time"
+)
+
+func TestMemoryStateStore(t *testing.T) {
+	s := NewMemoryStateStore(time.Hour)
+
+	if err := s.SetState(-1, 10, &models.ChatState{State: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetState(-1, 20, &models.ChatState{State: "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for member, want := range map[int64]string{10: "a", 20: "b"} {
+		got, err := s.GetState(-1, member)
+		if err != nil || got == nil || got.State != want {
+			t.Errorf("GetState(-1, %d) = %+v, %v, want %q", member, got, err, want)
+		}
+	}
+	if got, _ := s.GetState(-1, 0); got != nil {
+		t.Errorf("GetState(-1, 0) = %+v, want nil: the chat has no common dialog", got)
+	}
+
+	if err := s.DeleteState(-1, 10); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.GetState(-1, 10); got != nil {
+		t.Errorf("deleted dialog still present: %+v", got)
+	}
+	if got, _ := s.GetState(-1, 20); got == nil {
+		t.Error("deleting one member's dialog removed another's")
+	}
+
+	// Возвращается копия: изменения вызывающего кода не попадают в хранилище
+	got, _ := s.GetState(-1, 20)
+	got.State = "changed"
+	if again, _ := s.GetState(-1, 20); again.State != "b" {
+		t.Errorf("stored state changed through a returned copy: %q", again.State)
+	}
+}
+
+func TestMemoryStateStoreExpires(t *testing.T) {
+	s := NewMemoryStateStore(-time.Second)
+	if err := s.SetState(1, 0, &models.ChatState{State: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.GetState(1, 0); got != nil {
+		t.Errorf("expired dialog returned: %+v", got)
+	}
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"finuchet-bot/internal/models"
+	"strconv"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const redisStateKeyPrefix = "finuchet:state:"
+
+type RedisStateStore struct {
+	client *goredis.Client
+	ttl    time.Duration
+}
+
+func NewRedisStateStore(client *goredis.Client, ttl time.Duration) *RedisStateStore {
+	return &RedisStateStore{client: client, ttl: ttl}
+}
+
+func (s *RedisStateStore) GetState(chatID, memberID int64) (*models.ChatState, error) {
+	data, err := s.client.Get(context.Background(), redisStateKey(chatID, memberID)).Bytes()
+	if err == goredis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &models.ChatState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (s *RedisStateStore) SetState(chatID, memberID int64, state *models.ChatState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	// TTL продлевается при каждом шаге диалога
+	return s.client.Set(context.Background(), redisStateKey(chatID, memberID), data, s.ttl).Err()
+}
+
+func (s *RedisStateStore) DeleteState(chatID, memberID int64) error {
+	return s.client.Del(context.Background(), redisStateKey(chatID, memberID)).Err()
+}
+
+func (s *RedisStateStore) Close() error {
+	return s.client.Close()
+}
+
+// Ключ диалога: "finuchet:state:<чат>" или "finuchet:state:<чат>:<участник>" в группе
+func redisStateKey(chatID, memberID int64) string {
+	key := redisStateKeyPrefix + strconv.FormatInt(chatID, 10)
+	if memberID != 0 {
+		key += ":" + strconv.FormatInt(memberID, 10)
+	}
+	return key
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"database/sql"
+	"finuchet-bot/internal/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Операции вместе с названиями категории и счета, тегами, именем записавшего участника
+// и долгом, который погашает операция
+const selectTransactions = `SELECT t.id, t.user_id, t.amount, t.currency, t.category_id, COALESCE(c.category, ''),
+		COALESCE(t.account_id, 0), COALESCE(acc.name, ''), t.type, t.create_dat, t.note,
+		ARRAY(SELECT tt.tag FROM transaction_tags AS tt WHERE tt.transaction_id = t.id ORDER BY tt.tag), t.merchant,
+		COALESCE(t.import_hash, ''), COALESCE(t.recurring_id, 0), COALESCE(t.member_id, 0), COALESCE(m.name, ''),
+		COALESCE(dp.debt_id, 0), COALESCE(debt.counterparty, ''), t.created_at
+	FROM transactions AS t
+	LEFT JOIN user_categories AS c ON c.id = t.category_id
+	LEFT JOIN accounts AS acc ON acc.id = t.account_id
+	LEFT JOIN ledger_members AS m ON m.ledger_id = t.user_id AND m.member_id = t.member_id
+	LEFT JOIN debt_payments AS dp ON dp.transaction_id = t.id
+	LEFT JOIN debts AS debt ON debt.id = dp.debt_id`
+
+// Счет новой операции: указанный параметром $n или основной счет пользователя $1
+func accountOrDefault(n int) string {
+	return fmt.Sprintf("COALESCE($%d, (SELECT id FROM accounts WHERE user_id = $1 AND is_default))", n)
+}
+
+func scanTransaction(row interface{ Scan(...any) error }) (*models.Transaction, error) {
+	transaction := &models.Transaction{}
+	var categoryID sql.NullInt64
+	err := row.Scan(&transaction.ID, &transaction.UserID, &transaction.Amount, &transaction.Currency, &categoryID, &transaction.Category,
+		&transaction.AccountID, &transaction.Account, &transaction.Type, &transaction.Date, &transaction.Note, pq.Array(&transaction.Tags), &transaction.Merchant,
+		&transaction.ImportHash, &transaction.RecurringID, &transaction.MemberID, &transaction.Member,
+		&transaction.DebtID, &transaction.Debt, &transaction.CreatedAt)
+	transaction.CategoryID = categoryID.Int64
+	return transaction, err
+}
+
+func (r *PostgresRepository) queryTransactions(query string, args ...any) ([]*models.Transaction, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*models.Transaction
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, rows.Err()
+}
+
+// GetRecentTransactions возвращает операции пользователя, начиная с самых новых
+func (r *PostgresRepository) GetRecentTransactions(userID int64, limit, offset int) ([]*models.Transaction, error) {
+	return r.queryTransactions(selectTransactions+`
+		WHERE t.user_id = $1
+		ORDER BY t.create_dat DESC, t.id DESC
+		LIMIT $2 OFFSET $3`, userID, limit, offset)
+}
+
+// GetTransactionByID возвращает операцию, только если она принадлежит пользователю
+func (r *PostgresRepository) GetTransactionByID(userID, transactionID int64) (*models.Transaction, error) {
+	transaction, err := scanTransaction(r.db.QueryRow(selectTransactions+" WHERE t.id = $1 AND t.user_id = $2", transactionID, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return transaction, err
+}
+
+// UpdateTransaction изменяет операцию вместе с тегами; для чужой или уже удаленной операции возвращает ErrNotFound
+func (r *PostgresRepository) UpdateTransaction(transaction *models.Transaction) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE transactions SET amount = $3, currency = $4, category_id = $5, type = $6, create_dat = $7, note = $8, merchant = $9,
+			account_id = $10
+		WHERE id = $1 AND user_id = $2`,
+		transaction.ID, transaction.UserID, transaction.Amount, transaction.Currency, nullID(transaction.CategoryID), transaction.Type,
+		transaction.Date, transaction.Note, transaction.Merchant, nullID(transaction.AccountID))
+	if err != nil {
+		return err
+	}
+	if err := checkAffected(result); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM transaction_tags WHERE transaction_id = $1", transaction.ID); err != nil {
+		return err
+	}
+	if err := insertTags(tx, transaction); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Сохранение тегов операции; повторяющиеся теги пропускаются
+func insertTags(tx *sql.Tx, transaction *models.Transaction) error {
+	if len(transaction.Tags) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(`INSERT INTO transaction_tags (transaction_id, tag)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT DO NOTHING`, transaction.ID, pq.Array(transaction.Tags))
+	return err
+}
+
+// SearchTransactions возвращает до limit последних операций, у которых заметка, продавец
+// или категория содержат query либо есть тег, равный query без "#"
+func (r *PostgresRepository) SearchTransactions(userID int64, query string, limit int) ([]*models.Transaction, error) {
+	return r.queryTransactions(selectTransactions+`
+		WHERE t.user_id = $1
+		  AND (t.note ILIKE '%' || $2 || '%'
+		    OR t.merchant ILIKE '%' || $2 || '%'
+		    OR c.category ILIKE '%' || $2 || '%'
+		    OR EXISTS (SELECT 1 FROM transaction_tags AS tt WHERE tt.transaction_id = t.id AND tt.tag = $3))
+		ORDER BY t.create_dat DESC, t.id DESC
+		LIMIT $4`, userID, escapeLike(query), strings.ToLower(strings.TrimPrefix(query, "#")), limit)
+}
+
+// Экранирование символов шаблона LIKE, чтобы "%" и "_" в запросе искались буквально
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// DeleteTransaction удаляет операцию; для чужой или уже удаленной операции возвращает ErrNotFound
+func (r *PostgresRepository) DeleteTransaction(userID, transactionID int64) error {
+	result, err := r.db.Exec("DELETE FROM transactions WHERE id = $1 AND user_id = $2", transactionID, userID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+// ErrNotFound, если запрос не затронул ни одной строки
+func checkAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// IterateTransactions передает операции за период [from, to) в fn по одной, не загружая их все в память.
+// Нулевые from и to снимают ограничение с соответствующей стороны.
+func (r *PostgresRepository) IterateTransactions(userID int64, from, to time.Time, fn func(*models.Transaction) error) error {
+	rows, err := r.db.Query(selectTransactions+`
+		WHERE t.user_id = $1
+		  AND ($2::date IS NULL OR t.create_dat >= $2::date)
+		  AND ($3::date IS NULL OR t.create_dat < $3::date)
+		ORDER BY t.create_dat, t.id`, userID, nullDate(from), nullDate(to))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(transaction); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// BulkInsertTransactions добавляет операции в одной транзакции БД.
+// Операции с уже загруженным ранее ImportHash пропускаются; возвращается число добавленных.
+func (r *PostgresRepository) BulkInsertTransactions(transactions []*models.Transaction) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO transactions (user_id, amount, currency, category_id, type, create_dat, note, import_hash, account_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), ` + accountOrDefault(9) + `)
+		ON CONFLICT (user_id, import_hash) WHERE import_hash IS NOT NULL DO NOTHING
+		RETURNING id`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	inserted := 0
+	for _, t := range transactions {
+		err := stmt.QueryRow(t.UserID, t.Amount, t.Currency, nullID(t.CategoryID), t.Type, t.Date, t.Note, t.ImportHash, nullID(t.AccountID)).Scan(&t.ID)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		inserted++
+	}
+	return inserted, tx.Commit()
+}
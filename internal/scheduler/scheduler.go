@@ -0,0 +1,54 @@
+// Package scheduler запускает периодические задачи бота внутри процесса.
+// Расписание и отметки о выполнении хранятся в базе у самих задач, поэтому после
+// перезапуска планировщик продолжает с того места, где остановился.
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Run вызывает fn сразу после запуска, затем каждые interval по часам clock,
+// пока не отменен ctx. fn получает текущее время часов.
+func Run(ctx context.Context, clock Clock, interval time.Duration, fn func(now time.Time)) {
+	for {
+		fn(clock.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-clock.After(interval):
+		}
+	}
+}
+
+// Group запускает фоновые задачи и позволяет дождаться их завершения при остановке,
+// чтобы хранилища закрывались только после того, как задачи перестали к ним обращаться.
+type Group struct {
+	wg sync.WaitGroup
+}
+
+// Go запускает fn в отдельной горутине
+func (g *Group) Go(fn func()) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		fn()
+	}()
+}
+
+// Wait дожидается завершения всех задач, но не дольше ctx; при истечении возвращает ctx.Err()
+func (g *Group) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,126 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+const interval = time.Hour
+
+// runScheduler запускает Run с FakeClock; каждый вызов задачи попадает в calls
+func runScheduler(t *testing.T) (clock *FakeClock, calls chan time.Time, cancel context.CancelFunc, done chan struct{}) {
+	t.Helper()
+	clock = NewFakeClock(time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC))
+	calls = make(chan time.Time, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done = make(chan struct{})
+	go func() {
+		defer close(done)
+		Run(ctx, clock, interval, func(now time.Time) { calls <- now })
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return clock, calls, cancel, done
+}
+
+// Ожидание, пока планировщик уснет до следующего интервала
+func waitSleeping(t *testing.T, clock *FakeClock) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for clock.Waiters() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("scheduler did not start waiting for the next interval")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func expectCall(t *testing.T, calls <-chan time.Time, want time.Time) {
+	t.Helper()
+	select {
+	case got := <-calls:
+		if !got.Equal(want) {
+			t.Errorf("task called with %s, want %s", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("task was not called for %s", want)
+	}
+}
+
+func expectNoCall(t *testing.T, calls <-chan time.Time) {
+	t.Helper()
+	select {
+	case got := <-calls:
+		t.Fatalf("unexpected task call at %s", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestRunCallsImmediatelyThenWaitsFullInterval(t *testing.T) {
+	clock, calls, _, _ := runScheduler(t)
+	start := clock.Now()
+
+	expectCall(t, calls, start)
+	waitSleeping(t, clock)
+
+	clock.Advance(interval - time.Second)
+	expectNoCall(t, calls)
+
+	clock.Advance(time.Second)
+	expectCall(t, calls, start.Add(interval))
+}
+
+func TestRunOneCallPerInterval(t *testing.T) {
+	clock, calls, _, _ := runScheduler(t)
+	start := clock.Now()
+	expectCall(t, calls, start)
+
+	for i := 1; i <= 3; i++ {
+		waitSleeping(t, clock)
+		clock.Advance(interval)
+		expectCall(t, calls, start.Add(time.Duration(i)*interval))
+		expectNoCall(t, calls)
+	}
+
+	// Долгий простой не порождает пачку пропущенных вызовов: задачи сами наверстывают по своим данным
+	waitSleeping(t, clock)
+	clock.Advance(5 * interval)
+	expectCall(t, calls, start.Add(8*interval))
+	expectNoCall(t, calls)
+}
+
+func TestRunStopsOnCancel(t *testing.T) {
+	clock, calls, cancel, done := runScheduler(t)
+	expectCall(t, calls, clock.Now())
+	waitSleeping(t, clock)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+
+	clock.Advance(interval)
+	expectNoCall(t, calls)
+}
+
+func TestGroupWait(t *testing.T) {
+	release := make(chan struct{})
+	var g Group
+	g.Go(func() { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := g.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Wait() with a running task = %v, want DeadlineExceeded", err)
+	}
+
+	close(release)
+	if err := g.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"errors"
+	"finuchet-bot/internal/currency"
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"finuchet-bot/internal/repository"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrAccountNotFound    = errors.New("account not found")
+	ErrAccountExists      = errors.New("account already exists")
+	ErrInvalidAccountName = errors.New("invalid account name")
+	ErrDefaultAccount     = errors.New("default account cannot be archived")
+	ErrSameAccount        = errors.New("transfer requires two different accounts")
+)
+
+const (
+	maxAccountNameLength = 50 // Соответствует VARCHAR(50) в accounts
+	defaultAccountName   = "Основной"
+	transfersLimit       = 10 // Сколько последних переводов показывать
+)
+
+// Создание основного счета в базовой валюте, если у пользователя еще нет ни одного
+func (s *FinanceService) seedAccount(user *models.User) error {
+	return s.repo.SeedAccount(&models.Account{UserID: user.ID, Name: defaultAccountName, Currency: user.BaseCurrency})
+}
+
+// GetAccounts возвращает неархивные счета пользователя с текущими остатками
+func (s *FinanceService) GetAccounts(chatID int64) ([]*models.Account, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetAccountBalances(user.ID, time.Time{})
+}
+
+func (s *FinanceService) GetAccount(chatID, accountID int64) (*models.Account, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return s.account(user, accountID)
+}
+
+// CreateAccount добавляет счет с начальным остатком; пустой code означает базовую валюту.
+// Начальный остаток может быть отрицательным, например долг по кредитной карте.
+func (s *FinanceService) CreateAccount(chatID int64, name, code string, opening money.Amount) (*models.Account, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if name, err = validateAccountName(name); err != nil {
+		return nil, err
+	}
+	if err := opening.Check(); err != nil {
+		return nil, err
+	}
+	account := &models.Account{UserID: user.ID, Name: name, Currency: user.BaseCurrency, OpeningBalance: opening}
+	if code != "" {
+		var ok bool
+		if account.Currency, ok = currency.Parse(code); !ok {
+			return nil, ErrUnknownCurrency
+		}
+	}
+	if err := s.repo.CreateAccount(account); err != nil {
+		return nil, accountError(err)
+	}
+	return account, nil
+}
+
+// SetOpeningBalance изменяет начальный остаток счета
+func (s *FinanceService) SetOpeningBalance(chatID, accountID int64, opening money.Amount) error {
+	if err := opening.Check(); err != nil {
+		return err
+	}
+	return s.updateAccount(chatID, accountID, func(a *models.Account) error {
+		a.OpeningBalance = opening
+		return nil
+	})
+}
+
+// Архивирование (archived=true) или восстановление счета; основной счет архивировать нельзя
+func (s *FinanceService) ArchiveAccount(chatID, accountID int64, archived bool) error {
+	return s.updateAccount(chatID, accountID, func(a *models.Account) error {
+		if archived && a.IsDefault {
+			return ErrDefaultAccount
+		}
+		a.Archived = archived
+		return nil
+	})
+}
+
+// SetDefaultAccount делает счет основным: на него записываются операции без выбранного счета
+func (s *FinanceService) SetDefaultAccount(chatID, accountID int64) error {
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+	account, err := s.activeAccount(user, accountID)
+	if err != nil {
+		return err
+	}
+	return s.repo.SetDefaultAccount(user.ID, account.ID)
+}
+
+// Transfer переводит amount со счета fromID на счет toID. Если валюты счетов различаются
+// и зачисленная сумма toAmount не указана (0), она считается по курсу на дату перевода.
+// Нулевая date означает сегодня.
+func (s *FinanceService) Transfer(chatID, fromID, toID int64, amount, toAmount money.Amount, date time.Time) (*models.Transfer, error) {
+	if err := checkAmount(amount); err != nil {
+		return nil, err
+	}
+	if fromID == toID {
+		return nil, ErrSameAccount
+	}
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	from, err := s.activeAccount(user, fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.activeAccount(user, toID)
+	if err != nil {
+		return nil, err
+	}
+	if date.IsZero() {
+		date = dates.Day(s.now(user))
+	}
+
+	if toAmount == 0 {
+		rate, err := s.crossRate(user, from.Currency, to.Currency, date)
+		if err != nil {
+			return nil, err
+		}
+		toAmount = amount.Mul(rate)
+	}
+	if err := checkAmount(toAmount); err != nil {
+		return nil, err
+	}
+
+	transfer := &models.Transfer{
+		UserID:        user.ID,
+		FromAccountID: from.ID,
+		FromAccount:   from.Name,
+		FromCurrency:  from.Currency,
+		ToAccountID:   to.ID,
+		ToAccount:     to.Name,
+		ToCurrency:    to.Currency,
+		Amount:        amount,
+		ToAmount:      toAmount,
+		Date:          date,
+	}
+	return transfer, s.repo.AddTransfer(transfer)
+}
+
+// GetTransfers возвращает последние переводы между счетами
+func (s *FinanceService) GetTransfers(chatID int64) ([]*models.Transfer, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetRecentTransfers(user.ID, transfersLimit)
+}
+
+// DeleteTransfer отменяет перевод: остатки обоих счетов возвращаются к прежним
+func (s *FinanceService) DeleteTransfer(chatID, transferID int64) error {
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteTransfer(user.ID, transferID)
+}
+
+// Перенос операции на другой счет
+func (s *FinanceService) UpdateTransactionAccount(chatID, transactionID, accountID int64) error {
+	return s.updateTransaction(chatID, transactionID, func(user *models.User, t *models.Transaction) error {
+		account, err := s.activeAccount(user, accountID)
+		if err != nil {
+			return err
+		}
+		t.AccountID = account.ID
+		return nil
+	})
+}
+
+// Курс пересчета валюты from в to через базовую валюту пользователя
+func (s *FinanceService) crossRate(user *models.User, from, to string, date time.Time) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	rate := func(code string) (float64, error) {
+		if code == user.BaseCurrency {
+			return 1, nil
+		}
+		rate, ok, err := s.repo.GetRate(user.ID, code, user.BaseCurrency, date)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			return 0, ErrNoRate
+		}
+		return rate, nil
+	}
+	fromRate, err := rate(from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := rate(to)
+	if err != nil {
+		return 0, err
+	}
+	return fromRate / toRate, nil
+}
+
+func (s *FinanceService) updateAccount(chatID, accountID int64, update func(*models.Account) error) error {
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+	account, err := s.account(user, accountID)
+	if err != nil {
+		return err
+	}
+	if err := update(account); err != nil {
+		return err
+	}
+	return accountError(s.repo.UpdateAccount(account))
+}
+
+// Счет пользователя или ErrAccountNotFound
+func (s *FinanceService) account(user *models.User, accountID int64) (*models.Account, error) {
+	account, err := s.repo.GetAccountByID(user.ID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, ErrAccountNotFound
+	}
+	return account, nil
+}
+
+// Неархивный счет пользователя: только на такие счета можно записывать операции и переводы
+func (s *FinanceService) activeAccount(user *models.User, accountID int64) (*models.Account, error) {
+	account, err := s.account(user, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.Archived {
+		return nil, ErrAccountNotFound
+	}
+	return account, nil
+}
+
+func validateAccountName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || utf8.RuneCountInString(name) > maxAccountNameLength || strings.HasPrefix(name, "/") {
+		return "", ErrInvalidAccountName
+	}
+	return name, nil
+}
+
+func accountError(err error) error {
+	if errors.Is(err, repository.ErrDuplicate) {
+		return ErrAccountExists
+	}
+	return err
+}
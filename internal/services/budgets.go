@@ -0,0 +1,258 @@
+package services
+
+import (
+	"errors"
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"time"
+)
+
+var (
+	ErrBudgetNotFound = errors.New("budget not found")
+	ErrBudgetCategory = errors.New("budget requires an expense category")
+)
+
+// Пороги предупреждений о расходовании бюджета, в процентах
+var budgetThresholds = []int64{100, 80}
+
+// Состояние бюджета категории за месяц
+type BudgetStatus struct {
+	*models.Budget
+	Month     time.Time    // Первый день месяца
+	Carry     money.Amount // Остаток, перенесенный с прошлых месяцев
+	Spent     money.Amount // Потрачено за месяц
+	Available money.Amount // Лимит с учетом переноса
+}
+
+// Remaining - сколько еще можно потратить; отрицательное значение означает перерасход
+func (b *BudgetStatus) Remaining() money.Amount {
+	return b.Available - b.Spent
+}
+
+// Percent - доля израсходованного бюджета
+func (b *BudgetStatus) Percent() float64 {
+	if b.Available <= 0 {
+		return 100
+	}
+	return b.Spent.Percent(b.Available)
+}
+
+// Предупреждение о пересечении порога бюджета
+type BudgetAlert struct {
+	*BudgetStatus
+	Threshold int64 // Пересеченный порог: 80 или 100
+}
+
+// SetBudget задает месячный лимит категории расходов
+func (s *FinanceService) SetBudget(chatID, categoryID int64, amount money.Amount, rollover bool) error {
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+	if err := checkAmount(amount); err != nil {
+		return err
+	}
+	category, err := s.category(user, categoryID)
+	if err != nil {
+		return err
+	}
+	if category.Type != "expense" {
+		return ErrBudgetCategory
+	}
+
+	return s.repo.SetBudget(&models.Budget{
+		UserID:     user.ID,
+		CategoryID: categoryID,
+		Amount:     amount,
+		Rollover:   rollover,
+		StartMonth: dates.MonthStart(s.now(user)),
+	})
+}
+
+// SetBudgetAmount изменяет лимит существующего бюджета
+func (s *FinanceService) SetBudgetAmount(chatID, categoryID int64, amount money.Amount) error {
+	budget, err := s.GetBudget(chatID, categoryID)
+	if err != nil {
+		return err
+	}
+	return s.SetBudget(chatID, categoryID, amount, budget.Rollover)
+}
+
+// ToggleBudgetRollover включает или выключает перенос остатка
+func (s *FinanceService) ToggleBudgetRollover(chatID, categoryID int64) error {
+	budget, err := s.GetBudget(chatID, categoryID)
+	if err != nil {
+		return err
+	}
+	return s.SetBudget(chatID, categoryID, budget.Amount, !budget.Rollover)
+}
+
+// DeleteBudget снимает лимит с категории
+func (s *FinanceService) DeleteBudget(chatID, categoryID int64) error {
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteBudget(user.ID, categoryID)
+}
+
+// GetBudget возвращает бюджет категории или ErrBudgetNotFound
+func (s *FinanceService) GetBudget(chatID, categoryID int64) (*models.Budget, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	budget, err := s.repo.GetBudget(user.ID, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	if budget == nil {
+		return nil, ErrBudgetNotFound
+	}
+	return budget, nil
+}
+
+// GetBudgetStatuses возвращает состояние всех бюджетов пользователя за месяц, содержащий month
+func (s *FinanceService) GetBudgetStatuses(chatID int64, month time.Time) ([]*BudgetStatus, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return s.budgetStatuses(user, month)
+}
+
+// GetBudgetStatus возвращает состояние бюджета категории за месяц, содержащий month
+func (s *FinanceService) GetBudgetStatus(chatID, categoryID int64, month time.Time) (*BudgetStatus, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	budget, err := s.repo.GetBudget(user.ID, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	if budget == nil {
+		return nil, ErrBudgetNotFound
+	}
+	return s.budgetStatus(user, budget, month)
+}
+
+// CheckBudget сообщает, пересекла ли добавленная операция порог 80% или 100% бюджета ее категории.
+// Возвращает nil, если бюджета нет или порог не пересечен.
+func (s *FinanceService) CheckBudget(chatID int64, transaction *models.Transaction) (*BudgetAlert, error) {
+	if transaction.Type != "expense" || transaction.CategoryID == 0 {
+		return nil, nil
+	}
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	budget, err := s.repo.GetBudget(user.ID, transaction.CategoryID)
+	if err != nil || budget == nil {
+		return nil, err
+	}
+
+	date := transaction.Date
+	if date.IsZero() {
+		date = s.now(user)
+	}
+	status, err := s.budgetStatus(user, budget, date)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := s.convert(user, transaction.Amount, transaction.Currency, date)
+	if errors.Is(err, ErrNoRate) {
+		return nil, nil // Без курса сумма не учтена в расходах, проверять нечего
+	}
+	if err != nil {
+		return nil, err
+	}
+	before := status.Spent - amount
+	for _, threshold := range budgetThresholds {
+		// Сравнение в целых числах: spent/available >= threshold/100
+		crossed := func(spent money.Amount) bool { return int64(spent)*100 >= int64(status.Available)*threshold }
+		if !crossed(before) && crossed(status.Spent) {
+			return &BudgetAlert{BudgetStatus: status, Threshold: threshold}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *FinanceService) budgetStatuses(user *models.User, month time.Time) ([]*BudgetStatus, error) {
+	budgets, err := s.repo.GetBudgets(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*BudgetStatus, 0, len(budgets))
+	for _, budget := range budgets {
+		status, err := s.budgetStatus(user, budget, month)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Расходы категории за месяц и остаток, перенесенный с прошлых месяцев.
+// Переносится только неизрасходованная часть: перерасход не уменьшает следующие месяцы.
+// Каждый месяц считается по лимиту, который действовал в нем.
+func (s *FinanceService) budgetStatus(user *models.User, budget *models.Budget, month time.Time) (*BudgetStatus, error) {
+	month = dates.MonthStart(month)
+	status := &BudgetStatus{Budget: budget, Month: month}
+
+	from := month
+	// Месяц начала из базы приходит в UTC, а month - в поясе пользователя
+	start := monthIn(budget.StartMonth, month.Location())
+	if budget.Rollover && start.Before(month) {
+		from = start
+	}
+	totals, err := s.repo.GetCategoryMonthlyTotals(user.ID, budget.CategoryID, from, month.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+	limits, err := s.repo.GetBudgetLimits(budget.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	spent := make(map[string]money.Amount)
+	for _, t := range totals {
+		if t.Type == "expense" {
+			spent[t.Date.Format("2006-01")] += t.Amount
+		}
+	}
+	for m := from; m.Before(month); m = m.AddDate(0, 1, 0) {
+		status.Carry += budgetLimit(budget, limits, m) - spent[m.Format("2006-01")]
+		if status.Carry < 0 {
+			status.Carry = 0
+		}
+	}
+	status.Spent = spent[month.Format("2006-01")]
+	status.Available = budgetLimit(budget, limits, month) + status.Carry
+	return status, nil
+}
+
+// Лимит, действовавший в месяце m: последний заданный не позже m. До первой записи
+// действует самый ранний лимит, а без истории - текущий.
+func budgetLimit(budget *models.Budget, limits []*models.BudgetLimit, m time.Time) money.Amount {
+	if len(limits) == 0 {
+		return budget.Amount
+	}
+	amount := limits[0].Amount
+	for _, limit := range limits {
+		if monthIn(limit.Month, m.Location()).After(m) {
+			break
+		}
+		amount = limit.Amount
+	}
+	return amount
+}
+
+// Первый день месяца даты из базы в поясе loc
+func monthIn(month time.Time, loc *time.Location) time.Time {
+	return time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"finuchet-bot/internal/repository"
+	"testing"
+	"time"
+)
+
+// budgetsRepo хранит один бюджет, историю его лимитов и помесячные расходы его категории
+type budgetsRepo struct {
+	repository.Repository
+	budget *models.Budget
+	limits []*models.BudgetLimit
+	spent  map[time.Time]money.Amount
+}
+
+func (r *budgetsRepo) GetLedgerByChatID(chatID int64) (*models.User, error) {
+	return &models.User{ID: 1, ChatID: chatID, Timezone: "UTC"}, nil
+}
+
+func (r *budgetsRepo) GetCategoryByID(userID, categoryID int64) (*models.Category, error) {
+	return &models.Category{ID: categoryID, UserID: userID, Type: "expense"}, nil
+}
+
+func (r *budgetsRepo) GetBudget(int64, int64) (*models.Budget, error) {
+	return r.budget, nil
+}
+
+// SetBudget повторяет запрос PostgresRepository.SetBudget: месяц начала сбрасывается только
+// при переключении переноса, а лимит записывается с месяца budget.StartMonth
+func (r *budgetsRepo) SetBudget(budget *models.Budget) error {
+	month := budget.StartMonth
+	if r.budget != nil && r.budget.Rollover == budget.Rollover {
+		budget.StartMonth = r.budget.StartMonth
+	}
+	saved := *budget
+	r.budget = &saved
+
+	for _, limit := range r.limits {
+		if limit.Month.Equal(month) {
+			limit.Amount = budget.Amount
+			return nil
+		}
+	}
+	r.limits = append(r.limits, &models.BudgetLimit{Month: month, Amount: budget.Amount})
+	return nil
+}
+
+func (r *budgetsRepo) GetBudgetLimits(int64) ([]*models.BudgetLimit, error) {
+	return r.limits, nil
+}
+
+func (r *budgetsRepo) GetCategoryMonthlyTotals(_, _ int64, from, to time.Time) ([]*models.DateTotal, error) {
+	var totals []*models.DateTotal
+	for m := from; m.Before(to); m = m.AddDate(0, 1, 0) {
+		if amount, ok := r.spent[m]; ok {
+			totals = append(totals, &models.DateTotal{Date: m, Type: "expense", Amount: amount})
+		}
+	}
+	return totals, nil
+}
+
+func TestBudgetStatusCarry(t *testing.T) {
+	spent := map[time.Time]money.Amount{
+		date(2026, 1, 1): money.New(6000, 0),
+		date(2026, 2, 1): money.New(12000, 0), // Перерасход съедает перенос, но не уходит в минус
+		date(2026, 3, 1): money.New(3000, 0),
+	}
+	tests := []struct {
+		name      string
+		rollover  bool
+		start     time.Time
+		wantCarry money.Amount
+	}{
+		{"carries unspent remainder", true, date(2026, 1, 1), money.New(2000, 0)},
+		{"overspending does not go below zero", true, date(2026, 2, 1), 0},
+		{"starts this month", true, date(2026, 3, 1), 0},
+		{"rollover off", false, date(2026, 1, 1), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &budgetsRepo{
+				budget: &models.Budget{CategoryID: 7, Amount: money.New(10000, 0), Rollover: tt.rollover, StartMonth: tt.start},
+				spent:  spent,
+			}
+			s := NewFinanceService(repo)
+
+			status, err := s.GetBudgetStatus(100, 7, date(2026, 3, 20))
+			if err != nil {
+				t.Fatalf("GetBudgetStatus() error = %v", err)
+			}
+			if status.Carry != tt.wantCarry {
+				t.Errorf("carry = %s, want %s", status.Carry, tt.wantCarry)
+			}
+			if want := money.New(10000, 0) + tt.wantCarry; status.Available != want {
+				t.Errorf("available = %s, want %s", status.Available, want)
+			}
+			if want := money.New(3000, 0); status.Spent != want {
+				t.Errorf("spent = %s, want %s", status.Spent, want)
+			}
+		})
+	}
+}
+
+func TestBudgetLimitChangeKeepsCarry(t *testing.T) {
+	thisMonth := dates.MonthStart(time.Now().UTC())
+	month := func(n int) time.Time { return thisMonth.AddDate(0, n, 0) }
+	repo := &budgetsRepo{
+		budget: &models.Budget{CategoryID: 7, Amount: money.New(10000, 0), Rollover: true, StartMonth: month(-2)},
+		limits: []*models.BudgetLimit{{Month: month(-2), Amount: money.New(10000, 0)}},
+		spent: map[time.Time]money.Amount{
+			month(-2): money.New(6000, 0),
+			month(-1): money.New(7000, 0),
+			month(0):  money.New(1000, 0),
+		},
+	}
+	s := NewFinanceService(repo)
+
+	carry := func(m time.Time) (money.Amount, money.Amount) {
+		t.Helper()
+		status, err := s.GetBudgetStatus(100, 7, m)
+		if err != nil {
+			t.Fatalf("GetBudgetStatus(%s) error = %v", m.Format("2006-01"), err)
+		}
+		return status.Carry, status.Available
+	}
+
+	// Два месяца по 10000: остатки 4000 и 3000
+	if got, available := carry(month(0)); got != money.New(7000, 0) || available != money.New(17000, 0) {
+		t.Fatalf("before change: carry = %s, available = %s, want 7000.00 and 17000.00", got, available)
+	}
+
+	if err := s.SetBudgetAmount(100, 7, money.New(15000, 0)); err != nil {
+		t.Fatalf("SetBudgetAmount() error = %v", err)
+	}
+	if !repo.budget.StartMonth.Equal(month(-2)) {
+		t.Errorf("start month = %s, want %s", repo.budget.StartMonth.Format("2006-01"), month(-2).Format("2006-01"))
+	}
+
+	// Прошлые месяцы считаются по старому лимиту, текущий - по новому
+	if got, available := carry(month(-1)); got != money.New(4000, 0) || available != money.New(14000, 0) {
+		t.Errorf("last month after change: carry = %s, available = %s, want 4000.00 and 14000.00", got, available)
+	}
+	if got, available := carry(month(0)); got != money.New(7000, 0) || available != money.New(22000, 0) {
+		t.Errorf("this month after change: carry = %s, available = %s, want 7000.00 and 22000.00", got, available)
+	}
+	// Следующий месяц получает остаток текущего по новому лимиту: 7000 + 15000 - 1000
+	if got, available := carry(month(1)); got != money.New(21000, 0) || available != money.New(36000, 0) {
+		t.Errorf("next month after change: carry = %s, available = %s, want 21000.00 and 36000.00", got, available)
+	}
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"errors"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/repository"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrCategoryNotFound    = errors.New("category not found")
+	ErrCategoryExists      = errors.New("category already exists")
+	ErrInvalidCategoryName = errors.New("invalid category name")
+	ErrCategoryType        = errors.New("category type does not match transaction type")
+)
+
+const maxCategoryNameLength = 50 // Соответствует VARCHAR(50) в user_categories
+
+// Категории доходов по умолчанию
+var DefaultIncomeCategories = []models.Category{
+	{Name: "З/п", Emoji: "💸", Type: "income", Aliases: []string{"зп", "зарплата", "аванс"}},
+	{Name: "Дебитор", Emoji: "🫴", Type: "income", Aliases: []string{"долг", "возврат"}},
+	{Name: "Премия", Emoji: "💰", Type: "income", Aliases: []string{"бонус"}},
+	{Name: "Подработка", Emoji: "🤑", Type: "income", Aliases: []string{"фриланс", "халтура"}},
+	{Name: "Инвест", Emoji: "💹", Type: "income", Aliases: []string{"дивиденды", "купоны"}},
+	{Name: "Вклад", Emoji: "🏦", Type: "income", Aliases: []string{"проценты", "кешбэк", "кэшбэк"}},
+}
+
+// Категории расходов по умолчанию
+var DefaultExpenseCategories = []models.Category{
+	{Name: "Аптеки", Emoji: "🏥", Type: "expense", Aliases: []string{"лекарства"}},
+	{Name: "Авиабилеты", Emoji: "🛫", Type: "expense", Aliases: []string{"самолет", "самолёт"}},
+	{Name: "Аксессуары", Emoji: "🕶️", Type: "expense"},
+	{Name: "Анализы", Emoji: "💉", Type: "expense"},
+	{Name: "Аренда", Emoji: "🔑", Type: "expense", Aliases: []string{"квартира"}},
+	{Name: "БытХим", Emoji: "🧹", Type: "expense", Aliases: []string{"химия"}},
+	{Name: "Витамины", Emoji: "💊", Type: "expense", Aliases: []string{"бады"}},
+	{Name: "Госуслуги", Emoji: "🏢", Type: "expense", Aliases: []string{"пошлина", "штраф", "налог"}},
+	{Name: "Дом и ремонт", Emoji: "🛠️", Type: "expense", Aliases: []string{"ремонт"}},
+	{Name: "Ж/д билеты", Emoji: "🚂", Type: "expense", Aliases: []string{"поезд", "жд", "электричка"}},
+	{Name: "Животные", Emoji: "🐾", Type: "expense", Aliases: []string{"кот", "собака", "ветеринар"}},
+	{Name: "ЖКХ", Emoji: "👾", Type: "expense", Aliases: []string{"коммуналка", "свет", "газ", "вода"}},
+	{Name: "Инвестиции", Emoji: "💹", Type: "expense", Aliases: []string{"акции", "облигации"}},
+	{Name: "Интернет", Emoji: "🌐", Type: "expense"},
+	{Name: "Канцтовары", Emoji: "📝", Type: "expense", Aliases: []string{"канцелярия"}},
+	{Name: "Каршеринг", Emoji: "🏎️", Type: "expense"},
+	{Name: "Книги", Emoji: "📚", Type: "expense"},
+	{Name: "Красота", Emoji: "😻", Type: "expense", Aliases: []string{"маникюр", "парикмахер", "стрижка"}},
+	{Name: "Кредиты", Emoji: "💸", Type: "expense", Aliases: []string{"ипотека", "кредит"}},
+	{Name: "Медицина", Emoji: "🩺", Type: "expense", Aliases: []string{"врач", "стоматолог"}},
+	{Name: "Моб. связь", Emoji: "📞", Type: "expense", Aliases: []string{"связь", "телефон"}},
+	{Name: "Наличные", Emoji: "🗞️", Type: "expense", Aliases: []string{"банкомат"}},
+	{Name: "Образование", Emoji: "🎓", Type: "expense", Aliases: []string{"курсы", "учеба", "учёба"}},
+	{Name: "Одежда и обувь", Emoji: "👟", Type: "expense", Aliases: []string{"одежда", "обувь"}},
+	{Name: "Переводы", Emoji: "📤", Type: "expense", Aliases: []string{"перевод"}},
+	{Name: "Подарки", Emoji: "🎁", Type: "expense", Aliases: []string{"подарок"}},
+	{Name: "Подписки", Emoji: "🤳", Type: "expense", Aliases: []string{"подписка"}},
+	{Name: "Развлечения", Emoji: "🎢", Type: "expense", Aliases: []string{"кино", "театр", "концерт"}},
+	{Name: "Еда", Emoji: "🍜", Type: "expense", Aliases: []string{"кафе", "ресторан", "обед", "доставка"}},
+	{Name: "Супермаркет", Emoji: "🛒", Type: "expense", Aliases: []string{"продукты", "магазин"}},
+	{Name: "Такси", Emoji: "🚕", Type: "expense"},
+	{Name: "Топливо", Emoji: "⛽️", Type: "expense", Aliases: []string{"бензин", "заправка", "азс"}},
+	{Name: "Транспорт", Emoji: "🚌", Type: "expense", Aliases: []string{"метро", "автобус", "проезд"}},
+	{Name: "Цветы", Emoji: "💐", Type: "expense", Aliases: []string{"букет"}},
+	{Name: "Спорт", Emoji: "💪", Type: "expense", Aliases: []string{"фитнес", "бассейн", "зал"}},
+	{Name: "Остальное", Emoji: "🙉", Type: "expense", Aliases: []string{"прочее", "разное"}},
+}
+
+// Список категорий по умолчанию, создаваемых при регистрации
+func defaultCategories() []models.Category {
+	return append(append([]models.Category{}, DefaultIncomeCategories...), DefaultExpenseCategories...)
+}
+
+// Создание категорий по умолчанию, если у пользователя еще нет ни одной
+func (s *FinanceService) seedCategories(user *models.User) error {
+	existing, err := s.repo.GetCategories(user.ID, "", true)
+	if err != nil || len(existing) > 0 {
+		return err
+	}
+	return s.repo.SeedCategories(user.ID, defaultCategories())
+}
+
+// Категории пользователя; пустой transactionType означает оба типа
+func (s *FinanceService) GetCategories(chatID int64, transactionType string, withArchived bool) ([]*models.Category, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetCategories(user.ID, transactionType, withArchived)
+}
+
+func (s *FinanceService) GetCategory(chatID, categoryID int64) (*models.Category, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return s.category(user, categoryID)
+}
+
+func (s *FinanceService) CreateCategory(chatID int64, transactionType, name string) (*models.Category, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	name, err = validateCategoryName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	category := &models.Category{UserID: user.ID, Name: name, Type: transactionType}
+	if err := s.repo.CreateCategory(category); err != nil {
+		return nil, categoryError(err)
+	}
+	return category, nil
+}
+
+func (s *FinanceService) RenameCategory(chatID, categoryID int64, name string) error {
+	name, err := validateCategoryName(name)
+	if err != nil {
+		return err
+	}
+	return s.updateCategory(chatID, categoryID, func(c *models.Category) { c.Name = name })
+}
+
+func (s *FinanceService) SetCategoryEmoji(chatID, categoryID int64, emoji string) error {
+	emoji = strings.TrimSpace(emoji)
+	if utf8.RuneCountInString(emoji) > 8 {
+		return ErrInvalidCategoryName
+	}
+	return s.updateCategory(chatID, categoryID, func(c *models.Category) { c.Emoji = emoji })
+}
+
+// Архивирование (archived=true) или восстановление категории
+func (s *FinanceService) ArchiveCategory(chatID, categoryID int64, archived bool) error {
+	return s.updateCategory(chatID, categoryID, func(c *models.Category) { c.Archived = archived })
+}
+
+// Перемещение категории на delta позиций вверх (<0) или вниз (>0) среди категорий того же типа
+func (s *FinanceService) MoveCategory(chatID, categoryID int64, delta int) error {
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+	category, err := s.category(user, categoryID)
+	if err != nil {
+		return err
+	}
+	categories, err := s.repo.GetCategories(user.ID, category.Type, true)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, len(categories))
+	from := -1
+	for i, c := range categories {
+		ids[i] = c.ID
+		if c.ID == categoryID {
+			from = i
+		}
+	}
+	to := from + delta
+	if from < 0 || to < 0 || to >= len(ids) {
+		return nil // Категория уже с краю
+	}
+	ids[from], ids[to] = ids[to], ids[from]
+	return s.repo.SetCategoryPositions(user.ID, ids)
+}
+
+func (s *FinanceService) updateCategory(chatID, categoryID int64, update func(*models.Category)) error {
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+	category, err := s.category(user, categoryID)
+	if err != nil {
+		return err
+	}
+	update(category)
+	return categoryError(s.repo.UpdateCategory(category))
+}
+
+// Категория пользователя или ErrCategoryNotFound
+func (s *FinanceService) category(user *models.User, categoryID int64) (*models.Category, error) {
+	category, err := s.repo.GetCategoryByID(user.ID, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, ErrCategoryNotFound
+	}
+	return category, nil
+}
+
+func validateCategoryName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || utf8.RuneCountInString(name) > maxCategoryNameLength || strings.HasPrefix(name, "/") {
+		return "", ErrInvalidCategoryName
+	}
+	return name, nil
+}
+
+// Нарушение уникальности (user_id, category, type) означает, что категория с таким названием уже есть
+func categoryError(err error) error {
+	if errors.Is(err, repository.ErrDuplicate) {
+		return ErrCategoryExists
+	}
+	return err
+}
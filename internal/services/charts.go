@@ -0,0 +1,126 @@
+package services
+
+import (
+	"bytes"
+	"finuchet-bot/internal/charts"
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"time"
+)
+
+// Сколько месяцев показывать на диаграмме по месяцам
+const chartMonths = 12
+
+// Chart рисует диаграмму вида kind за период и возвращает PNG.
+// Диаграмма по месяцам охватывает chartMonths месяцев, заканчивая последним месяцем периода.
+func (s *FinanceService) Chart(chatID int64, kind string, period dates.Period) ([]byte, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	switch kind {
+	case charts.KindCategories:
+		totals, err := s.repo.GetCategoryTotals(user.ID, period.From, period.To, models.TransactionFilter{})
+		if err != nil {
+			return nil, err
+		}
+		var slices []charts.Slice
+		for _, t := range totals {
+			if t.Type != "expense" {
+				continue
+			}
+			label := t.Category
+			if label == "" {
+				label = "Без категории"
+			}
+			slices = append(slices, charts.Slice{Label: label, Value: t.Amount.Float64()})
+		}
+		err = charts.Pie(&buf, "Расходы по категориям", slices)
+		if err != nil {
+			return nil, err
+		}
+
+	case charts.KindMonthly:
+		end := period.To
+		if now := s.now(user); end.IsZero() || end.After(now) {
+			end = dates.Day(now).AddDate(0, 0, 1)
+		}
+		to := dates.MonthStart(end.AddDate(0, 0, -1)).AddDate(0, 1, 0)
+		from := to.AddDate(0, -chartMonths, 0)
+		totals, err := s.repo.GetMonthlyTotals(user.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		months := make([]charts.Month, chartMonths)
+		index := make(map[string]int)
+		for i := range months {
+			month := from.AddDate(0, i, 0)
+			months[i].Label = month.Format("01.06")
+			index[month.Format("2006-01")] = i
+		}
+		for _, t := range totals {
+			i, ok := index[t.Date.Format("2006-01")]
+			if !ok {
+				continue
+			}
+			if t.Type == "income" {
+				months[i].Income += t.Amount.Float64()
+			} else {
+				months[i].Expense += t.Amount.Float64()
+			}
+		}
+		if err := charts.Bars(&buf, "Доходы и расходы по месяцам", months); err != nil {
+			return nil, err
+		}
+
+	case charts.KindBalance:
+		totals, err := s.repo.GetDailyTotals(user.ID, period.From, period.To, models.TransactionFilter{})
+		if err != nil {
+			return nil, err
+		}
+		if len(totals) == 0 {
+			return nil, charts.ErrNoData
+		}
+
+		// Баланс по каждому дню периода, включая дни без операций
+		change := make(map[string]money.Amount)
+		for _, t := range totals {
+			if t.Type == "income" {
+				change[t.Date.Format("2006-01-02")] += t.Amount
+			} else {
+				change[t.Date.Format("2006-01-02")] -= t.Amount
+			}
+		}
+		first := totals[0].Date
+		if !period.From.IsZero() {
+			first = period.From
+		}
+		last := totals[len(totals)-1].Date
+		if !period.To.IsZero() {
+			last = period.To.AddDate(0, 0, -1)
+			if today := dates.Day(s.now(user)); last.After(today) {
+				last = today
+			}
+		}
+
+		var points []charts.Point
+		var balance money.Amount
+		first = time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, time.UTC)
+		last = time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, time.UTC)
+		for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+			balance += change[day.Format("2006-01-02")]
+			points = append(points, charts.Point{Label: day.Format("02.01"), Value: balance.Float64()})
+		}
+		if err := charts.Line(&buf, "Накопленный баланс", points); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, charts.ErrUnknownKind
+	}
+	return buf.Bytes(), nil
+}
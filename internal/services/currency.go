@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"finuchet-bot/internal/currency"
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"fmt"
+	"math"
+	"time"
+)
+
+var (
+	ErrUnknownCurrency = errors.New("unknown currency")
+	ErrInvalidRate     = errors.New("invalid exchange rate")
+	ErrNoRate          = errors.New("no exchange rate")
+)
+
+// SetBaseCurrency задает валюту, в которую пересчитываются отчеты и бюджеты
+func (s *FinanceService) SetBaseCurrency(chatID int64, code string) (string, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return "", err
+	}
+	code, ok := currency.Parse(code)
+	if !ok {
+		return "", ErrUnknownCurrency
+	}
+	return code, s.repo.SetBaseCurrency(user.ID, code)
+}
+
+// GetBaseCurrency возвращает базовую валюту пользователя
+func (s *FinanceService) GetBaseCurrency(chatID int64) (string, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return "", err
+	}
+	return user.BaseCurrency, nil
+}
+
+// SetRate сохраняет курс, введенный пользователем: одна единица code стоит rate базовой валюты.
+// Курс действует с сегодняшнего дня и важнее загруженного из общего источника.
+func (s *FinanceService) SetRate(chatID int64, code string, rate float64) (*models.ExchangeRate, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	code, ok := currency.Parse(code)
+	if !ok || code == user.BaseCurrency {
+		return nil, ErrUnknownCurrency
+	}
+	if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+		return nil, ErrInvalidRate
+	}
+
+	exchangeRate := &models.ExchangeRate{
+		UserID:   user.ID,
+		Currency: code,
+		Quote:    user.BaseCurrency,
+		Date:     dates.Day(s.now(user)),
+		Rate:     rate,
+	}
+	return exchangeRate, s.repo.SetRate(exchangeRate)
+}
+
+// GetRates возвращает последние известные курсы к базовой валюте пользователя
+func (s *FinanceService) GetRates(chatID int64) ([]*models.ExchangeRate, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetLatestRates(user.ID, user.BaseCurrency)
+}
+
+// UpdateRates загружает сегодняшние курсы из источника для всех базовых валют пользователей.
+// Валюта, для которой источник не дал курсов, пропускается: курсы остальных все равно
+// сохраняются, а ошибки по пропущенным возвращаются вместе.
+func (s *FinanceService) UpdateRates(ctx context.Context, provider currency.Provider) (int, error) {
+	quotes, err := s.repo.GetBaseCurrencies()
+	if err != nil {
+		return 0, err
+	}
+
+	today := dates.Day(time.Now())
+	var rates []*models.ExchangeRate
+	var errs []error
+	for _, quote := range quotes {
+		values, err := provider.Rates(ctx, quote, today)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rates to %s: %w", quote, err))
+			continue
+		}
+		for code, rate := range values {
+			rates = append(rates, &models.ExchangeRate{Currency: code, Quote: quote, Date: today, Rate: rate})
+		}
+	}
+	if len(rates) == 0 {
+		return 0, errors.Join(errs...)
+	}
+	if err := s.repo.SaveRates(rates); err != nil {
+		return 0, errors.Join(append(errs, err)...)
+	}
+	return len(rates), errors.Join(errs...)
+}
+
+// Сумма в базовой валюте пользователя по курсу на дату
+func (s *FinanceService) convert(user *models.User, amount money.Amount, code string, date time.Time) (money.Amount, error) {
+	if code == "" || code == user.BaseCurrency {
+		return amount, nil
+	}
+	rate, ok, err := s.repo.GetRate(user.ID, code, user.BaseCurrency, date)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, ErrNoRate
+	}
+	return amount.Mul(rate), nil
+}
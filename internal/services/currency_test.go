@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"finuchet-bot/internal/currency"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/repository"
+	"testing"
+)
+
+type ratesRepo struct {
+	repository.Repository
+	quotes []string
+	saved  []*models.ExchangeRate
+}
+
+func (r *ratesRepo) GetBaseCurrencies() ([]string, error) {
+	return r.quotes, nil
+}
+
+func (r *ratesRepo) SaveRates(rates []*models.ExchangeRate) error {
+	r.saved = append(r.saved, rates...)
+	return nil
+}
+
+func TestUpdateRatesSkipsUnknownQuote(t *testing.T) {
+	repo := &ratesRepo{quotes: []string{"RUB", "GEL", "USD"}}
+	s := NewFinanceService(repo)
+	provider := currency.StaticProvider{Quote: "RUB", Values: map[string]float64{"USD": 90, "EUR": 100}}
+
+	n, err := s.UpdateRates(context.Background(), provider)
+	if !errors.Is(err, currency.ErrUnknownCurrency) {
+		t.Errorf("UpdateRates() error = %v, want ErrUnknownCurrency for GEL", err)
+	}
+	// К RUB: USD и EUR; к USD: RUB и EUR
+	if n != 4 || len(repo.saved) != 4 {
+		t.Fatalf("UpdateRates() saved %d (reported %d) rates, want 4", len(repo.saved), n)
+	}
+	for _, rate := range repo.saved {
+		if rate.Quote == "GEL" {
+			t.Errorf("saved rate %s/%s for a quote missing from the source", rate.Currency, rate.Quote)
+		}
+	}
+}
+
+func TestUpdateRatesAllKnown(t *testing.T) {
+	repo := &ratesRepo{quotes: []string{"RUB"}}
+	s := NewFinanceService(repo)
+	provider := currency.StaticProvider{Quote: "RUB", Values: map[string]float64{"USD": 90}}
+
+	n, err := s.UpdateRates(context.Background(), provider)
+	if err != nil || n != 1 {
+		t.Fatalf("UpdateRates() = %d, %v, want 1, nil", n, err)
+	}
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"errors"
+	"finuchet-bot/internal/currency"
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrDebtNotFound        = errors.New("debt not found")
+	ErrDebtDirection       = errors.New("unknown debt direction")
+	ErrInvalidCounterparty = errors.New("invalid counterparty")
+	ErrDebtPaymentType     = errors.New("transaction type does not match debt direction")
+	ErrDebtCurrency        = errors.New("transaction currency does not match debt currency")
+	ErrDebtOverpaid        = errors.New("payment exceeds remaining debt")
+)
+
+// Направления долга
+const (
+	DebtLent     = "lent"     // Пользователь дал в долг: погашения - доходы
+	DebtBorrowed = "borrowed" // Пользователь занял: погашения - расходы
+)
+
+const (
+	maxCounterpartyLength = 100 // Соответствует VARCHAR(100) в debts
+	debtReminderDays      = 7   // Как часто повторять напоминание о просроченном долге
+)
+
+// Итог по человеку в одной валюте: положительный - должны пользователю, отрицательный - должен он
+type DebtBalance struct {
+	Counterparty string
+	Currency     string
+	Amount       money.Amount
+}
+
+// PaymentType возвращает тип операций, которыми погашается долг направления direction
+func PaymentType(direction string) string {
+	if direction == DebtBorrowed {
+		return "expense"
+	}
+	return "income"
+}
+
+// CreateDebt записывает долг. Без валюты долг в базовой валюте, без даты - сегодняшний;
+// нулевой DueDate - долг без срока.
+func (s *FinanceService) CreateDebt(chatID int64, debt *models.Debt) error {
+	if debt.Direction != DebtLent && debt.Direction != DebtBorrowed {
+		return ErrDebtDirection
+	}
+	if err := checkAmount(debt.Amount); err != nil {
+		return err
+	}
+	counterparty, err := validateCounterparty(debt.Counterparty)
+	if err != nil {
+		return err
+	}
+	if utf8.RuneCountInString(debt.Note) > maxNoteLength {
+		return ErrNoteTooLong
+	}
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+
+	debt.UserID = user.ID
+	debt.Counterparty = counterparty
+	if debt.Currency == "" {
+		debt.Currency = user.BaseCurrency
+	} else {
+		code, ok := currency.Parse(debt.Currency)
+		if !ok {
+			return ErrUnknownCurrency
+		}
+		debt.Currency = code
+	}
+	if debt.Date.IsZero() {
+		debt.Date = dates.Day(s.now(user))
+	}
+	return s.repo.CreateDebt(debt)
+}
+
+// GetDebts возвращает открытые долги или, с withClosed, все долги пользователя
+func (s *FinanceService) GetDebts(chatID int64, withClosed bool) ([]*models.Debt, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetDebts(user.ID, withClosed)
+}
+
+func (s *FinanceService) GetDebt(chatID, debtID int64) (*models.Debt, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return s.debt(user, debtID)
+}
+
+// DebtBalances сводит остатки открытых долгов по людям: долги одного человека в одной валюте
+// взаимно учитываются, имена сравниваются без учета регистра. Итоги упорядочены по имени.
+func DebtBalances(debts []*models.Debt) []*DebtBalance {
+	byKey := make(map[string]*DebtBalance)
+	var balances []*DebtBalance
+	for _, d := range debts {
+		if !d.IsOpen() {
+			continue
+		}
+		key := strings.ToLower(d.Counterparty) + "|" + d.Currency
+		balance, ok := byKey[key]
+		if !ok {
+			balance = &DebtBalance{Counterparty: d.Counterparty, Currency: d.Currency}
+			byKey[key] = balance
+			balances = append(balances, balance)
+		}
+		if d.Direction == DebtBorrowed {
+			balance.Amount -= d.Remaining()
+		} else {
+			balance.Amount += d.Remaining()
+		}
+	}
+	sort.SliceStable(balances, func(i, j int) bool {
+		return strings.ToLower(balances[i].Counterparty) < strings.ToLower(balances[j].Counterparty)
+	})
+	return balances
+}
+
+// SetDebtDueDate меняет срок возврата; нулевой due снимает срок
+func (s *FinanceService) SetDebtDueDate(chatID, debtID int64, due time.Time) error {
+	return s.updateDebt(chatID, debtID, func(d *models.Debt) {
+		d.DueDate = due
+	})
+}
+
+// CloseDebt закрывает долг без полного погашения (closed=true) или снова открывает его
+func (s *FinanceService) CloseDebt(chatID, debtID int64, closed bool) error {
+	return s.updateDebt(chatID, debtID, func(d *models.Debt) {
+		d.Closed = closed
+	})
+}
+
+// DeleteDebt удаляет долг; операции погашений остаются обычными доходами и расходами
+func (s *FinanceService) DeleteDebt(chatID, debtID int64) error {
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteDebt(user.ID, debtID)
+}
+
+// SetTransactionDebt засчитывает существующую операцию в погашение долга;
+// нулевой debtID отменяет погашение
+func (s *FinanceService) SetTransactionDebt(chatID, transactionID, debtID int64) error {
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+	t, err := s.transaction(user, transactionID)
+	if err != nil {
+		return err
+	}
+	if debtID != 0 {
+		if t.DebtID == debtID {
+			return nil
+		}
+		t.DebtID = debtID
+		if err := s.checkDebtPayment(user, t); err != nil {
+			return err
+		}
+	}
+	return s.repo.SetTransactionDebt(t.ID, debtID)
+}
+
+// ClaimOverdueDebts забирает просроченные долги, о которых пора напомнить
+func (s *FinanceService) ClaimOverdueDebts(now time.Time) ([]*models.Debt, error) {
+	return s.repo.ClaimOverdueDebts(now, debtReminderDays)
+}
+
+// Проверка погашения: открытый долг пользователя, подходящие тип и валюта операции,
+// сумма не больше остатка долга
+func (s *FinanceService) checkDebtPayment(user *models.User, t *models.Transaction) error {
+	debt, err := s.debt(user, t.DebtID)
+	if err != nil {
+		return err
+	}
+	if t.Type != PaymentType(debt.Direction) {
+		return ErrDebtPaymentType
+	}
+	if t.Currency != debt.Currency {
+		return ErrDebtCurrency
+	}
+	if !debt.IsOpen() || t.Amount > debt.Remaining() {
+		return ErrDebtOverpaid
+	}
+	t.Debt = debt.Counterparty
+	return nil
+}
+
+func (s *FinanceService) updateDebt(chatID, debtID int64, update func(*models.Debt)) error {
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+	debt, err := s.debt(user, debtID)
+	if err != nil {
+		return err
+	}
+	update(debt)
+	return s.repo.UpdateDebt(debt)
+}
+
+// Долг пользователя или ErrDebtNotFound
+func (s *FinanceService) debt(user *models.User, debtID int64) (*models.Debt, error) {
+	debt, err := s.repo.GetDebt(user.ID, debtID)
+	if err != nil {
+		return nil, err
+	}
+	if debt == nil {
+		return nil, ErrDebtNotFound
+	}
+	return debt, nil
+}
+
+func validateCounterparty(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || utf8.RuneCountInString(name) > maxCounterpartyLength {
+		return "", ErrInvalidCounterparty
+	}
+	return name, nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/digest"
+	"finuchet-bot/internal/models"
+	"fmt"
+	"time"
+)
+
+var ErrDigestSchedule = errors.New("invalid digest schedule")
+
+// Сводка, которую пора отправить
+type DigestRun struct {
+	*models.Digest
+	Period dates.Period // Период, который описывает сводка
+	Missed int          // Сколько более ранних отправок пропущено, пока бот не работал
+}
+
+// SetDigest подписывает пользователя на сводку с отправкой в minute минут от полуночи по его времени.
+// Повторная подписка той же периодичности меняет время отправки.
+func (s *FinanceService) SetDigest(chatID int64, frequency string, minute int) (*models.Digest, error) {
+	if minute < 0 || minute >= 24*60 {
+		return nil, ErrDigestSchedule
+	}
+	if frequency != digest.Daily && frequency != digest.Weekly && frequency != digest.Monthly {
+		return nil, ErrDigestSchedule
+	}
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &models.Digest{UserID: user.ID, ChatID: chatID, Timezone: user.Timezone, Frequency: frequency, Minute: minute}
+	d.NextRun = digestSchedule(d).Next(time.Now())
+	return d, s.repo.SetDigest(d)
+}
+
+// DeleteDigest отменяет подписку на сводку
+func (s *FinanceService) DeleteDigest(chatID int64, frequency string) error {
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteDigest(user.ID, frequency)
+}
+
+// GetDigests возвращает подписки пользователя на сводки
+func (s *FinanceService) GetDigests(chatID int64) ([]*models.Digest, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetDigests(user.ID)
+}
+
+// ClaimDueDigests выбирает сводки, время которых наступило к now, и переносит их следующую
+// отправку. Если бот не работал и пропустил несколько отправок, возвращается одна сводка
+// за последний завершившийся период. Сводку, уже забранную другим запуском, второй раз не вернет.
+func (s *FinanceService) ClaimDueDigests(now time.Time) ([]*DigestRun, error) {
+	due, err := s.repo.GetDueDigests(now)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []*DigestRun
+	var errs []error
+	for _, d := range due {
+		schedule := digestSchedule(d)
+		run := &DigestRun{Digest: d}
+		last := d.NextRun
+		for next := schedule.Next(last); !next.After(now); next = schedule.Next(last) {
+			last = next
+			run.Missed++
+		}
+
+		ok, err := s.repo.ClaimDigest(d, schedule.Next(last), now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("digest %d: %w", d.ID, err))
+			continue
+		}
+		if !ok {
+			continue
+		}
+		run.Period = schedule.Period(last)
+		runs = append(runs, run)
+	}
+	return runs, errors.Join(errs...)
+}
+
+func digestSchedule(d *models.Digest) digest.Schedule {
+	return digest.Schedule{Frequency: d.Frequency, Minute: d.Minute, Location: dates.Location(d.Timezone)}
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/export"
+	"fmt"
+	"io"
+)
+
+// Выгрузка операций за период в формате CSV, XLSX или JSON.
+// Файл формируется потоково: операции читаются из базы и сразу пишутся в возвращаемый поток,
+// поэтому вызывающий код обязан закрыть его, даже если не дочитал до конца.
+func (s *FinanceService) ExportData(chatID int64, format string, period dates.Period) (io.ReadCloser, string, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !export.Supported(format) {
+		return nil, "", export.ErrUnknownFormat
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		w, err := export.NewWriter(format, pw)
+		if err == nil {
+			err = s.repo.IterateTransactions(user.ID, period.From, period.To, w.Write)
+			if closeErr := w.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		pw.CloseWithError(err)
+	}()
+
+	filename := fmt.Sprintf("finuchet-%s.%s", s.now(user).Format("2006-01-02"), format)
+	return pr, filename, nil
+}
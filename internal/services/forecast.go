@@ -0,0 +1,116 @@
+package services
+
+import (
+	"errors"
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/forecast"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"time"
+)
+
+// Сколько прошлых месяцев загружать для прогноза: сезонность сравнивает с тем же месяцем год назад
+const forecastHistoryMonths = 12
+
+// Прогноз на конец текущего месяца
+type Forecast struct {
+	*forecast.Forecast
+	Currency     string       // Базовая валюта, в которой посчитаны суммы
+	Balance      money.Amount // Текущий остаток всех счетов
+	MissingRates []string     // Валюты без курса: их операции, правила и счета не вошли в суммы
+}
+
+// EndBalance возвращает ожидаемый остаток всех счетов на конец месяца
+func (f *Forecast) EndBalance() money.Amount {
+	return f.Balance + f.Remaining()
+}
+
+// GetForecast прогнозирует доходы, расходы и остаток на конец текущего месяца
+// по операциям месяца, повторяющимся правилам и прошлым месяцам
+func (s *FinanceService) GetForecast(chatID int64) (*Forecast, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now(user)
+	month := dates.MonthStart(now)
+	next := month.AddDate(0, 1, 0)
+	from := month.AddDate(0, -forecastHistoryMonths, 0)
+
+	input := forecast.Input{Today: now}
+	if input.History, err = s.repo.GetCategoryMonthTotals(user.ID, from, month); err != nil {
+		return nil, err
+	}
+	if input.Current, err = s.repo.GetCategoryMonthTotals(user.ID, month, next); err != nil {
+		return nil, err
+	}
+	result := &Forecast{Currency: user.BaseCurrency}
+	if result.MissingRates, err = s.repo.GetMissingRates(user.ID, from, next, models.TransactionFilter{}); err != nil {
+		return nil, err
+	}
+	missing := func(code string) {
+		for _, c := range result.MissingRates {
+			if c == code {
+				return
+			}
+		}
+		result.MissingRates = append(result.MissingRates, code)
+	}
+
+	if input.Planned, err = s.plannedRecurring(user, month, now, missing); err != nil {
+		return nil, err
+	}
+	accounts, err := s.repo.GetAccountBalances(user.ID, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range accounts {
+		amount, err := s.convert(user, a.Balance, a.Currency, now)
+		if errors.Is(err, ErrNoRate) {
+			missing(a.Currency)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Balance += amount
+	}
+
+	result.Forecast = forecast.Project(input)
+	return result, nil
+}
+
+// Операции повторяющихся правил, которые планировщик еще создаст в месяце month, в базовой валюте
+// по курсу на now. Пропущенные планировщиком даты этого месяца тоже учитываются: он их догонит.
+func (s *FinanceService) plannedRecurring(user *models.User, month, now time.Time, missing func(string)) ([]*models.CategoryMonthTotal, error) {
+	rules, err := s.repo.GetRecurringRules(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	var planned []*models.CategoryMonthTotal
+	for _, rule := range rules {
+		if rule.Paused || rule.NextDate.IsZero() {
+			continue
+		}
+		from := rule.NextDate
+		if from.Before(month) {
+			from = month
+		}
+		count := len(ruleSchedule(rule).Between(from, month.AddDate(0, 1, -1), 31))
+		if count == 0 {
+			continue
+		}
+		amount, err := s.convert(user, rule.Amount.Mul(float64(count)), rule.Currency, now)
+		if errors.Is(err, ErrNoRate) {
+			missing(rule.Currency)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		planned = append(planned, &models.CategoryMonthTotal{
+			CategoryID: rule.CategoryID, Category: rule.Category, Emoji: rule.Emoji, Type: rule.Type, Recurring: amount,
+		})
+	}
+	return planned, nil
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"errors"
+	"finuchet-bot/internal/currency"
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"finuchet-bot/internal/repository"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrGoalNotFound    = errors.New("goal not found")
+	ErrGoalExists      = errors.New("goal already exists")
+	ErrInvalidGoalName = errors.New("invalid goal name")
+	ErrGoalCurrency    = errors.New("transaction currency does not match goal currency")
+	ErrGoalAllocated   = errors.New("contribution exceeds unallocated transaction amount")
+)
+
+const maxGoalNameLength = 100 // Соответствует VARCHAR(100) в goals
+
+// CreateGoal создает цель накоплений. Без валюты цель в базовой валюте; нулевой Deadline - цель без срока.
+func (s *FinanceService) CreateGoal(chatID int64, goal *models.Goal) error {
+	if err := checkAmount(goal.Target); err != nil {
+		return err
+	}
+	name, err := validateGoalName(goal.Name)
+	if err != nil {
+		return err
+	}
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+
+	goal.UserID = user.ID
+	goal.Name = name
+	if goal.Currency == "" {
+		goal.Currency = user.BaseCurrency
+	} else {
+		code, ok := currency.Parse(goal.Currency)
+		if !ok {
+			return ErrUnknownCurrency
+		}
+		goal.Currency = code
+	}
+	if err := s.repo.CreateGoal(goal); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return ErrGoalExists
+		}
+		return err
+	}
+	return nil
+}
+
+// GetGoals возвращает цели пользователя с накопленными суммами
+func (s *FinanceService) GetGoals(chatID int64) ([]*models.Goal, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetGoals(user.ID)
+}
+
+func (s *FinanceService) GetGoal(chatID, goalID int64) (*models.Goal, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return s.goal(user, goalID)
+}
+
+// GetGoalContributions возвращает последние limit взносов в цель
+func (s *FinanceService) GetGoalContributions(chatID, goalID int64, limit int) ([]*models.GoalContribution, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	goal, err := s.goal(user, goalID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetGoalContributions(goal.ID, limit)
+}
+
+// SetGoalTarget меняет сумму цели. completed сообщает, что с новой суммой цель оказалась достигнута.
+func (s *FinanceService) SetGoalTarget(chatID, goalID int64, target money.Amount) (completed bool, err error) {
+	if err := checkAmount(target); err != nil {
+		return false, err
+	}
+	return s.updateGoal(chatID, goalID, func(g *models.Goal) {
+		g.Target = target
+	})
+}
+
+// SetGoalDeadline меняет срок цели; нулевой deadline снимает срок
+func (s *FinanceService) SetGoalDeadline(chatID, goalID int64, deadline time.Time) error {
+	_, err := s.updateGoal(chatID, goalID, func(g *models.Goal) {
+		g.Deadline = deadline
+	})
+	return err
+}
+
+// DeleteGoal удаляет цель вместе со взносами
+func (s *FinanceService) DeleteGoal(chatID, goalID int64) error {
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteGoal(user.ID, goalID)
+}
+
+// Contribute вносит в цель отложенные сегодня деньги в валюте цели.
+// completed сообщает, что этим взносом цель достигнута.
+func (s *FinanceService) Contribute(chatID, goalID int64, amount money.Amount) (completed bool, err error) {
+	if err := checkAmount(amount); err != nil {
+		return false, err
+	}
+	user, err := s.user(chatID)
+	if err != nil {
+		return false, err
+	}
+	goal, err := s.goal(user, goalID)
+	if err != nil {
+		return false, err
+	}
+	return s.addContribution(&models.GoalContribution{GoalID: goal.ID, Amount: amount, Date: dates.Day(s.now(user))})
+}
+
+// ContributeFromTransaction вносит в цель часть дохода или отдельную операцию, например перевод в копилку.
+// Нулевой amount означает всю еще не распределенную по целям сумму операции; больше нее внести нельзя.
+func (s *FinanceService) ContributeFromTransaction(chatID, goalID, transactionID int64, amount money.Amount) (completed bool, err error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return false, err
+	}
+	goal, err := s.goal(user, goalID)
+	if err != nil {
+		return false, err
+	}
+	t, err := s.transaction(user, transactionID)
+	if err != nil {
+		return false, err
+	}
+	if t.Currency != goal.Currency {
+		return false, ErrGoalCurrency
+	}
+	allocated, err := s.repo.GetAllocatedAmount(t.ID)
+	if err != nil {
+		return false, err
+	}
+	free := t.Amount - allocated
+	if amount == 0 {
+		amount = free
+	}
+	if amount <= 0 || amount > free {
+		return false, ErrGoalAllocated
+	}
+	return s.addContribution(&models.GoalContribution{GoalID: goal.ID, Amount: amount, Date: t.Date, TransactionID: t.ID})
+}
+
+// UndoGoalContribution отменяет последний взнос в цель; операция, из которой он сделан, остается
+func (s *FinanceService) UndoGoalContribution(chatID, goalID int64) error {
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+	goal, err := s.goal(user, goalID)
+	if err != nil {
+		return err
+	}
+	last, err := s.repo.GetGoalContributions(goal.ID, 1)
+	if err != nil || len(last) == 0 {
+		return err
+	}
+	if err := s.repo.DeleteGoalContribution(goal.ID, last[0].ID); err != nil {
+		return err
+	}
+	_, err = s.repo.UpdateGoalCompletion(goal.ID, time.Now())
+	return err
+}
+
+// MonthlyRate возвращает, сколько нужно откладывать в месяц, чтобы успеть к сроку цели: остаток
+// делится на месяцы с текущего до месяца срока, а срок 1-го числа свой месяц не включает.
+// С прошедшим сроком нужен весь остаток сразу. ok = false у цели без срока.
+func MonthlyRate(goal *models.Goal, today time.Time) (rate money.Amount, ok bool) {
+	if goal.Deadline.IsZero() {
+		return 0, false
+	}
+	d := goal.Deadline
+	months := (d.Year()-today.Year())*12 + int(d.Month()-today.Month())
+	if d.Day() > 1 {
+		months++
+	}
+	if months < 1 {
+		months = 1
+	}
+	return goal.Remaining().Div(int64(months)), true
+}
+
+func (s *FinanceService) addContribution(contribution *models.GoalContribution) (bool, error) {
+	if err := s.repo.AddGoalContribution(contribution); err != nil {
+		return false, err
+	}
+	return s.repo.UpdateGoalCompletion(contribution.GoalID, time.Now())
+}
+
+func (s *FinanceService) updateGoal(chatID, goalID int64, update func(*models.Goal)) (bool, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return false, err
+	}
+	goal, err := s.goal(user, goalID)
+	if err != nil {
+		return false, err
+	}
+	update(goal)
+	if err := s.repo.UpdateGoal(goal); err != nil {
+		return false, err
+	}
+	return s.repo.UpdateGoalCompletion(goal.ID, time.Now())
+}
+
+// Цель пользователя или ErrGoalNotFound
+func (s *FinanceService) goal(user *models.User, goalID int64) (*models.Goal, error) {
+	goal, err := s.repo.GetGoal(user.ID, goalID)
+	if err != nil {
+		return nil, err
+	}
+	if goal == nil {
+		return nil, ErrGoalNotFound
+	}
+	return goal, nil
+}
+
+func validateGoalName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || utf8.RuneCountInString(name) > maxGoalNameLength {
+		return "", ErrInvalidGoalName
+	}
+	return name, nil
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"errors"
+	"finuchet-bot/internal/bankimport"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/quickentry"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrEmptyStatement - в выписке не нашлось ни одной операции
+var ErrEmptyStatement = errors.New("no transactions in statement")
+
+// Категория, в которую попадают нераспознанные расходы
+const fallbackExpenseCategory = "Остальное"
+
+// Срок, за который история операций используется для подбора категорий
+const importHistoryDepth = 365 * 24 * time.Hour
+
+// Строка выписки, подготовленная к импорту
+type ImportRow struct {
+	bankimport.Row
+	Category  *models.Category // nil, если категорию подобрать не удалось
+	Duplicate bool             // Операция уже есть в базе
+	hash      string
+}
+
+// Результат разбора или импорта выписки
+type ImportResult struct {
+	Rows       []ImportRow
+	Skipped    int   // Нераспознанные строки файла
+	SkipErr    error // Причина пропуска первой из них
+	Duplicates int
+	Imported   int // Заполняется только при импорте
+}
+
+// PrepareImport разбирает выписку, отмечает дубли и подбирает категории, ничего не сохраняя
+func (s *FinanceService) PrepareImport(chatID int64, r io.Reader, layout bankimport.Layout) (*ImportResult, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return s.prepareImport(user, r, layout)
+}
+
+// ImportTransactions загружает новые операции из выписки одной транзакцией БД
+func (s *FinanceService) ImportTransactions(chatID int64, r io.Reader, layout bankimport.Layout) (*ImportResult, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.prepareImport(user, r, layout)
+	if err != nil {
+		return nil, err
+	}
+
+	var transactions []*models.Transaction
+	for _, row := range result.Rows {
+		if row.Duplicate {
+			continue
+		}
+		transaction := &models.Transaction{
+			UserID:     user.ID,
+			Amount:     row.Amount,
+			Type:       row.Type,
+			Currency:   user.BaseCurrency,
+			Date:       row.Date,
+			Note:       truncate(row.Description, maxNoteLength),
+			ImportHash: row.hash,
+		}
+		if row.Currency != "" {
+			transaction.Currency = row.Currency
+		}
+		if row.Category != nil {
+			transaction.CategoryID = row.Category.ID
+		}
+		transactions = append(transactions, transaction)
+	}
+	if len(transactions) == 0 {
+		return result, nil
+	}
+
+	inserted, err := s.repo.BulkInsertTransactions(transactions)
+	if err != nil {
+		return nil, err
+	}
+	// Операции, загруженные параллельно или отредактированные после прошлого импорта, отсекает уникальный индекс
+	result.Duplicates += len(transactions) - inserted
+	result.Imported = inserted
+	return result, nil
+}
+
+func (s *FinanceService) prepareImport(user *models.User, r io.Reader, layout bankimport.Layout) (*ImportResult, error) {
+	parsed, err := bankimport.Parse(r, layout)
+	if err != nil {
+		return nil, err
+	}
+	if len(parsed.Rows) == 0 {
+		if parsed.FirstErr != nil {
+			return nil, fmt.Errorf("%w: %v", ErrEmptyStatement, parsed.FirstErr)
+		}
+		return nil, ErrEmptyStatement
+	}
+
+	categories, err := s.repo.GetCategories(user.ID, "", false)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := parsed.Rows[0].Date, parsed.Rows[0].Date
+	for _, row := range parsed.Rows {
+		if row.Date.Before(from) {
+			from = row.Date
+		}
+		if row.Date.After(to) {
+			to = row.Date
+		}
+	}
+	to = to.AddDate(0, 0, 1)
+
+	// Существующие операции: сколько раз встречается каждый отпечаток и какую категорию
+	// пользователь выбирал для такого же описания
+	existing := make(map[string]int)
+	history := make(map[string]int64)
+	err = s.repo.IterateTransactions(user.ID, from.Add(-importHistoryDepth), to, func(t *models.Transaction) error {
+		if !t.Date.Before(from) {
+			hash := bankimport.Hash(t.Date, t.Type, t.Amount, t.Note)
+			existing[hash]++
+			// Заметку импортированной операции могли изменить, отпечаток выписки сохраняется
+			if t.ImportHash != "" && t.ImportHash != hash {
+				existing[t.ImportHash]++
+			}
+		}
+		if t.CategoryID != 0 && t.Note != "" {
+			history[t.Type+"|"+normalizeDescription(t.Note)] = t.CategoryID
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{Skipped: parsed.Skipped, SkipErr: parsed.FirstErr}
+	seen := make(map[string]int)
+	for _, row := range parsed.Rows {
+		hash := row.Hash()
+		seen[hash]++
+		// Одинаковые покупки в один день различаются порядковым номером
+		importHash := hash
+		if n := seen[hash]; n > 1 {
+			importHash = bankimport.Hash(row.Date, row.Type, row.Amount, fmt.Sprintf("%s#%d", row.Description, n))
+		}
+
+		item := ImportRow{
+			Row:       row,
+			Category:  matchImportCategory(row, categories, history),
+			Duplicate: seen[hash] <= existing[hash] || (importHash != hash && existing[importHash] > 0),
+			hash:      importHash,
+		}
+		if item.Duplicate {
+			result.Duplicates++
+		}
+		result.Rows = append(result.Rows, item)
+	}
+	return result, nil
+}
+
+// Категория по описанию: как в прошлых операциях, затем по словам описания,
+// для расходов - "Остальное"
+func matchImportCategory(row bankimport.Row, categories []*models.Category, history map[string]int64) *models.Category {
+	var ofType []*models.Category
+	for _, c := range categories {
+		if c.Type == row.Type {
+			ofType = append(ofType, c)
+		}
+	}
+
+	if id, ok := history[row.Type+"|"+normalizeDescription(row.Description)]; ok {
+		for _, c := range ofType {
+			if c.ID == id {
+				return c
+			}
+		}
+	}
+
+	for _, word := range strings.FieldsFunc(row.Description, isSeparator) {
+		if c, ok := quickentry.MatchCategory(word, ofType); ok {
+			return c
+		}
+	}
+
+	if row.Type == "expense" {
+		for _, c := range ofType {
+			if c.Name == fallbackExpenseCategory {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+func normalizeDescription(description string) string {
+	return strings.ToLower(strings.Join(strings.FieldsFunc(description, isSeparator), " "))
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// Обрезка строки до max символов
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"finuchet-bot/internal/bankimport"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/repository"
+	"strings"
+	"testing"
+	"time"
+)
+
+// importRepo хранит операции в памяти; уникальность ImportHash проверяется, как индексом в базе
+type importRepo struct {
+	repository.Repository
+	transactions []*models.Transaction
+}
+
+func (r *importRepo) GetLedgerByChatID(chatID int64) (*models.User, error) {
+	return &models.User{ID: 1, ChatID: chatID, BaseCurrency: "RUB", Timezone: "UTC"}, nil
+}
+
+func (r *importRepo) GetCategories(int64, string, bool) ([]*models.Category, error) {
+	return []*models.Category{
+		{ID: 1, Name: "Такси", Type: "expense"},
+		{ID: 2, Name: "Остальное", Type: "expense"},
+		{ID: 3, Name: "Зарплата", Type: "income"},
+	}, nil
+}
+
+func (r *importRepo) IterateTransactions(_ int64, from, to time.Time, fn func(*models.Transaction) error) error {
+	for _, t := range r.transactions {
+		if !t.Date.Before(from) && t.Date.Before(to) {
+			if err := fn(t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *importRepo) BulkInsertTransactions(transactions []*models.Transaction) (int, error) {
+	inserted := 0
+next:
+	for _, t := range transactions {
+		for _, existing := range r.transactions {
+			if t.ImportHash != "" && existing.ImportHash == t.ImportHash {
+				continue next
+			}
+		}
+		r.transactions = append(r.transactions, t)
+		inserted++
+	}
+	return inserted, nil
+}
+
+// Две одинаковые поездки в один день - разные операции
+const statement = `Дата;Сумма;Описание
+05.03.2026;-350,00;Яндекс Такси
+05.03.2026;-350,00;Яндекс Такси
+04.03.2026;85 000,00;Зарплата
+03.03.2026;-1 200,50;Аптека
+`
+
+func TestImportTwiceAddsNothing(t *testing.T) {
+	repo := &importRepo{}
+	s := NewFinanceService(repo)
+	layout := bankimport.DefaultLayouts()[1] // generic
+
+	first, err := s.ImportTransactions(100, strings.NewReader(statement), layout)
+	if err != nil {
+		t.Fatalf("first import error = %v", err)
+	}
+	if first.Imported != 4 || first.Duplicates != 0 {
+		t.Fatalf("first import: imported %d, duplicates %d; want 4 and 0", first.Imported, first.Duplicates)
+	}
+	categories := map[string]int64{"Яндекс Такси": 1, "Зарплата": 3, "Аптека": 2}
+	for _, tr := range repo.transactions {
+		if tr.CategoryID != categories[tr.Note] {
+			t.Errorf("%q imported into category %d, want %d", tr.Note, tr.CategoryID, categories[tr.Note])
+		}
+	}
+
+	second, err := s.ImportTransactions(100, strings.NewReader(statement), layout)
+	if err != nil {
+		t.Fatalf("second import error = %v", err)
+	}
+	if second.Imported != 0 || second.Duplicates != 4 || len(repo.transactions) != 4 {
+		t.Errorf("second import: imported %d, duplicates %d, stored %d; want 0, 4, 4",
+			second.Imported, second.Duplicates, len(repo.transactions))
+	}
+
+	// Выписка за более длинный период добавляет только новые строки
+	longer := statement + "02.03.2026;-350,00;Яндекс Такси\n"
+	third, err := s.ImportTransactions(100, strings.NewReader(longer), layout)
+	if err != nil {
+		t.Fatalf("third import error = %v", err)
+	}
+	if third.Imported != 1 || third.Duplicates != 4 {
+		t.Errorf("third import: imported %d, duplicates %d; want 1 and 4", third.Imported, third.Duplicates)
+	}
+}
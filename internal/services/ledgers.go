@@ -0,0 +1,248 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"finuchet-bot/internal/models"
+	"time"
+)
+
+var (
+	ErrNotOwner        = errors.New("only the ledger owner can manage members")
+	ErrNotMember       = errors.New("not a member of the ledger")
+	ErrOwnerRole       = errors.New("owner role cannot be changed")
+	ErrInvalidRole     = errors.New("invalid member role")
+	ErrInviteNotFound  = errors.New("invite not found or expired")
+	ErrMemberNotFound  = errors.New("member not found")
+	ErrLedgerSelection = errors.New("ledger can be selected only in a private chat")
+)
+
+// Роли участников учета
+const (
+	RoleOwner  = "owner"  // Все действия, включая управление участниками и очистку данных
+	RoleEditor = "editor" // Ввод и изменение операций, категорий, бюджетов и счетов
+	RoleViewer = "viewer" // Только отчеты, история и выгрузка
+)
+
+// Сколько действует ссылка-приглашение
+const inviteTTL = 7 * 24 * time.Hour
+
+// CanEdit сообщает, может ли участник с ролью role изменять данные учета
+func CanEdit(role string) bool {
+	return role == RoleOwner || role == RoleEditor
+}
+
+// MemberRole возвращает роль участника member в учете чата; пустая роль - не участник.
+// У учета без участников, например группы, заведенной до появления ролей,
+// владельцем становится первый обратившийся к боту.
+func (s *FinanceService) MemberRole(chatID int64, member *models.Member) (string, error) {
+	ledger, err := s.user(chatID)
+	if err != nil {
+		return "", err
+	}
+	current, err := s.repo.GetMember(ledger.ID, member.ID)
+	if err != nil {
+		return "", err
+	}
+	if current != nil {
+		// Имя в Telegram могло измениться; оно нужно для разбивки отчета по участникам
+		if member.Name != "" && member.Name != current.Name {
+			if err := s.repo.SetMemberName(ledger.ID, member.ID, member.Name); err != nil {
+				return "", err
+			}
+		}
+		return current.Role, nil
+	}
+	claimed, err := s.repo.ClaimLedger(&models.Member{LedgerID: ledger.ID, ID: member.ID, Name: member.Name})
+	if err != nil || !claimed {
+		return "", err
+	}
+	return RoleOwner, nil
+}
+
+// GetMembers возвращает участников учета чата
+func (s *FinanceService) GetMembers(chatID int64) ([]*models.Member, error) {
+	ledger, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetMembers(ledger.ID)
+}
+
+// SetMemberRole меняет роль участника memberID; менять роли может только владелец,
+// а роль самого владельца не меняется
+func (s *FinanceService) SetMemberRole(chatID, actorID, memberID int64, role string) error {
+	if role != RoleEditor && role != RoleViewer {
+		return ErrInvalidRole
+	}
+	ledger, member, err := s.manageMember(chatID, actorID, memberID)
+	if err != nil {
+		return err
+	}
+	return s.repo.SetMemberRole(ledger.ID, member.ID, role)
+}
+
+// RemoveMember исключает участника memberID из учета. Исключать может владелец,
+// выйти из учета - сам участник; владелец учет не покидает.
+func (s *FinanceService) RemoveMember(chatID, actorID, memberID int64) error {
+	if actorID == memberID {
+		ledger, err := s.user(chatID)
+		if err != nil {
+			return err
+		}
+		member, err := s.member(ledger, memberID)
+		if err != nil {
+			return err
+		}
+		if member.Role == RoleOwner {
+			return ErrOwnerRole
+		}
+		return s.repo.DeleteMember(ledger.ID, memberID)
+	}
+	ledger, member, err := s.manageMember(chatID, actorID, memberID)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteMember(ledger.ID, member.ID)
+}
+
+// CreateInvite создает одноразовое приглашение в учет чата с ролью role; приглашать может только владелец
+func (s *FinanceService) CreateInvite(chatID, actorID int64, role string) (*models.Invite, error) {
+	if role != RoleEditor && role != RoleViewer {
+		return nil, ErrInvalidRole
+	}
+	ledger, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireOwner(ledger, actorID); err != nil {
+		return nil, err
+	}
+	token := make([]byte, 12)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	invite := &models.Invite{
+		Token:     hex.EncodeToString(token),
+		LedgerID:  ledger.ID,
+		Role:      role,
+		CreatedBy: actorID,
+		ExpiresAt: time.Now().Add(inviteTTL),
+	}
+	return invite, s.repo.CreateInvite(invite)
+}
+
+// AcceptInvite добавляет member в учет по приглашению token и переключает личный чат
+// на этот учет. Чат должен быть зарегистрирован. Возвращает участие в учете вместе с его названием.
+func (s *FinanceService) AcceptInvite(chatID int64, member *models.Member, token string) (*models.Member, error) {
+	own, err := s.ownUser(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if own.ChatID < 0 {
+		return nil, ErrLedgerSelection
+	}
+	invite, err := s.repo.TakeInvite(token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if invite == nil {
+		return nil, ErrInviteNotFound
+	}
+	if invite.LedgerID != own.ID {
+		err := s.repo.AddMember(&models.Member{LedgerID: invite.LedgerID, ID: member.ID, Name: member.Name, Role: invite.Role})
+		if err != nil {
+			return nil, err
+		}
+		if err := s.repo.SetUserLedger(own.ID, invite.LedgerID); err != nil {
+			return nil, err
+		}
+	}
+
+	ledger, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	joined, err := s.member(ledger, member.ID)
+	if err != nil {
+		return nil, err
+	}
+	joined.LedgerTitle, joined.LedgerChat = ledger.Title, ledger.ChatID
+	return joined, nil
+}
+
+// GetLedgers возвращает учеты, в которых участвует memberID, и учет, который сейчас ведется из чата
+func (s *FinanceService) GetLedgers(chatID, memberID int64) (ledgers []*models.Member, activeID int64, err error) {
+	ledger, err := s.user(chatID)
+	if err != nil {
+		return nil, 0, err
+	}
+	ledgers, err = s.repo.GetMemberships(memberID)
+	return ledgers, ledger.ID, err
+}
+
+// UseLedger переключает личный чат на учет ledgerID, в котором участвует memberID;
+// собственный учет чата выбирается всегда
+func (s *FinanceService) UseLedger(chatID, memberID, ledgerID int64) error {
+	own, err := s.ownUser(chatID)
+	if err != nil {
+		return err
+	}
+	if own.ChatID < 0 {
+		return ErrLedgerSelection
+	}
+	if ledgerID == own.ID {
+		return s.repo.SetUserLedger(own.ID, 0)
+	}
+	member, err := s.repo.GetMember(ledgerID, memberID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return ErrNotMember
+	}
+	return s.repo.SetUserLedger(own.ID, ledgerID)
+}
+
+// Участник, которым управляет владелец actorID; участник-владелец не изменяется
+func (s *FinanceService) manageMember(chatID, actorID, memberID int64) (*models.User, *models.Member, error) {
+	ledger, err := s.user(chatID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.requireOwner(ledger, actorID); err != nil {
+		return nil, nil, err
+	}
+	member, err := s.member(ledger, memberID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if member.Role == RoleOwner {
+		return nil, nil, ErrOwnerRole
+	}
+	return ledger, member, nil
+}
+
+func (s *FinanceService) requireOwner(ledger *models.User, actorID int64) error {
+	actor, err := s.repo.GetMember(ledger.ID, actorID)
+	if err != nil {
+		return err
+	}
+	if actor == nil || actor.Role != RoleOwner {
+		return ErrNotOwner
+	}
+	return nil
+}
+
+// Участник учета или ErrMemberNotFound
+func (s *FinanceService) member(ledger *models.User, memberID int64) (*models.Member, error) {
+	member, err := s.repo.GetMember(ledger.ID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrMemberNotFound
+	}
+	return member, nil
+}
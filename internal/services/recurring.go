@@ -0,0 +1,209 @@
+package services
+
+import (
+	"errors"
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/recurring"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrRecurringNotFound  = errors.New("recurring rule not found")
+	ErrRecurringFrequency = errors.New("unknown recurring frequency")
+	ErrRecurringDates     = errors.New("recurring rule ends before it starts")
+)
+
+// Сколько пропущенных операций одного правила создается за один запуск планировщика.
+// Остальные будут созданы при следующих запусках.
+const recurringCatchUpLimit = 366
+
+// Результат исполнения правила: операции, созданные за один запуск планировщика
+type RecurringRun struct {
+	Rule         *models.RecurringRule
+	Transactions []*models.Transaction
+}
+
+// Расписание правила
+func ruleSchedule(rule *models.RecurringRule) recurring.Schedule {
+	return recurring.Schedule{
+		Frequency:  rule.Frequency,
+		DayOfMonth: rule.DayOfMonth,
+		Start:      rule.StartDate,
+		End:        rule.EndDate,
+	}
+}
+
+// CreateRecurringRule сохраняет правило повторяющейся операции.
+// Операции с прошедшими датами создаст планировщик при ближайшем запуске.
+func (s *FinanceService) CreateRecurringRule(chatID int64, rule *models.RecurringRule) error {
+	if err := checkAmount(rule.Amount); err != nil {
+		return err
+	}
+	if !recurring.Valid(rule.Frequency) {
+		return ErrRecurringFrequency
+	}
+	if rule.DayOfMonth < 0 || rule.DayOfMonth > 31 {
+		return ErrRecurringFrequency
+	}
+	if utf8.RuneCountInString(rule.Note) > maxNoteLength {
+		return ErrNoteTooLong
+	}
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+	if rule.StartDate.IsZero() {
+		rule.StartDate = dates.Day(s.now(user))
+	}
+	if !rule.EndDate.IsZero() && rule.EndDate.Before(rule.StartDate) {
+		return ErrRecurringDates
+	}
+	category, err := s.category(user, rule.CategoryID)
+	if err != nil {
+		return err
+	}
+	if category.Type != rule.Type {
+		return ErrCategoryType
+	}
+
+	next, ok := ruleSchedule(rule).Next(rule.StartDate)
+	if !ok {
+		return ErrRecurringDates
+	}
+	rule.UserID = user.ID
+	rule.ChatID = chatID
+	rule.Timezone = user.Timezone
+	rule.Category = category.Name
+	rule.Emoji = category.Emoji
+	rule.NextDate = next
+	if rule.Currency == "" {
+		rule.Currency = user.BaseCurrency
+	}
+	return s.repo.CreateRecurringRule(rule)
+}
+
+// GetRecurringRules возвращает правила пользователя
+func (s *FinanceService) GetRecurringRules(chatID int64) ([]*models.RecurringRule, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetRecurringRules(user.ID)
+}
+
+// GetRecurringRule возвращает правило пользователя или ErrRecurringNotFound
+func (s *FinanceService) GetRecurringRule(chatID, ruleID int64) (*models.RecurringRule, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return s.recurringRule(user, ruleID)
+}
+
+// SetRecurringPaused приостанавливает или возобновляет правило.
+// Операции за время паузы не создаются: после возобновления расписание продолжается с сегодняшнего дня.
+func (s *FinanceService) SetRecurringPaused(chatID, ruleID int64, paused bool) error {
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+	rule, err := s.recurringRule(user, ruleID)
+	if err != nil {
+		return err
+	}
+
+	next := rule.NextDate
+	if !paused && rule.Paused && !next.IsZero() {
+		if today := dates.Day(s.now(user)); next.Before(today) {
+			next, _ = ruleSchedule(rule).Next(today)
+		}
+	}
+	return s.repo.SetRecurringRuleState(user.ID, ruleID, paused, next)
+}
+
+// DeleteRecurringRule удаляет правило; уже созданные операции сохраняются
+func (s *FinanceService) DeleteRecurringRule(chatID, ruleID int64) error {
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.recurringRule(user, ruleID); err != nil {
+		return err
+	}
+	return s.repo.DeleteRecurringRule(user.ID, ruleID)
+}
+
+// ProcessRecurring создает операции по всем правилам с датами не позже сегодняшнего дня
+// по часовому поясу владельца, в том числе пропущенные, пока бот не работал. Повторный вызов не создает дублей: дата следующей
+// операции переносится в той же транзакции БД, а операция на дату правила уникальна.
+// Ошибка одного правила не останавливает обработку остальных.
+func (s *FinanceService) ProcessRecurring(now time.Time) ([]*RecurringRun, error) {
+	rules, err := s.repo.GetDueRecurringRules(now)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []*RecurringRun
+	var errs []error
+	for _, rule := range rules {
+		today := dates.Day(now.In(dates.Location(rule.Timezone)))
+		run, err := s.processRecurringRule(rule, today)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("recurring rule %d: %w", rule.ID, err))
+			continue
+		}
+		if len(run.Transactions) > 0 {
+			runs = append(runs, run)
+		}
+	}
+	return runs, errors.Join(errs...)
+}
+
+func (s *FinanceService) processRecurringRule(rule *models.RecurringRule, today time.Time) (*RecurringRun, error) {
+	schedule := ruleSchedule(rule)
+	due := schedule.Between(rule.NextDate, today, recurringCatchUpLimit)
+
+	var next time.Time
+	if len(due) > 0 {
+		next, _ = schedule.Next(due[len(due)-1].AddDate(0, 0, 1))
+	} else {
+		next, _ = schedule.Next(rule.NextDate)
+	}
+
+	transactions := make([]*models.Transaction, 0, len(due))
+	for _, date := range due {
+		transactions = append(transactions, &models.Transaction{
+			UserID:      rule.UserID,
+			Amount:      rule.Amount,
+			Currency:    rule.Currency,
+			CategoryID:  rule.CategoryID,
+			Category:    rule.Category,
+			Type:        rule.Type,
+			Date:        date,
+			Note:        rule.Note,
+			RecurringID: rule.ID,
+		})
+	}
+
+	created, err := s.repo.ApplyRecurringRule(rule, transactions, next)
+	if err != nil {
+		return nil, err
+	}
+	rule.NextDate = next
+	return &RecurringRun{Rule: rule, Transactions: created}, nil
+}
+
+// Правило пользователя или ErrRecurringNotFound
+func (s *FinanceService) recurringRule(user *models.User, ruleID int64) (*models.RecurringRule, error) {
+	rule, err := s.repo.GetRecurringRule(user.ID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil {
+		return nil, ErrRecurringNotFound
+	}
+	return rule, nil
+}
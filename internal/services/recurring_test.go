@@ -0,0 +1,147 @@
+package services
+
+import (
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/recurring"
+	"finuchet-bot/internal/repository"
+	"testing"
+	"time"
+)
+
+// recurringRepo запоминает, что планировщик записал бы в базу
+type recurringRepo struct {
+	repository.Repository
+	due     []*models.RecurringRule
+	created []*models.Transaction
+	next    time.Time
+}
+
+func (r *recurringRepo) GetDueRecurringRules(time.Time) ([]*models.RecurringRule, error) {
+	return r.due, nil
+}
+
+func (r *recurringRepo) ApplyRecurringRule(_ *models.RecurringRule, transactions []*models.Transaction, next time.Time) ([]*models.Transaction, error) {
+	r.created = append(r.created, transactions...)
+	r.next = next
+	return transactions, nil
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestProcessRecurringRule(t *testing.T) {
+	tests := []struct {
+		name      string
+		rule      models.RecurringRule
+		today     time.Time
+		wantDates []time.Time
+		wantNext  time.Time
+	}{
+		{
+			name:      "catches up missed months",
+			rule:      models.RecurringRule{Frequency: recurring.Monthly, StartDate: date(2026, 1, 31), NextDate: date(2026, 1, 31)},
+			today:     date(2026, 4, 15),
+			wantDates: []time.Time{date(2026, 1, 31), date(2026, 2, 28), date(2026, 3, 31)},
+			wantNext:  date(2026, 4, 30),
+		},
+		{
+			name:     "nothing due yet",
+			rule:     models.RecurringRule{Frequency: recurring.Monthly, StartDate: date(2026, 1, 31), NextDate: date(2026, 4, 30)},
+			today:    date(2026, 4, 15),
+			wantNext: date(2026, 4, 30),
+		},
+		{
+			name:      "due today",
+			rule:      models.RecurringRule{Frequency: recurring.Weekly, StartDate: date(2026, 1, 7), NextDate: date(2026, 1, 14)},
+			today:     date(2026, 1, 14),
+			wantDates: []time.Time{date(2026, 1, 14)},
+			wantNext:  date(2026, 1, 21),
+		},
+		{
+			name: "end date is inclusive and finishes the rule",
+			rule: models.RecurringRule{Frequency: recurring.Daily, StartDate: date(2026, 1, 1),
+				EndDate: date(2026, 1, 3), NextDate: date(2026, 1, 2)},
+			today:     date(2026, 1, 10),
+			wantDates: []time.Time{date(2026, 1, 2), date(2026, 1, 3)},
+		},
+		{
+			name:      "leap day yearly",
+			rule:      models.RecurringRule{Frequency: recurring.Yearly, StartDate: date(2024, 2, 29), NextDate: date(2025, 2, 28)},
+			today:     date(2028, 3, 1),
+			wantDates: []time.Time{date(2025, 2, 28), date(2026, 2, 28), date(2027, 2, 28), date(2028, 2, 29)},
+			wantNext:  date(2029, 2, 28),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &recurringRepo{}
+			s := NewFinanceService(repo)
+			rule := tt.rule
+
+			run, err := s.processRecurringRule(&rule, tt.today)
+			if err != nil {
+				t.Fatalf("processRecurringRule() error = %v", err)
+			}
+			if len(run.Transactions) != len(tt.wantDates) {
+				t.Fatalf("created %d transactions, want %d", len(run.Transactions), len(tt.wantDates))
+			}
+			for i, tr := range run.Transactions {
+				if !tr.Date.Equal(tt.wantDates[i]) {
+					t.Errorf("transaction #%d on %s, want %s", i, tr.Date.Format(time.DateOnly), tt.wantDates[i].Format(time.DateOnly))
+				}
+			}
+			if !repo.next.Equal(tt.wantNext) || !rule.NextDate.Equal(tt.wantNext) {
+				t.Errorf("next = %s (rule %s), want %s", repo.next.Format(time.DateOnly),
+					rule.NextDate.Format(time.DateOnly), tt.wantNext.Format(time.DateOnly))
+			}
+		})
+	}
+}
+
+func TestProcessRecurringRuleCatchUpLimit(t *testing.T) {
+	repo := &recurringRepo{}
+	s := NewFinanceService(repo)
+	rule := &models.RecurringRule{Frequency: recurring.Daily, StartDate: date(2020, 1, 1), NextDate: date(2020, 1, 1)}
+	today := date(2026, 1, 1)
+
+	run, err := s.processRecurringRule(rule, today)
+	if err != nil {
+		t.Fatalf("processRecurringRule() error = %v", err)
+	}
+	if len(run.Transactions) != recurringCatchUpLimit {
+		t.Fatalf("created %d transactions, want %d", len(run.Transactions), recurringCatchUpLimit)
+	}
+	// Остальные пропущенные операции создаст следующий запуск
+	if want := date(2021, 1, 1); !rule.NextDate.Equal(want) {
+		t.Fatalf("next = %s, want %s", rule.NextDate.Format(time.DateOnly), want.Format(time.DateOnly))
+	}
+
+	run, err = s.processRecurringRule(rule, today)
+	if err != nil {
+		t.Fatalf("second processRecurringRule() error = %v", err)
+	}
+	if first := run.Transactions[0].Date; !first.Equal(date(2021, 1, 1)) {
+		t.Errorf("second run starts on %s, want 2021-01-01", first.Format(time.DateOnly))
+	}
+}
+
+func TestProcessRecurringUsesOwnerTimezone(t *testing.T) {
+	repo := &recurringRepo{due: []*models.RecurringRule{{
+		ID: 1, Frequency: recurring.Daily, Timezone: "Asia/Vladivostok",
+		StartDate: date(2026, 1, 1), NextDate: date(2026, 1, 1),
+	}}}
+	s := NewFinanceService(repo)
+
+	// 15:00 UTC 1 января - уже 2 января во Владивостоке
+	runs, err := s.ProcessRecurring(time.Date(2026, 1, 1, 15, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("ProcessRecurring() error = %v", err)
+	}
+	if len(runs) != 1 || len(runs[0].Transactions) != 2 {
+		t.Fatalf("ProcessRecurring() created %d transactions, want 2", len(repo.created))
+	}
+	if want := date(2026, 1, 3); !repo.next.Equal(want) {
+		t.Errorf("next = %s, want %s", repo.next.Format(time.DateOnly), want.Format(time.DateOnly))
+	}
+}
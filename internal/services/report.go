@@ -0,0 +1,226 @@
+package services
+
+import (
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"time"
+)
+
+// Сколько крупнейших расходов показывать в отчете
+const topExpensesLimit = 5
+
+// Отчет о доходах и расходах за период
+type Report struct {
+	Period       dates.Period
+	Filter       models.TransactionFilter // Отбор операций по тегу или продавцу
+	Currency     string                   // Базовая валюта, в которой посчитаны суммы
+	Income       money.Amount
+	Expense      money.Amount
+	IncomeCount  int
+	ExpenseCount int
+	Days         []*models.DateTotal // Суммы по дням, в порядке дат
+
+	Categories          []*CategoryShare      // Доли категорий, по убыванию суммы внутри типа
+	TopExpenses         []*models.Transaction // Крупнейшие расходы периода
+	AverageDailyExpense money.Amount          // Средний расход за прошедшие дни периода
+	HasPrevious         bool                  // Есть предыдущий период для сравнения
+	Budgets             []*BudgetStatus       // Бюджеты, если период - один календарный месяц
+
+	Currencies   []*models.CurrencyTotal // Суммы в исходных валютах, если операции были не только в базовой
+	MissingRates []string                // Валюты без курса: их операции не вошли в суммы
+
+	Members []*models.MemberTotal // Суммы по участникам, если операции записывали несколько человек
+
+	Accounts   []*models.Account // Остатки счетов на конец периода; в отчете с фильтром не заполняются
+	AccountsAt time.Time         // Начало дня, на который посчитаны остатки; нулевое - текущие остатки
+}
+
+// Категория в отчете
+type CategoryShare struct {
+	models.CategoryTotal
+	Share    float64      // Доля от всех операций того же типа, в процентах
+	Previous money.Amount // Сумма за предыдущий период
+}
+
+// Change возвращает изменение суммы относительно предыдущего периода в процентах;
+// ok = false, если в предыдущем периоде операций по категории не было
+func (c *CategoryShare) Change() (percent float64, ok bool) {
+	if c.Previous == 0 {
+		return 0, false
+	}
+	return (c.Amount - c.Previous).Percent(c.Previous), true
+}
+
+// Balance - разница доходов и расходов за период
+func (r *Report) Balance() money.Amount {
+	return r.Income - r.Expense
+}
+
+// CategoriesOf возвращает категории отчета одного типа
+func (r *Report) CategoriesOf(transactionType string) []*CategoryShare {
+	var result []*CategoryShare
+	for _, c := range r.Categories {
+		if c.Type == transactionType {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// GetReport считает доходы и расходы за период с разбивкой по категориям; суммирование выполняется в базе
+func (s *FinanceService) GetReport(chatID int64, period dates.Period) (*Report, error) {
+	return s.GetFilteredReport(chatID, period, models.TransactionFilter{})
+}
+
+// GetFilteredReport считает отчет только по операциям с тегом или продавцом из filter.
+// Бюджеты и остатки счетов в отчет с фильтром не попадают: они считаются по всем операциям.
+// Переводы между счетами меняют только остатки и не считаются доходом или расходом.
+func (s *FinanceService) GetFilteredReport(chatID int64, period dates.Period, filter models.TransactionFilter) (*Report, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	days, err := s.repo.GetDailyTotals(user.ID, period.From, period.To, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Period: period, Filter: filter, Currency: user.BaseCurrency, Days: days}
+	for _, d := range days {
+		switch d.Type {
+		case "income":
+			report.Income += d.Amount
+			report.IncomeCount += d.Count
+		case "expense":
+			report.Expense += d.Amount
+			report.ExpenseCount += d.Count
+		}
+	}
+
+	if err := s.addCategoryShares(user, report); err != nil {
+		return nil, err
+	}
+	if report.TopExpenses, err = s.repo.GetLargestTransactions(user.ID, "expense", period.From, period.To, filter, topExpensesLimit); err != nil {
+		return nil, err
+	}
+	if elapsed := elapsedDays(report, s.now(user)); elapsed > 0 {
+		report.AverageDailyExpense = report.Expense.Div(int64(elapsed))
+	}
+	if err := s.addCurrencyTotals(user, report); err != nil {
+		return nil, err
+	}
+	if err := s.addMemberTotals(user, report); err != nil {
+		return nil, err
+	}
+	if month := period.From; filter.IsEmpty() && !month.IsZero() && month.Equal(dates.MonthStart(month)) && period.To.Equal(month.AddDate(0, 1, 0)) {
+		if report.Budgets, err = s.budgetStatuses(user, month); err != nil {
+			return nil, err
+		}
+	}
+	if filter.IsEmpty() {
+		// За завершившийся период - остатки на его конец, иначе текущие
+		if !period.To.IsZero() && !period.To.After(dates.Day(s.now(user))) {
+			report.AccountsAt = period.To
+		}
+		if report.Accounts, err = s.repo.GetAccountBalances(user.ID, report.AccountsAt); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// Разбивка по участникам; в учете одного человека не заполняется
+func (s *FinanceService) addMemberTotals(user *models.User, report *Report) error {
+	totals, err := s.repo.GetMemberTotals(user.ID, report.Period.From, report.Period.To, report.Filter)
+	if err != nil {
+		return err
+	}
+	members := make(map[int64]bool)
+	for _, t := range totals {
+		members[t.MemberID] = true
+	}
+	if len(members) > 1 {
+		report.Members = totals
+	}
+	return nil
+}
+
+// Разбивка по категориям и сравнение с предыдущим таким же периодом
+func (s *FinanceService) addCategoryShares(user *models.User, report *Report) error {
+	totals, err := s.repo.GetCategoryTotals(user.ID, report.Period.From, report.Period.To, report.Filter)
+	if err != nil {
+		return err
+	}
+
+	previous := make(map[categoryKey]money.Amount)
+	if !report.Period.IsAll() {
+		report.HasPrevious = true
+		prev := report.Period.Shift(-1)
+		prevTotals, err := s.repo.GetCategoryTotals(user.ID, prev.From, prev.To, report.Filter)
+		if err != nil {
+			return err
+		}
+		for _, t := range prevTotals {
+			previous[keyOf(t)] += t.Amount
+		}
+	}
+
+	for _, t := range totals {
+		share := &CategoryShare{CategoryTotal: *t, Previous: previous[keyOf(t)]}
+		sum := report.Expense
+		if t.Type == "income" {
+			sum = report.Income
+		}
+		share.Share = t.Amount.Percent(sum)
+		report.Categories = append(report.Categories, share)
+	}
+	return nil
+}
+
+// Исходные суммы по валютам и валюты, которые не удалось пересчитать
+func (s *FinanceService) addCurrencyTotals(user *models.User, report *Report) error {
+	totals, err := s.repo.GetCurrencyTotals(user.ID, report.Period.From, report.Period.To, report.Filter)
+	if err != nil {
+		return err
+	}
+	for _, t := range totals {
+		if t.Currency != user.BaseCurrency {
+			report.Currencies = totals
+			break
+		}
+	}
+	if report.Currencies == nil {
+		return nil
+	}
+	report.MissingRates, err = s.repo.GetMissingRates(user.ID, report.Period.From, report.Period.To, report.Filter)
+	return err
+}
+
+// Ключ категории для сравнения периодов
+type categoryKey struct {
+	Type       string
+	CategoryID int64
+}
+
+func keyOf(t *models.CategoryTotal) categoryKey {
+	return categoryKey{Type: t.Type, CategoryID: t.CategoryID}
+}
+
+// Число прошедших дней периода: будущие дни не учитываются, период "за все время"
+// считается с первой операции
+func elapsedDays(report *Report, now time.Time) int {
+	period := report.Period
+	if period.From.IsZero() {
+		if len(report.Days) == 0 {
+			return 0
+		}
+		period.From = report.Days[0].Date
+	}
+	tomorrow := dates.Day(now).AddDate(0, 0, 1)
+	if period.To.IsZero() || period.To.After(tomorrow) {
+		period.To = tomorrow
+	}
+	return period.Days()
+}
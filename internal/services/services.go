@@ -1,11 +1,17 @@
 package services
 
 import (
+	"errors"
+	"finuchet-bot/internal/dates"
 	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
 	"finuchet-bot/internal/repository"
-	"fmt"
+	"time"
 )
 
+// ErrUserNotFound - чат еще не зарегистрирован через /start
+var ErrUserNotFound = errors.New("user not found")
+
 type FinanceService struct {
 	repo repository.Repository
 }
@@ -14,75 +20,136 @@ func NewFinanceService(repo repository.Repository) *FinanceService {
 	return &FinanceService{repo: repo}
 }
 
-func (s *FinanceService) RegisterUser(chatID int64) error {
+// Регистрация чата и создание категорий и основного счета по умолчанию. Участник member,
+// первым зарегистрировавший чат, становится владельцем его учета; title - название группы.
+// created сообщает, что чат зарегистрирован впервые.
+func (s *FinanceService) RegisterUser(chatID int64, member *models.Member, title string) (created bool, err error) {
 	user, err := s.repo.GetUserByChatID(chatID)
-	if err != nil || user != nil {
-		return err
+	if err != nil {
+		return false, err
+	}
+	if user == nil {
+		if err := s.repo.CreateUser(&models.User{ChatID: chatID}); err != nil {
+			return false, err
+		}
+		if user, err = s.ownUser(chatID); err != nil {
+			return false, err
+		}
+		created = true
 	}
-	return s.repo.CreateUser(&models.User{ChatID: chatID})
+	if title != user.Title {
+		if err := s.repo.SetUserTitle(user.ID, title); err != nil {
+			return false, err
+		}
+	}
+	if _, err := s.repo.ClaimLedger(&models.Member{LedgerID: user.ID, ID: member.ID, Name: member.Name}); err != nil {
+		return false, err
+	}
+	if err := s.seedAccount(user); err != nil {
+		return false, err
+	}
+	return created, s.seedCategories(user)
 }
 
-// Метод обработки доходов
-func (s *FinanceService) AddIncome(chatID int64, amount float64, category string) error {
-	user, err := s.repo.GetUserByChatID(chatID)
-	if err != nil || user == nil {
-		return err
+// Учет, который ведется из чата, или ErrUserNotFound. Из личного чата это может быть
+// общий учет группы, выбранный участником.
+func (s *FinanceService) user(chatID int64) (*models.User, error) {
+	user, err := s.repo.GetLedgerByChatID(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
 	}
-	return s.repo.AddTransaction(&models.Transaction{
-		UserID:   user.ID,
-		Amount:   amount,
-		Category: category,
-		Type:     "income",
-	})
+	return user, nil
 }
 
-// Метод обработки расходов
-func (s *FinanceService) AddExpense(chatID int64, amount float64, category string) error {
+// Собственный учет чата независимо от выбранного общего учета, или ErrUserNotFound
+func (s *FinanceService) ownUser(chatID int64) (*models.User, error) {
 	user, err := s.repo.GetUserByChatID(chatID)
-	if err != nil || user == nil {
-		return err
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
 	}
-	return s.repo.AddTransaction(&models.Transaction{
-		UserID:   user.ID,
-		Amount:   amount,
-		Category: category,
-		Type:     "expense",
-	})
+	return user, nil
 }
 
-// Метод очистки данных
-func (s *FinanceService) ClearData(chatID int64) error {
-	user, err := s.repo.GetUserByChatID(chatID)
-	if err != nil || user == nil {
+// Метод добавления операции с указанными датой и заметкой.
+// Категория должна принадлежать пользователю и соответствовать типу операции.
+// Без даты операция записывается на сегодня по часовому поясу пользователя.
+func (s *FinanceService) AddTransaction(chatID int64, transaction *models.Transaction) error {
+	if err := checkAmount(transaction.Amount); err != nil {
+		return err
+	}
+	if err := checkDetails(transaction); err != nil {
 		return err
 	}
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+	category, err := s.category(user, transaction.CategoryID)
+	if err != nil {
+		return err
+	}
+	if category.Type != transaction.Type {
+		return ErrCategoryType
+	}
 
-	return s.repo.DelData(user.ID)
+	transaction.UserID = user.ID
+	transaction.Category = category.Name
+	if transaction.Date.IsZero() {
+		transaction.Date = dates.Day(s.now(user))
+	}
+	if transaction.Currency == "" {
+		transaction.Currency = user.BaseCurrency
+	}
+	// Без выбранного счета операция записывается на основной, в его валюте она остается как введена
+	if transaction.AccountID != 0 {
+		account, err := s.activeAccount(user, transaction.AccountID)
+		if err != nil {
+			return err
+		}
+		transaction.Account = account.Name
+	}
+	if transaction.DebtID != 0 {
+		if err := s.checkDebtPayment(user, transaction); err != nil {
+			return err
+		}
+	}
+	return s.repo.AddTransaction(transaction)
 }
 
-func (s *FinanceService) GetReport(chatID int64) (string, error) {
-	user, err := s.repo.GetUserByChatID(chatID)
-	if err != nil || user == nil {
-		return "", err
+// Метод обработки доходов; нулевая date означает сегодня
+func (s *FinanceService) AddIncome(chatID int64, amount money.Amount, categoryID int64, date time.Time) (*models.Transaction, error) {
+	transaction := &models.Transaction{
+		Amount:     amount,
+		CategoryID: categoryID,
+		Type:       "income",
+		Date:       date,
 	}
+	return transaction, s.AddTransaction(chatID, transaction)
+}
 
-	transactions, err := s.repo.GetTransactions(user.ID)
-	if err != nil {
-		return "", err
+// Метод обработки расходов; нулевая date означает сегодня
+func (s *FinanceService) AddExpense(chatID int64, amount money.Amount, categoryID int64, date time.Time) (*models.Transaction, error) {
+	transaction := &models.Transaction{
+		Amount:     amount,
+		CategoryID: categoryID,
+		Type:       "expense",
+		Date:       date,
 	}
+	return transaction, s.AddTransaction(chatID, transaction)
+}
 
-	var income, expense float64
-	for _, t := range transactions {
-		if t.Type == "income" {
-			income += t.Amount
-		} else if t.Type == "expense" {
-			expense += t.Amount
-		}
+// Метод очистки данных
+func (s *FinanceService) ClearData(chatID int64) error {
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
 	}
 
-	report := "Доходы: " + fmt.Sprintf("%.2f", income) + "\n" +
-		"Расходы: " + fmt.Sprintf("%.2f", expense) + "\n" +
-		"Баланс: " + fmt.Sprintf("%.2f", income-expense)
-
-	return report, nil
+	return s.repo.DelData(user.ID)
 }
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"errors"
+	"finuchet-bot/internal/dates"
+	"finuchet-bot/internal/models"
+	"time"
+)
+
+var ErrUnknownTimezone = errors.New("unknown timezone")
+
+// Текущее время в часовом поясе пользователя: от него считаются "сегодня" и "этот месяц"
+func (s *FinanceService) now(user *models.User) time.Time {
+	return time.Now().In(dates.Location(user.Timezone))
+}
+
+// Now возвращает текущее время в часовом поясе пользователя
+func (s *FinanceService) Now(chatID int64) (time.Time, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return s.now(user), nil
+}
+
+// GetTimezone возвращает часовой пояс пользователя
+func (s *FinanceService) GetTimezone(chatID int64) (string, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return "", err
+	}
+	return user.Timezone, nil
+}
+
+// SetTimezone задает часовой пояс пользователя по имени IANA ("Asia/Vladivostok")
+// и переносит подписки на сводки на то же местное время в новом поясе
+func (s *FinanceService) SetTimezone(chatID int64, name string) (string, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return "", err
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "" || name == "Local" {
+		return "", ErrUnknownTimezone
+	}
+	if err := s.repo.SetTimezone(user.ID, loc.String()); err != nil {
+		return "", err
+	}
+
+	digests, err := s.repo.GetDigests(user.ID)
+	if err != nil {
+		return "", err
+	}
+	for _, d := range digests {
+		d.Timezone = loc.String()
+		d.NextRun = digestSchedule(d).Next(time.Now())
+		if err := s.repo.SetDigest(d); err != nil {
+			return "", err
+		}
+	}
+	return loc.String(), nil
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"errors"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/money"
+	"finuchet-bot/internal/repository"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrInvalidAmount       = errors.New("invalid amount")
+	ErrNoteTooLong         = errors.New("note too long")
+	ErrTagTooLong          = errors.New("tag too long")
+	ErrMerchantTooLong     = errors.New("merchant too long")
+)
+
+const (
+	maxNoteLength     = 255 // Соответствует VARCHAR(255) в transactions
+	maxTagLength      = 50  // Соответствует VARCHAR(50) в transaction_tags
+	maxMerchantLength = 100 // Соответствует VARCHAR(100) в transactions
+	searchLimit       = 20  // Сколько найденных операций показывать
+)
+
+// Сумма операции или лимита должна быть положительной и помещаться в NUMERIC(10,2)
+func checkAmount(amount money.Amount) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+	return amount.Check()
+}
+
+// Последние операции пользователя; hasMore сообщает, есть ли более старые
+func (s *FinanceService) GetRecentTransactions(chatID int64, limit, offset int) (transactions []*models.Transaction, hasMore bool, err error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, false, err
+	}
+	transactions, err = s.repo.GetRecentTransactions(user.ID, limit+1, offset)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(transactions) > limit {
+		return transactions[:limit], true, nil
+	}
+	return transactions, false, nil
+}
+
+func (s *FinanceService) GetTransaction(chatID, transactionID int64) (*models.Transaction, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return s.transaction(user, transactionID)
+}
+
+func (s *FinanceService) UpdateTransactionAmount(chatID, transactionID int64, amount money.Amount) error {
+	if err := checkAmount(amount); err != nil {
+		return err
+	}
+	return s.updateTransaction(chatID, transactionID, func(user *models.User, t *models.Transaction) error {
+		t.Amount = amount
+		return nil
+	})
+}
+
+// Категория должна быть того же типа, что и операция
+func (s *FinanceService) UpdateTransactionCategory(chatID, transactionID, categoryID int64) error {
+	return s.updateTransaction(chatID, transactionID, func(user *models.User, t *models.Transaction) error {
+		category, err := s.category(user, categoryID)
+		if err != nil {
+			return err
+		}
+		if category.Type != t.Type {
+			return ErrCategoryType
+		}
+		t.CategoryID = category.ID
+		return nil
+	})
+}
+
+func (s *FinanceService) UpdateTransactionDate(chatID, transactionID int64, date time.Time) error {
+	return s.updateTransaction(chatID, transactionID, func(user *models.User, t *models.Transaction) error {
+		t.Date = date
+		return nil
+	})
+}
+
+func (s *FinanceService) UpdateTransactionNote(chatID, transactionID int64, note string) error {
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > maxNoteLength {
+		return ErrNoteTooLong
+	}
+	return s.updateTransaction(chatID, transactionID, func(user *models.User, t *models.Transaction) error {
+		t.Note = note
+		return nil
+	})
+}
+
+// Теги заменяют прежние; пустой список удаляет все теги операции
+func (s *FinanceService) UpdateTransactionTags(chatID, transactionID int64, tags []string) error {
+	if err := checkTags(tags); err != nil {
+		return err
+	}
+	return s.updateTransaction(chatID, transactionID, func(user *models.User, t *models.Transaction) error {
+		t.Tags = tags
+		return nil
+	})
+}
+
+func (s *FinanceService) UpdateTransactionMerchant(chatID, transactionID int64, merchant string) error {
+	merchant = strings.TrimSpace(merchant)
+	if utf8.RuneCountInString(merchant) > maxMerchantLength {
+		return ErrMerchantTooLong
+	}
+	return s.updateTransaction(chatID, transactionID, func(user *models.User, t *models.Transaction) error {
+		t.Merchant = merchant
+		return nil
+	})
+}
+
+// SearchTransactions ищет операции по тегу, заметке, продавцу или категории, новые первыми
+func (s *FinanceService) SearchTransactions(chatID int64, query string) ([]*models.Transaction, error) {
+	user, err := s.user(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.SearchTransactions(user.ID, strings.TrimSpace(query), searchLimit)
+}
+
+func (s *FinanceService) DeleteTransaction(chatID, transactionID int64) error {
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.transaction(user, transactionID); err != nil {
+		return err
+	}
+	return transactionError(s.repo.DeleteTransaction(user.ID, transactionID))
+}
+
+func (s *FinanceService) updateTransaction(chatID, transactionID int64, update func(*models.User, *models.Transaction) error) error {
+	user, err := s.user(chatID)
+	if err != nil {
+		return err
+	}
+	transaction, err := s.transaction(user, transactionID)
+	if err != nil {
+		return err
+	}
+	if err := update(user, transaction); err != nil {
+		return err
+	}
+	return transactionError(s.repo.UpdateTransaction(transaction))
+}
+
+// Операция могла быть удалена между чтением и записью
+func transactionError(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrTransactionNotFound
+	}
+	return err
+}
+
+// Заметка, теги и продавец должны помещаться в свои колонки
+func checkDetails(t *models.Transaction) error {
+	if utf8.RuneCountInString(t.Note) > maxNoteLength {
+		return ErrNoteTooLong
+	}
+	if utf8.RuneCountInString(t.Merchant) > maxMerchantLength {
+		return ErrMerchantTooLong
+	}
+	return checkTags(t.Tags)
+}
+
+func checkTags(tags []string) error {
+	for _, tag := range tags {
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return ErrTagTooLong
+		}
+	}
+	return nil
+}
+
+// Операция пользователя или ErrTransactionNotFound
+func (s *FinanceService) transaction(user *models.User, transactionID int64) (*models.Transaction, error) {
+	transaction, err := s.repo.GetTransactionByID(user.ID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if transaction == nil {
+		return nil, ErrTransactionNotFound
+	}
+	return transaction, nil
+}
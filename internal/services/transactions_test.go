@@ -0,0 +1,54 @@
+package services
+
+import (
+	"errors"
+	"finuchet-bot/internal/models"
+	"finuchet-bot/internal/repository"
+	"testing"
+)
+
+// goneRepo отдает операцию при чтении, но к записи ее уже удалили
+type goneRepo struct {
+	repository.Repository
+	err error // Что вернут UpdateTransaction и DeleteTransaction
+}
+
+func (r *goneRepo) GetLedgerByChatID(chatID int64) (*models.User, error) {
+	return &models.User{ID: 1, ChatID: chatID, Timezone: "UTC"}, nil
+}
+
+func (r *goneRepo) GetTransactionByID(userID, transactionID int64) (*models.Transaction, error) {
+	return &models.Transaction{ID: transactionID, UserID: userID, Type: "expense", Amount: 100}, nil
+}
+
+func (r *goneRepo) UpdateTransaction(*models.Transaction) error {
+	return r.err
+}
+
+func (r *goneRepo) DeleteTransaction(int64, int64) error {
+	return r.err
+}
+
+func TestChangeOfMissingTransaction(t *testing.T) {
+	failure := errors.New("connection reset")
+	tests := []struct {
+		name    string
+		repoErr error
+		want    error
+	}{
+		{"deleted in the meantime", repository.ErrNotFound, ErrTransactionNotFound},
+		{"other errors pass through", failure, failure},
+		{"success", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewFinanceService(&goneRepo{err: tt.repoErr})
+			if err := s.UpdateTransactionNote(100, 5, "обед"); !errors.Is(err, tt.want) {
+				t.Errorf("UpdateTransactionNote() error = %v, want %v", err, tt.want)
+			}
+			if err := s.DeleteTransaction(100, 5); !errors.Is(err, tt.want) {
+				t.Errorf("DeleteTransaction() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
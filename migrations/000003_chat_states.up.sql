@@ -0,0 +1,10 @@
+----------------------------------------------------
+-- Состояния диалогов с пользователями (переживают перезапуск бота)
+CREATE TABLE chat_states (
+    chat_id BIGINT PRIMARY KEY,
+    data JSONB NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX chat_states_expires_at_idx ON chat_states (expires_at);
@@ -0,0 +1,4 @@
+----------------------------------------------------
+-- Заметка к операции (например, из быстрого ввода "350 кафе обед")
+ALTER TABLE transactions
+ADD COLUMN note VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,8 @@
+----------------------------------------------------
+DROP INDEX IF EXISTS user_categories_user_id_idx;
+
+ALTER TABLE user_categories
+DROP COLUMN IF EXISTS archived,
+DROP COLUMN IF EXISTS position,
+DROP COLUMN IF EXISTS aliases,
+DROP COLUMN IF EXISTS emoji;
@@ -0,0 +1,9 @@
+----------------------------------------------------
+-- Управление категориями: эмодзи, синонимы для быстрого ввода, порядок и архив
+ALTER TABLE user_categories
+ADD COLUMN emoji VARCHAR(16) NOT NULL DEFAULT '',
+ADD COLUMN aliases TEXT[] NOT NULL DEFAULT '{}',
+ADD COLUMN position INT NOT NULL DEFAULT 0,
+ADD COLUMN archived BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX user_categories_user_id_idx ON user_categories (user_id, type, position);
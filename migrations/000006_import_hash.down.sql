@@ -0,0 +1,5 @@
+----------------------------------------------------
+DROP INDEX IF EXISTS transactions_import_hash_idx;
+
+ALTER TABLE transactions
+DROP COLUMN IF EXISTS import_hash;
@@ -0,0 +1,8 @@
+----------------------------------------------------
+-- Отпечаток операции из банковской выписки для защиты от повторного импорта
+ALTER TABLE transactions
+ADD COLUMN import_hash VARCHAR(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_import_hash_idx
+ON transactions (user_id, import_hash)
+WHERE import_hash IS NOT NULL;
@@ -0,0 +1,2 @@
+----------------------------------------------------
+DROP INDEX IF EXISTS transactions_user_id_create_dat_idx;
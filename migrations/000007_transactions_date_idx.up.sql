@@ -0,0 +1,4 @@
+----------------------------------------------------
+-- Отчеты группируют операции пользователя по дням
+CREATE INDEX IF NOT EXISTS transactions_user_id_create_dat_idx
+ON transactions (user_id, create_dat);
@@ -0,0 +1,12 @@
+----------------------------------------------------
+-- Месячные бюджеты по категориям расходов
+CREATE TABLE budgets (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category_id INT NOT NULL REFERENCES user_categories(id) ON DELETE CASCADE,
+    amount NUMERIC(10, 2) NOT NULL CHECK (amount > 0),
+    rollover BOOLEAN NOT NULL DEFAULT FALSE, -- Неизрасходованный остаток переносится на следующий месяц
+    start_month DATE NOT NULL,               -- С какого месяца считается перенос остатка
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, category_id)
+);
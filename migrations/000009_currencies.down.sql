@@ -0,0 +1,8 @@
+----------------------------------------------------
+DROP TABLE IF EXISTS exchange_rates;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS base_currency;
+
+ALTER TABLE transactions
+DROP COLUMN IF EXISTS currency;
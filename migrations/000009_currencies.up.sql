@@ -0,0 +1,25 @@
+----------------------------------------------------
+-- Мультивалютность: валюта операции, базовая валюта пользователя и курсы
+ALTER TABLE transactions
+ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'RUB';
+
+ALTER TABLE users
+ADD COLUMN base_currency CHAR(3) NOT NULL DEFAULT 'RUB';
+
+-- Стоимость одной единицы currency в валюте quote на дату.
+-- Курсы без user_id загружаются из общего источника, с user_id - введены пользователем.
+CREATE TABLE exchange_rates (
+    id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(id) ON DELETE CASCADE,
+    currency CHAR(3) NOT NULL,
+    quote CHAR(3) NOT NULL,
+    rate_date DATE NOT NULL,
+    rate NUMERIC(18, 8) NOT NULL CHECK (rate > 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX exchange_rates_key_idx
+ON exchange_rates (COALESCE(user_id, 0), currency, quote, rate_date);
+
+CREATE INDEX exchange_rates_lookup_idx
+ON exchange_rates (currency, quote, rate_date);
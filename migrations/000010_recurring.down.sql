@@ -0,0 +1,7 @@
+----------------------------------------------------
+DROP INDEX IF EXISTS transactions_recurring_idx;
+
+ALTER TABLE transactions
+DROP COLUMN IF EXISTS recurring_id;
+
+DROP TABLE IF EXISTS recurring_rules;
@@ -0,0 +1,31 @@
+----------------------------------------------------
+-- Повторяющиеся операции: аренда, зарплата, подписки
+CREATE TABLE recurring_rules (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category_id INT REFERENCES user_categories(id) ON DELETE SET NULL,
+    type VARCHAR(10) CHECK (type IN ('income', 'expense')) NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL DEFAULT 'RUB',
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    frequency VARCHAR(10) CHECK (frequency IN ('daily', 'weekly', 'monthly', 'yearly')) NOT NULL,
+    day_of_month SMALLINT CHECK (day_of_month BETWEEN 1 AND 31), -- NULL - день start_date
+    start_date DATE NOT NULL,
+    end_date DATE,                                                -- NULL - без окончания
+    next_date DATE,                                               -- NULL - расписание закончилось
+    paused BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX recurring_rules_due_idx
+ON recurring_rules (next_date)
+WHERE NOT paused AND next_date IS NOT NULL;
+
+-- Операция, созданная по правилу; одна на правило и дату, чтобы повторный запуск
+-- планировщика после сбоя не создал дубль
+ALTER TABLE transactions
+ADD COLUMN recurring_id INT REFERENCES recurring_rules(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX transactions_recurring_idx
+ON transactions (recurring_id, create_dat)
+WHERE recurring_id IS NOT NULL;
@@ -0,0 +1,5 @@
+----------------------------------------------------
+DROP TABLE IF EXISTS digests;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS timezone;
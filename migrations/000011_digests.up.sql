@@ -0,0 +1,21 @@
+----------------------------------------------------
+-- Регулярные сводки по расписанию
+ALTER TABLE users
+ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'Europe/Moscow'; -- Имя из базы IANA
+
+-- Подписка на сводку: время отправки хранится в местном времени пользователя,
+-- next_run - ближайший момент отправки; после простоя бота пропущенная сводка
+-- отправляется при запуске
+CREATE TABLE digests (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    frequency VARCHAR(10) CHECK (frequency IN ('daily', 'weekly', 'monthly')) NOT NULL,
+    send_minute SMALLINT NOT NULL CHECK (send_minute BETWEEN 0 AND 1439), -- Минуты от полуночи
+    next_run TIMESTAMPTZ NOT NULL,
+    last_run TIMESTAMPTZ,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, frequency)
+);
+
+CREATE INDEX digests_next_run_idx
+ON digests (next_run);
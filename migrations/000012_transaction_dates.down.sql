@@ -0,0 +1,4 @@
+----------------------------------------------------
+ALTER TABLE transactions
+ALTER COLUMN create_dat DROP NOT NULL,
+ALTER COLUMN create_dat SET DEFAULT CURRENT_DATE;
@@ -0,0 +1,10 @@
+----------------------------------------------------
+-- Дату операции задает приложение по часовому поясу пользователя,
+-- а не CURRENT_DATE сервера БД
+UPDATE transactions
+SET create_dat = created_at::date
+WHERE create_dat IS NULL;
+
+ALTER TABLE transactions
+ALTER COLUMN create_dat DROP DEFAULT,
+ALTER COLUMN create_dat SET NOT NULL;
@@ -0,0 +1,5 @@
+----------------------------------------------------
+DROP TABLE IF EXISTS transaction_tags;
+
+ALTER TABLE transactions
+DROP COLUMN IF EXISTS merchant;
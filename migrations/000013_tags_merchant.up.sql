@@ -0,0 +1,18 @@
+----------------------------------------------------
+-- Продавец операции ("350 кафе @Теремок")
+ALTER TABLE transactions
+ADD COLUMN merchant VARCHAR(100) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS transactions_merchant_idx
+ON transactions (user_id, lower(merchant))
+WHERE merchant <> '';
+
+-- Теги операции ("#отпуск"): хранятся без "#" в нижнем регистре
+CREATE TABLE transaction_tags (
+    transaction_id INT NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    tag VARCHAR(50) NOT NULL,
+    PRIMARY KEY (transaction_id, tag)
+);
+
+CREATE INDEX transaction_tags_tag_idx
+ON transaction_tags (tag);
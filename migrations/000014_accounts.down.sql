@@ -0,0 +1,9 @@
+----------------------------------------------------
+DROP TABLE IF EXISTS transfers;
+
+DROP INDEX IF EXISTS transactions_account_idx;
+
+ALTER TABLE transactions
+DROP COLUMN IF EXISTS account_id;
+
+DROP TABLE IF EXISTS accounts;
@@ -0,0 +1,53 @@
+----------------------------------------------------
+-- Счета пользователя: наличные, карты, вклады
+CREATE TABLE accounts (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'RUB',
+    opening_balance NUMERIC(10, 2) NOT NULL DEFAULT 0, -- Остаток на момент начала учета в боте
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,         -- Счет операций, для которых счет не выбран
+    archived BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX accounts_name_idx
+ON accounts (user_id, lower(name));
+
+CREATE UNIQUE INDEX accounts_default_idx
+ON accounts (user_id)
+WHERE is_default;
+
+-- У каждого пользователя есть основной счет, к нему относятся все прежние операции
+INSERT INTO accounts (user_id, name, currency, is_default)
+SELECT id, 'Основной', base_currency, TRUE
+FROM users;
+
+ALTER TABLE transactions
+ADD COLUMN account_id INT REFERENCES accounts(id) ON DELETE SET NULL;
+
+UPDATE transactions AS t
+SET account_id = a.id
+FROM accounts AS a
+WHERE a.user_id = t.user_id AND a.is_default;
+
+CREATE INDEX transactions_account_idx
+ON transactions (account_id, create_dat);
+
+-- Переводы между счетами. Перевод не доход и не расход, поэтому хранится отдельно от transactions;
+-- списание и зачисление - одна строка, так что перевод не может пройти наполовину.
+CREATE TABLE transfers (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_account_id INT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    to_account_id INT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    amount NUMERIC(10, 2) NOT NULL CHECK (amount > 0),       -- Списано в валюте счета-источника
+    to_amount NUMERIC(10, 2) NOT NULL CHECK (to_amount > 0), -- Зачислено в валюте счета-получателя
+    transfer_date DATE NOT NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (from_account_id <> to_account_id)
+);
+
+CREATE INDEX transfers_user_idx
+ON transfers (user_id, transfer_date);
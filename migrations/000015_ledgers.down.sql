@@ -0,0 +1,11 @@
+----------------------------------------------------
+ALTER TABLE transactions
+DROP COLUMN IF EXISTS member_id;
+
+DROP TABLE IF EXISTS ledger_invites;
+
+DROP TABLE IF EXISTS ledger_members;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS ledger_id,
+DROP COLUMN IF EXISTS title;
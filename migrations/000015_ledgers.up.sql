@@ -0,0 +1,44 @@
+----------------------------------------------------
+-- Совместный учет: запись в users - это учет чата (личного или группового),
+-- а участники - люди Telegram с ролями в этом учете
+ALTER TABLE users
+ADD COLUMN title VARCHAR(100) NOT NULL DEFAULT '',             -- Название группы для списка учетов
+ADD COLUMN ledger_id INT REFERENCES users(id) ON DELETE SET NULL; -- Общий учет, который ведется из личного чата
+
+CREATE TABLE ledger_members (
+    ledger_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    member_id BIGINT NOT NULL, -- Telegram ID участника
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    role VARCHAR(10) CHECK (role IN ('owner', 'editor', 'viewer')) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ledger_id, member_id)
+);
+
+CREATE INDEX ledger_members_member_idx
+ON ledger_members (member_id);
+
+-- Владелец личного учета - сам пользователь: в личном чате chat_id совпадает с его Telegram ID.
+-- У групп владельцем становится первый, кто обратится к боту.
+INSERT INTO ledger_members (ledger_id, member_id, role)
+SELECT id, chat_id, 'owner'
+FROM users
+WHERE chat_id > 0;
+
+-- Одноразовые приглашения по ссылке t.me/<бот>?start=<token>
+CREATE TABLE ledger_invites (
+    token VARCHAR(32) PRIMARY KEY,
+    ledger_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(10) CHECK (role IN ('editor', 'viewer')) NOT NULL,
+    created_by BIGINT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Кто записал операцию; NULL - повторяющиеся и импортированные операции
+ALTER TABLE transactions
+ADD COLUMN member_id BIGINT;
+
+UPDATE transactions AS t
+SET member_id = u.chat_id
+FROM users AS u
+WHERE u.id = t.user_id AND u.chat_id > 0 AND t.recurring_id IS NULL AND t.import_hash IS NULL;
@@ -0,0 +1,4 @@
+----------------------------------------------------
+DROP TABLE IF EXISTS debt_payments;
+
+DROP TABLE IF EXISTS debts;
@@ -0,0 +1,33 @@
+----------------------------------------------------
+-- Долги: деньги, которые пользователь дал в долг (lent) или занял (borrowed)
+CREATE TABLE debts (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    direction VARCHAR(10) CHECK (direction IN ('lent', 'borrowed')) NOT NULL,
+    counterparty VARCHAR(100) NOT NULL,            -- Кто должен или кому должны
+    amount NUMERIC(10, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL DEFAULT 'RUB',
+    debt_date DATE NOT NULL,
+    due_date DATE,                                 -- Срок возврата; NULL - без срока
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    closed BOOLEAN NOT NULL DEFAULT FALSE,         -- Закрыт вручную, например долг прощен
+    reminded_on DATE,                              -- Местная дата последнего напоминания о просрочке
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX debts_user_idx
+ON debts (user_id, lower(counterparty));
+
+CREATE INDEX debts_due_idx
+ON debts (due_date)
+WHERE NOT closed;
+
+-- Погашения: операция дохода (нам вернули) или расхода (мы вернули), засчитанная в долг.
+-- Сумма погашения - сумма операции, так что исправление операции меняет и остаток долга.
+CREATE TABLE debt_payments (
+    transaction_id INT PRIMARY KEY REFERENCES transactions(id) ON DELETE CASCADE,
+    debt_id INT NOT NULL REFERENCES debts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX debt_payments_debt_idx
+ON debt_payments (debt_id);
@@ -0,0 +1,4 @@
+----------------------------------------------------
+DROP TABLE IF EXISTS goal_contributions;
+
+DROP TABLE IF EXISTS goals;
@@ -0,0 +1,33 @@
+----------------------------------------------------
+-- Цели накоплений: сколько и к какому сроку нужно отложить
+CREATE TABLE goals (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    target NUMERIC(10, 2) NOT NULL CHECK (target > 0),
+    currency CHAR(3) NOT NULL DEFAULT 'RUB',
+    deadline DATE,                                 -- К какому дню накопить; NULL - без срока
+    completed_at TIMESTAMP,                        -- Когда цель достигнута; сбрасывается, если накоплений снова меньше цели
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX goals_user_name_idx
+ON goals (user_id, lower(name));
+
+-- Взносы в цель: отложенные вручную деньги, часть дохода или отдельная операция.
+-- Взнос из операции удаляется вместе с ней.
+CREATE TABLE goal_contributions (
+    id SERIAL PRIMARY KEY,
+    goal_id INT NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+    amount NUMERIC(10, 2) NOT NULL CHECK (amount > 0),
+    contribution_date DATE NOT NULL,
+    transaction_id INT REFERENCES transactions(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX goal_contributions_goal_idx
+ON goal_contributions (goal_id);
+
+CREATE INDEX goal_contributions_transaction_idx
+ON goal_contributions (transaction_id)
+WHERE transaction_id IS NOT NULL;
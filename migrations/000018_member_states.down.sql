@@ -0,0 +1,6 @@
+----------------------------------------------------
+DELETE FROM chat_states WHERE member_id <> 0;
+
+ALTER TABLE chat_states DROP CONSTRAINT chat_states_pkey;
+ALTER TABLE chat_states DROP COLUMN member_id;
+ALTER TABLE chat_states ADD PRIMARY KEY (chat_id);
@@ -0,0 +1,6 @@
+----------------------------------------------------
+-- Диалоги участников группы хранятся отдельно: member_id - участник, 0 - весь чат
+ALTER TABLE chat_states ADD COLUMN member_id BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE chat_states DROP CONSTRAINT chat_states_pkey;
+ALTER TABLE chat_states ADD PRIMARY KEY (chat_id, member_id);
@@ -0,0 +1,12 @@
+----------------------------------------------------
+-- История лимитов бюджета: лимит действует с month до следующей записи,
+-- чтобы перенос остатка за прошлые месяцы считался по их собственному лимиту
+CREATE TABLE budget_limits (
+    budget_id INT NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+    month DATE NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL CHECK (amount > 0),
+    PRIMARY KEY (budget_id, month)
+);
+
+INSERT INTO budget_limits (budget_id, month, amount)
+SELECT id, start_month, amount FROM budgets;
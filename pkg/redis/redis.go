@@ -1,33 +1,29 @@
 package redis
 
-// var ctx = context.Background()
+import (
+	"context"
+	"finuchet-bot/config"
+	"fmt"
+	"time"
 
-// // RedisClient - глобальный клиент Redis
-// var RedisClient *redis.Client
+	goredis "github.com/redis/go-redis/v9"
+)
 
-// // InitializeRedis - настройка и подключение к Redis
-// func InitializeRedis(addr, password string, db int) {
-// 	RedisClient = redis.NewClient(&redis.Options{
-// 		Addr:     addr,     // адрес Redis (например, "localhost:6379")
-// 		Password: password, // пароль, если требуется (оставьте пустым, если нет)
-// 		DB:       db,       // номер базы данных
-// 	})
+// Connect - настройка и подключение к Redis
+func Connect(cfg config.RedisConfig) (*goredis.Client, error) {
+	client := goredis.NewClient(&goredis.Options{
+		Addr:     cfg.Addr,     // адрес Redis (например, "localhost:6379")
+		Password: cfg.Password, // пароль, если требуется (оставьте пустым, если нет)
+		DB:       cfg.DB,       // номер базы данных
+	})
 
-// 	// Проверка соединения
-// 	_, err := RedisClient.Ping(ctx).Result()
-// 	if err != nil {
-// 		log.Fatalf("Не удалось подключиться к Redis: %v", err)
-// 	}
+	// Проверка соединения
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("error connecting to redis: %v", err)
+	}
 
-// 	log.Println("Успешно подключен к Redis")
-// }
-
-// // SetValue - пример записи значения в Redis
-// func SetValue(key string, value interface{}, expiration time.Duration) error {
-// 	return RedisClient.Set(ctx, key, value, expiration).Err()
-// }
-
-// // GetValue - пример получения значения из Redis
-// func GetValue(key string) (string, error) {
-// 	return RedisClient.Get(ctx, key).Result()
-// }
+	return client, nil
+}